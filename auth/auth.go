@@ -0,0 +1,385 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"pinger/logger"
+	"pinger/metrics"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+/*
+API authentication with scoped tokens or client certificates. Both are defined in config
+and/or token file (same format):
+
+	[[auth.tokens]]
+	name = "noc-dashboard"
+	token = "long-random-string"        # or token-sha256 = "<hex sha256 of token>"
+	topics = ["switches", "cameras"]    # "*" - all topics
+	scopes = ["read", "ping"]           # read, ping, write
+
+	[[auth.certs]]
+	name = "nms"
+	subjects = ["CN=nms.noc.local", "DNS:nms.noc.local", "URI:spiffe://noc/nms", "EMAIL:nms@noc.local", "IP:10.0.0.5"]
+	topics = ["*"]
+	scopes = ["read"]
+
+Token is sent as `Authorization: Bearer <token>` or `X-API-Key: <token>` header. Without token, verified
+client certificate (see TLSReloader) is matched against certs subjects.
+If there are no tokens and certs, authentication is disabled and every request has full access.
+*/
+
+// Scopes
+const (
+	ScopeRead  = "read"  // reports, dumps, metrics
+	ScopePing  = "ping"  // ping-now, ping-api
+	ScopeWrite = "write" // store hosts and topics
+	ScopeAdmin = "admin" // config reload
+)
+
+// Scopes - all known scopes
+var Scopes = []string{ScopeRead, ScopePing, ScopeWrite, ScopeAdmin}
+
+// AllTopics - topic wildcard
+const AllTopics = "*"
+
+// Token - token definition from config
+type Token struct {
+	Name   string
+	Hash   string // hex sha256 of token
+	Topics []string
+	Scopes []string
+}
+
+// Cert - client certificate definition from config
+type Cert struct {
+	Name     string
+	Subjects []string // "CN=", "DNS:", "URI:", "EMAIL:", "IP:" prefixed values
+	Topics   []string
+	Scopes   []string
+}
+
+/*
+Principal - authenticated API client. Does not contain token itself, so it's safe to log and return
+*/
+type Principal struct {
+	Name   string
+	Topics []string
+	Scopes []string
+}
+
+// Anonymous - principal used when authentication is disabled
+var Anonymous = &Principal{Name: "anonymous", Topics: []string{AllTopics}, Scopes: Scopes}
+
+type contextKey struct{}
+
+var (
+	principals map[string]*Principal // hash => principal
+	certs      []Cert
+	enabled    bool
+	mx         sync.Mutex
+
+	failures = metrics.NewCounter("pinger_auth_failures_total", "Rejected API requests by reason")
+)
+
+// Init - set tokens and certs from config and token file (optional). Replaces all previously loaded ones at once,
+// so requests during config reload are authenticated by either old or new tokens
+func Init(tokens []Token, certList []Cert, tokenFile string) error {
+	all := append([]Token{}, tokens...)
+	allCerts := append([]Cert{}, certList...)
+	if tokenFile != "" {
+		fileTokens, fileCerts, err := LoadFile(tokenFile)
+		if err != nil {
+			return err
+		}
+		all = append(all, fileTokens...)
+		allCerts = append(allCerts, fileCerts...)
+	}
+
+	loaded := make(map[string]*Principal, len(all))
+	for _, t := range all {
+		loaded[t.Hash] = &Principal{Name: t.Name, Topics: t.Topics, Scopes: t.Scopes}
+	}
+
+	mx.Lock()
+	defer mx.Unlock()
+	principals = loaded
+	certs = allCerts
+	enabled = len(all) > 0 || len(certs) > 0
+	if enabled {
+		logger.Log("API authentication enabled, %d tokens and %d client certs loaded", len(all), len(certs))
+	}
+	return nil
+}
+
+// Enabled - authentication is enabled
+func Enabled() bool {
+	mx.Lock()
+	defer mx.Unlock()
+	return enabled
+}
+
+// LoadFile - read tokens and certs from toml file with [[tokens]] and [[certs]] tables
+func LoadFile(path string) ([]Token, []Cert, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, nil, fmt.Errorf("cannot read token file: %s", err.Error())
+	}
+	tokens, err := ParseTokens(v.Get("tokens"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("token file '%s': %s", path, err.Error())
+	}
+	certList, err := ParseCerts(v.Get("certs"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("token file '%s': %s", path, err.Error())
+	}
+	return tokens, certList, nil
+}
+
+// ParseTokens - parse array of token tables
+func ParseTokens(value interface{}) ([]Token, error) {
+	result := make([]Token, 0)
+	list, err := tables(value, "tokens")
+	if err != nil {
+		return nil, err
+	}
+
+	for n, m := range list {
+		t := Token{}
+		t.Name, _ = m["name"].(string)
+		if t.Name == "" {
+			return nil, fmt.Errorf("token %d: missing 'name'", n)
+		}
+		if token, _ := m["token"].(string); token != "" {
+			t.Hash = Hash(token)
+		} else if hash, _ := m["token-sha256"].(string); hash != "" {
+			if b, err := hex.DecodeString(hash); err != nil || len(b) != sha256.Size {
+				return nil, fmt.Errorf("token '%s': wrong 'token-sha256'", t.Name)
+			}
+			t.Hash = strings.ToLower(hash)
+		} else {
+			return nil, fmt.Errorf("token '%s': missing 'token' or 'token-sha256'", t.Name)
+		}
+
+		if t.Topics, t.Scopes, err = permissions(m); err != nil {
+			return nil, fmt.Errorf("token '%s': %s", t.Name, err.Error())
+		}
+		result = append(result, t)
+	}
+	return result, nil
+}
+
+// ParseCerts - parse array of client certificate tables
+func ParseCerts(value interface{}) ([]Cert, error) {
+	result := make([]Cert, 0)
+	list, err := tables(value, "certs")
+	if err != nil {
+		return nil, err
+	}
+
+	for n, m := range list {
+		c := Cert{}
+		c.Name, _ = m["name"].(string)
+		if c.Name == "" {
+			return nil, fmt.Errorf("cert %d: missing 'name'", n)
+		}
+		c.Subjects = stringList(m["subjects"])
+		if len(c.Subjects) == 0 {
+			return nil, fmt.Errorf("cert '%s': missing 'subjects'", c.Name)
+		}
+		for _, s := range c.Subjects {
+			if !isSubject(s) {
+				return nil, fmt.Errorf("cert '%s': subject '%s' should start with one of %v", c.Name, s, subjectPrefixes)
+			}
+		}
+		if c.Topics, c.Scopes, err = permissions(m); err != nil {
+			return nil, fmt.Errorf("cert '%s': %s", c.Name, err.Error())
+		}
+		result = append(result, c)
+	}
+	return result, nil
+}
+
+// tables - array of toml tables
+func tables(value interface{}, name string) ([]map[string]interface{}, error) {
+	result := make([]map[string]interface{}, 0)
+	switch v := value.(type) {
+	case nil:
+	case []map[string]interface{}:
+		result = v
+	case []interface{}:
+		for n, item := range v {
+			m, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s %d should be table", name, n)
+			}
+			result = append(result, m)
+		}
+	default:
+		return nil, fmt.Errorf("%s should be array of tables", name)
+	}
+	return result, nil
+}
+
+// permissions - parse topics and scopes of token or cert
+func permissions(m map[string]interface{}) ([]string, []string, error) {
+	topics := stringList(m["topics"])
+	if len(topics) == 0 {
+		return nil, nil, fmt.Errorf("missing 'topics'")
+	}
+	scopes := stringList(m["scopes"])
+	if len(scopes) == 0 {
+		return nil, nil, fmt.Errorf("missing 'scopes'")
+	}
+	for _, s := range scopes {
+		if !isScope(s) {
+			return nil, nil, fmt.Errorf("unknown scope '%s', known scopes: %v", s, Scopes)
+		}
+	}
+	return topics, scopes, nil
+}
+
+// Hash - hex sha256 of token
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+/*
+Authenticate - find principal by request token. Returns Anonymous if authentication is disabled
+*/
+func Authenticate(r *http.Request) (*Principal, bool) {
+	return AuthenticateToken(RequestToken(r.Header.Get("X-API-Key"), r.Header.Get("Authorization")), r.TLS)
+}
+
+// RequestToken - token from `X-API-Key` or `Authorization: Bearer` header values
+func RequestToken(apiKey string, authorization string) string {
+	if apiKey == "" && len(authorization) > 7 && strings.EqualFold(authorization[:7], "bearer ") {
+		return strings.TrimSpace(authorization[7:])
+	}
+	return apiKey
+}
+
+/*
+AuthenticateToken - find principal by token; without token - by verified client certificate of connection (can be nil).
+Used by http and grpc APIs
+*/
+func AuthenticateToken(token string, state *tls.ConnectionState) (*Principal, bool) {
+	mx.Lock()
+	on, loaded := enabled, principals
+	mx.Unlock()
+	if !on {
+		return Anonymous, true
+	}
+	if token == "" {
+		return certPrincipal(state)
+	}
+	// lookup by hash: comparison time does not depend on token contents
+	if p, ok := loaded[Hash(token)]; ok {
+		return p, true
+	}
+	return nil, false
+}
+
+// WithPrincipal - context with principal
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromRequest - principal of request, set by Middleware. Anonymous if not set and auth is disabled
+func FromRequest(r *http.Request) *Principal {
+	return FromContext(r.Context())
+}
+
+// FromContext - principal of context, set by WithPrincipal. Anonymous if not set and auth is disabled
+func FromContext(ctx context.Context) *Principal {
+	if p, ok := ctx.Value(contextKey{}).(*Principal); ok {
+		return p
+	}
+	if !Enabled() {
+		return Anonymous
+	}
+	return &Principal{Name: "unauthenticated"}
+}
+
+// HasScope - principal has scope for some topics
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// AllTopics - principal has access to all topics
+func (p *Principal) AllTopics() bool {
+	for _, t := range p.Topics {
+		if t == AllTopics {
+			return true
+		}
+	}
+	return false
+}
+
+// Can - principal has scope for topic
+func (p *Principal) Can(scope string, topic string) bool {
+	if !p.HasScope(scope) {
+		return false
+	}
+	if p.AllTopics() {
+		return true
+	}
+	for _, t := range p.Topics {
+		if t == topic {
+			return true
+		}
+	}
+	return false
+}
+
+// CanAll - principal has scope for all topics (global endpoints)
+func (p *Principal) CanAll(scope string) bool {
+	return p.HasScope(scope) && p.AllTopics()
+}
+
+// Rejected - count rejected request; reason is "unauthorized" or "forbidden"
+func Rejected(reason string) {
+	failures.Inc(metrics.Labels{"reason": reason})
+}
+
+func isScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func stringList(value interface{}) []string {
+	result := make([]string, 0)
+	switch v := value.(type) {
+	case []string:
+		result = append(result, v...)
+	case []interface{}:
+		for _, s := range v {
+			if str, ok := s.(string); ok {
+				result = append(result, str)
+			}
+		}
+	case string:
+		result = append(result, v)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestCan(t *testing.T) {
+	reader := &Principal{Name: "reader", Topics: []string{"switches", "routers"}, Scopes: []string{ScopeRead}}
+	admin := &Principal{Name: "admin", Topics: []string{AllTopics}, Scopes: []string{ScopeRead, ScopeWrite, ScopeAdmin}}
+	tests := []struct {
+		principal *Principal
+		scope     string
+		topic     string
+		can       bool
+		canAll    bool
+	}{
+		{reader, ScopeRead, "switches", true, false},
+		{reader, ScopeRead, "routers", true, false},
+		{reader, ScopeRead, "servers", false, false},
+		{reader, ScopeWrite, "switches", false, false},
+		{admin, ScopeWrite, "servers", true, true},
+		{admin, ScopeAdmin, "", true, true},
+		{admin, ScopePing, "switches", false, false},
+		{Anonymous, ScopePing, "switches", true, true},
+		{&Principal{Name: "unauthenticated"}, ScopeRead, "switches", false, false},
+	}
+	for _, test := range tests {
+		if can := test.principal.Can(test.scope, test.topic); can != test.can {
+			t.Errorf("%s can %s '%s': %v, expected %v", test.principal.Name, test.scope, test.topic, can, test.can)
+		}
+		if canAll := test.principal.CanAll(test.scope); canAll != test.canAll {
+			t.Errorf("%s can %s all topics: %v, expected %v", test.principal.Name, test.scope, canAll, test.canAll)
+		}
+	}
+}
+
+func TestParseTokens(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		err   string
+	}{
+		{"no tokens", nil, ""},
+		{"token", []interface{}{map[string]interface{}{"name": "a", "token": "t", "topics": []interface{}{"*"}, "scopes": []interface{}{"read", "write"}}}, ""},
+		{"hash", []interface{}{map[string]interface{}{"name": "a", "token-sha256": Hash("t"), "topics": []interface{}{"*"}, "scopes": []interface{}{"read"}}}, ""},
+		{"wrong hash", []interface{}{map[string]interface{}{"name": "a", "token-sha256": "abc", "topics": []interface{}{"*"}, "scopes": []interface{}{"read"}}}, "token 'a': wrong 'token-sha256'"},
+		{"missing name", []interface{}{map[string]interface{}{"token": "t", "topics": []interface{}{"*"}, "scopes": []interface{}{"read"}}}, "token 0: missing 'name'"},
+		{"missing token", []interface{}{map[string]interface{}{"name": "a", "topics": []interface{}{"*"}, "scopes": []interface{}{"read"}}}, "token 'a': missing 'token' or 'token-sha256'"},
+		{"missing topics", []interface{}{map[string]interface{}{"name": "a", "token": "t", "scopes": []interface{}{"read"}}}, "token 'a': missing 'topics'"},
+		{"missing scopes", []interface{}{map[string]interface{}{"name": "a", "token": "t", "topics": []interface{}{"*"}}}, "token 'a': missing 'scopes'"},
+		{"unknown scope", []interface{}{map[string]interface{}{"name": "a", "token": "t", "topics": []interface{}{"*"}, "scopes": []interface{}{"root"}}}, "token 'a': unknown scope 'root', known scopes: [read ping write admin]"},
+		{"not table", []interface{}{"a"}, "tokens 0 should be table"},
+	}
+	for _, test := range tests {
+		_, err := ParseTokens(test.value)
+		message := ""
+		if err != nil {
+			message = err.Error()
+		}
+		if message != test.err {
+			t.Errorf("%s: error is '%s', expected '%s'", test.name, message, test.err)
+		}
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	tokens := []Token{
+		{Name: "reader", Hash: Hash("read-token"), Topics: []string{"switches"}, Scopes: []string{ScopeRead}},
+		{Name: "admin", Hash: Hash("admin-token"), Topics: []string{AllTopics}, Scopes: Scopes},
+	}
+	if err := Init(tokens, nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	defer Init(nil, nil, "")
+
+	tests := []struct {
+		name      string
+		headers   map[string]string
+		principal string // empty if request is rejected
+	}{
+		{"bearer", map[string]string{"Authorization": "Bearer read-token"}, "reader"},
+		{"bearer case", map[string]string{"Authorization": "bearer admin-token"}, "admin"},
+		{"api key", map[string]string{"X-API-Key": "admin-token"}, "admin"},
+		{"api key first", map[string]string{"X-API-Key": "read-token", "Authorization": "Bearer admin-token"}, "reader"},
+		{"wrong token", map[string]string{"Authorization": "Bearer other"}, ""},
+		{"basic auth", map[string]string{"Authorization": "Basic cmVhZC10b2tlbg=="}, ""},
+		{"no token", nil, ""},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1/dump-hosts", nil)
+		for name, value := range test.headers {
+			req.Header.Set(name, value)
+		}
+		p, ok := Authenticate(req)
+		name := ""
+		if ok {
+			name = p.Name
+		}
+		if name != test.principal {
+			t.Errorf("%s: principal is '%s', expected '%s'", test.name, name, test.principal)
+		}
+	}
+}
+
+func TestAuthenticateDuringReload(t *testing.T) {
+	// many tokens: config with reader token is loaded longer
+	var tokens []Token
+	for n := 0; n < 200; n++ {
+		tokens = append(tokens, Token{Name: "other", Hash: Hash(fmt.Sprintf("token-%d", n)), Topics: []string{AllTopics}, Scopes: []string{ScopeRead}})
+	}
+	tokens = append(tokens, Token{Name: "reader", Hash: Hash("read-token"), Topics: []string{"switches"}, Scopes: []string{ScopeRead}})
+	if err := Init(tokens, nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	defer Init(nil, nil, "")
+
+	reloaded := make(chan bool)
+	var requests sync.WaitGroup
+	for n := 0; n < 4; n++ {
+		requests.Add(1)
+		go func() {
+			defer requests.Done()
+			for {
+				select {
+				case <-reloaded:
+					return
+				default:
+				}
+				if p, ok := AuthenticateToken("read-token", nil); !ok || p.Name != "reader" {
+					t.Errorf("unchanged token is rejected during reload")
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 200; i++ {
+		if err := Init(tokens, nil, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(reloaded)
+	requests.Wait()
+}
+
+func TestAuthenticationDisabled(t *testing.T) {
+	if err := Init(nil, nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1/dump-hosts", nil)
+	if p, ok := Authenticate(req); !ok || p != Anonymous {
+		t.Errorf("principal without tokens is %v, expected anonymous", p)
+	}
+}
@@ -0,0 +1,243 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"pinger/logger"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Client certificate modes (`listen.client-auth`)
+const (
+	ClientAuthNone     = "none"     // client certificates are not requested
+	ClientAuthOptional = "optional" // certificate is verified if given, token can be used instead
+	ClientAuthRequire  = "require"  // every connection must have valid certificate
+)
+
+var subjectPrefixes = []string{"CN=", "DNS:", "URI:", "EMAIL:", "IP:"}
+
+/*
+TLSReloader - server tls config with certificate, key and client CA bundle, which are re-read
+when files change on disk (files are checked every CheckInterval)
+*/
+type TLSReloader struct {
+	CertFile      string
+	KeyFile       string
+	CAFile        string
+	ClientAuth    string
+	CheckInterval time.Duration
+
+	config   *tls.Config
+	modTimes map[string]time.Time
+	mx       sync.Mutex
+}
+
+// NewTLSReloader - load files and return reloader; start Watch() to follow changes
+func NewTLSReloader(certFile string, keyFile string, caFile string, clientAuth string) (*TLSReloader, error) {
+	t := &TLSReloader{CheckInterval: 10 * time.Second}
+	if err := t.Update(certFile, keyFile, caFile, clientAuth); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Update - switch to other files or client-auth mode (on config reload). On error previous config is kept
+func (t *TLSReloader) Update(certFile string, keyFile string, caFile string, clientAuth string) error {
+	if clientAuth == "" {
+		clientAuth = ClientAuthNone
+	}
+	if err := CheckClientAuth(caFile, clientAuth); err != nil {
+		return err
+	}
+
+	config, err := loadTLSConfig(certFile, keyFile, caFile, clientAuth)
+	if err != nil {
+		return err
+	}
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	t.CertFile, t.KeyFile, t.CAFile, t.ClientAuth = certFile, keyFile, caFile, clientAuth
+	t.config = config
+	t.modTimes = t.currentModTimes()
+	return nil
+}
+
+// Reload - read certificate, key and CA bundle. On error previous config is kept
+func (t *TLSReloader) Reload() error {
+	t.mx.Lock()
+	certFile, keyFile, caFile, clientAuth := t.CertFile, t.KeyFile, t.CAFile, t.ClientAuth
+	t.mx.Unlock()
+
+	config, err := loadTLSConfig(certFile, keyFile, caFile, clientAuth)
+	if err != nil {
+		return err
+	}
+	t.mx.Lock()
+	t.config = config
+	t.modTimes = t.currentModTimes()
+	t.mx.Unlock()
+	return nil
+}
+
+// CheckClientAuth - client-auth mode is known and has client CA if needed
+func CheckClientAuth(caFile string, clientAuth string) error {
+	switch clientAuth {
+	case ClientAuthNone:
+	case ClientAuthOptional, ClientAuthRequire:
+		if caFile == "" {
+			return fmt.Errorf("client-auth '%s' requires client-ca", clientAuth)
+		}
+	default:
+		return fmt.Errorf("unknown client-auth '%s', should be none, optional or require", clientAuth)
+	}
+	return nil
+}
+
+// CheckTLS - server certificate, key and client CA can be loaded (config check)
+func CheckTLS(certFile string, keyFile string, caFile string, clientAuth string) error {
+	if err := CheckClientAuth(caFile, clientAuth); err != nil {
+		return err
+	}
+	_, err := loadTLSConfig(certFile, keyFile, caFile, clientAuth)
+	return err
+}
+
+// loadTLSConfig - server config with certificate and client CA bundle (unless clientAuth is none)
+func loadTLSConfig(certFile string, keyFile string, caFile string, clientAuth string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load server certificate: %s", err.Error())
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+		NextProtos:   []string{"h2", "http/1.1"},
+		ClientAuth:   tls.NoClientCert,
+	}
+	if clientAuth != ClientAuthNone {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read client CA: %s", err.Error())
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in client CA '%s'", caFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+		if clientAuth == ClientAuthRequire {
+			config.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+	return config, nil
+}
+
+// Config - tls config for http.Server; every handshake uses the latest loaded files
+func (t *TLSReloader) Config() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2", "http/1.1"},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			t.mx.Lock()
+			defer t.mx.Unlock()
+			return t.config, nil
+		},
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			t.mx.Lock()
+			defer t.mx.Unlock()
+			return &t.config.Certificates[0], nil
+		},
+	}
+}
+
+// Watch - reload files when their modification time changes. Never returns
+func (t *TLSReloader) Watch() {
+	ticker := time.NewTicker(t.CheckInterval)
+	for range ticker.C {
+		t.mx.Lock()
+		changed := false
+		for file, modTime := range t.currentModTimes() {
+			if !modTime.Equal(t.modTimes[file]) {
+				changed = true
+			}
+		}
+		t.mx.Unlock()
+
+		if changed {
+			if err := t.Reload(); err != nil {
+				// files can be in the middle of update, next check will try again
+				logger.Err("[tls]: reload failed, keeping previous certificates: %s", err.Error())
+				continue
+			}
+			logger.Log("[tls]: certificates reloaded")
+		}
+	}
+}
+
+func (t *TLSReloader) currentModTimes() map[string]time.Time {
+	result := make(map[string]time.Time)
+	for _, file := range []string{t.CertFile, t.KeyFile, t.CAFile} {
+		if file == "" {
+			continue
+		}
+		if info, err := os.Stat(file); err == nil {
+			result[file] = info.ModTime()
+		}
+	}
+	return result
+}
+
+// certPrincipal - principal for verified client certificate of connection
+func certPrincipal(state *tls.ConnectionState) (*Principal, bool) {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil, false
+	}
+	subjects := CertSubjects(state.VerifiedChains[0][0])
+
+	mx.Lock()
+	defer mx.Unlock()
+	for _, c := range certs {
+		for _, s := range c.Subjects {
+			if subjects[s] {
+				return &Principal{Name: c.Name, Topics: c.Topics, Scopes: c.Scopes}, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// CertSubjects - set of prefixed subject values of certificate: common name and SANs
+func CertSubjects(cert *x509.Certificate) map[string]bool {
+	subjects := make(map[string]bool)
+	if cert.Subject.CommonName != "" {
+		subjects["CN="+cert.Subject.CommonName] = true
+	}
+	for _, name := range cert.DNSNames {
+		subjects["DNS:"+name] = true
+	}
+	for _, email := range cert.EmailAddresses {
+		subjects["EMAIL:"+email] = true
+	}
+	for _, uri := range cert.URIs {
+		subjects["URI:"+uri.String()] = true
+	}
+	for _, ip := range cert.IPAddresses {
+		subjects["IP:"+ip.String()] = true
+	}
+	return subjects
+}
+
+func isSubject(subject string) bool {
+	for _, prefix := range subjectPrefixes {
+		if strings.HasPrefix(subject, prefix) && len(subject) > len(prefix) {
+			return true
+		}
+	}
+	return false
+}
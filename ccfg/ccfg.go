@@ -1,69 +1,211 @@
 package ccfg
 
 import (
+	"fmt"
 	"github.com/spf13/viper"
+	"pinger/auth"
+	"pinger/history"
+	"pinger/notify"
+	"pinger/pools"
+	"time"
 )
 
 // Cfg - struct with config parameters
 type Cfg struct {
 	ListenIP        string
 	ListenPort      string
+	GrpcPort        string
 	SslCert         string
 	SslKey          string
+	SslClientCA     string
+	SslClientAuth   string
+	AllowedOrigins  []string
 	LogPath         string
 	ResultURL       string
+	ResultMethod    string
+	ResultHeaders   map[string]string
+	ResultBody      string
+	ResultSecret    string
+	UpdateSecret    string
 	SavePath		string
 	DefaultProbes   int
 	DefaultInterval int64
 	UpdatesInterval	int64
 	SaveInterval	int64
+	NotifySpoolPath	string
+	NotifyRetryMax	int64
+	EventLogSize	int
+	ShutdownTimeout	int64
+	DNSMinInterval	int64
+	DNSMaxInterval	int64
+	DegradedLoss	float64
+	DegradedRtt		float64
 	LogDebug        bool
 	Ssl             bool
-}
 
-// New - creating new instance of config struct
-func New(path *string) *Cfg {
+	HistoryPath      string
+	HistoryRetention int64
+	Maintenance      []history.Maintenance
+
+	Notifiers map[string]map[string]interface{}
+
+	// Topics - topics declared in config: name => topic json document (see pools.DBPool.SetStatic)
+	Topics map[string]interface{}
+
+	Tokens    []auth.Token
+	Certs     []auth.Cert
+	TokenFile string
+}
 
-	viper.SetConfigFile(*path)
-	err := viper.ReadInConfig()
+/*
+Load - read and validate config file; used on start, on reload and by -check-config (global viper instance is not touched).
+Invalid values are returned as *ValidationError
+*/
+func Load(path string) (*Cfg, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	err := v.ReadInConfig()
 	if err != nil {
-		panic(err.Error())
+		return nil, fmt.Errorf("cannot read config '%s': %s", path, err.Error())
 	}
 
 	c := new(Cfg)
-	viper.SetDefault("listen.ip", "0.0.0.0")
-	viper.SetDefault("listen.port", "1081")
-	viper.SetDefault("listen.ssl", false)
-	viper.SetDefault("log.path", "/var/log/pinger.log")
-	viper.SetDefault("pinger.save-path", "")
-	viper.SetDefault("log.debug", true)
-	viper.SetDefault("pinger.default-probes", 3)
-	viper.SetDefault("pinger.default-interval", 120)
-	viper.SetDefault("pinger.updates-interval", 30)
-	viper.SetDefault("pinger.save-interval", 180)
-
-	c.ListenIP = viper.GetString("listen.ip")
-	c.ListenPort = viper.GetString("listen.port")
-	c.Ssl = viper.GetBool("listen.ssl")
-	c.SslCert = viper.GetString("listen.cert")
-	c.SslKey = viper.GetString("listen.key")
-
-	c.LogPath = viper.GetString("log.path")
-	c.LogDebug = viper.GetBool("log.debug")
-
-	c.ResultURL = viper.GetString("pinger.result-url")
-	c.DefaultProbes = viper.GetInt("pinger.default-probes")
-	c.DefaultInterval = viper.GetInt64("pinger.default-interval")
-	c.UpdatesInterval = viper.GetInt64("pinger.updates-interval")
-	c.SaveInterval = viper.GetInt64("pinger.save-interval")
-	c.SavePath = viper.GetString("pinger.save-path")
-
-	// if ssl is enabled, cert & key must exist
-	if c.Ssl {
-		if c.SslKey == "" || c.SslCert == "" {
-			panic("SSL Key and cert must be defined if SSL is enabled!")
+	v.SetDefault("listen.ip", "0.0.0.0")
+	v.SetDefault("listen.port", "1081")
+	v.SetDefault("listen.ssl", false)
+	v.SetDefault("listen.client-auth", "none")
+	v.SetDefault("log.path", "/var/log/pinger.log")
+	v.SetDefault("pinger.save-path", "")
+	v.SetDefault("log.debug", true)
+	v.SetDefault("pinger.default-probes", 3)
+	v.SetDefault("pinger.default-interval", 120)
+	v.SetDefault("pinger.updates-interval", 30)
+	v.SetDefault("pinger.save-interval", 180)
+	v.SetDefault("pinger.notify-spool-path", "")
+	v.SetDefault("pinger.notify-retry-max", 3600)
+	v.SetDefault("pinger.event-log-size", notify.DefaultLogSize)
+	v.SetDefault("pinger.shutdown-timeout", 30)
+	v.SetDefault("pinger.dns-min-interval", int64(pools.DefaultMinResolve/time.Second))
+	v.SetDefault("pinger.dns-max-interval", int64(pools.DefaultMaxResolve/time.Second))
+	v.SetDefault("sla.history-path", "")
+	v.SetDefault("sla.retention-days", 400)
+
+	c.ListenIP = v.GetString("listen.ip")
+	c.ListenPort = v.GetString("listen.port")
+	c.GrpcPort = v.GetString("listen.grpc-port")
+	c.Ssl = v.GetBool("listen.ssl")
+	c.SslCert = v.GetString("listen.cert")
+	c.SslKey = v.GetString("listen.key")
+	c.SslClientCA = v.GetString("listen.client-ca")
+	c.SslClientAuth = v.GetString("listen.client-auth")
+	c.AllowedOrigins = v.GetStringSlice("listen.allowed-origins")
+
+	c.LogPath = v.GetString("log.path")
+	c.LogDebug = v.GetBool("log.debug")
+
+	c.ResultURL = v.GetString("pinger.result-url")
+	c.ResultMethod = v.GetString("pinger.result-method")
+	c.ResultHeaders = v.GetStringMapString("pinger.result-headers")
+	c.ResultBody = v.GetString("pinger.result-body")
+	c.ResultSecret = v.GetString("pinger.result-secret")
+	c.UpdateSecret = v.GetString("pinger.update-secret")
+	c.DefaultProbes = v.GetInt("pinger.default-probes")
+	c.DefaultInterval = v.GetInt64("pinger.default-interval")
+	c.UpdatesInterval = v.GetInt64("pinger.updates-interval")
+	c.SaveInterval = v.GetInt64("pinger.save-interval")
+	c.SavePath = v.GetString("pinger.save-path")
+	c.NotifySpoolPath = v.GetString("pinger.notify-spool-path")
+	c.NotifyRetryMax = v.GetInt64("pinger.notify-retry-max")
+	c.EventLogSize = v.GetInt("pinger.event-log-size")
+	c.ShutdownTimeout = v.GetInt64("pinger.shutdown-timeout")
+	c.DNSMinInterval = v.GetInt64("pinger.dns-min-interval")
+	c.DNSMaxInterval = v.GetInt64("pinger.dns-max-interval")
+	c.DegradedLoss = v.GetFloat64("pinger.degraded-loss")
+	c.DegradedRtt = v.GetFloat64("pinger.degraded-rtt")
+
+	c.Notifiers = make(map[string]map[string]interface{})
+	for name := range v.GetStringMap("notifiers") {
+		c.Notifiers[name] = v.GetStringMap("notifiers." + name)
+	}
+
+	c.Topics, err = parseTopics(v.Get("topics"))
+	if err != nil {
+		return nil, err
+	}
+
+	c.HistoryPath = v.GetString("sla.history-path")
+	c.HistoryRetention = v.GetInt64("sla.retention-days")
+	c.Maintenance, err = parseMaintenance(v.Get("sla.maintenance"))
+	if err != nil {
+		return nil, err
+	}
+
+	c.TokenFile = v.GetString("auth.token-file")
+	c.Tokens, err = auth.ParseTokens(v.Get("auth.tokens"))
+	if err != nil {
+		return nil, fmt.Errorf("auth.tokens: %s", err.Error())
+	}
+	c.Certs, err = auth.ParseCerts(v.Get("auth.certs"))
+	if err != nil {
+		return nil, fmt.Errorf("auth.certs: %s", err.Error())
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// parseMaintenance - parse [[sla.maintenance]] tables
+func parseMaintenance(value interface{}) ([]history.Maintenance, error) {
+	result := make([]history.Maintenance, 0)
+	if value == nil {
+		return result, nil
+	}
+
+	list, ok := value.([]interface{})
+	if !ok {
+		if maps, ok := value.([]map[string]interface{}); ok {
+			for _, m := range maps {
+				list = append(list, m)
+			}
+		} else {
+			return nil, fmt.Errorf("sla.maintenance should be array of tables")
+		}
+	}
+
+	for n, item := range list {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("sla.maintenance %d should be table", n)
+		}
+		window := history.Maintenance{}
+		window.Topic, _ = m["topic"].(string)
+		window.Host, _ = m["host"].(string)
+
+		var err error
+		if window.From, err = parseTime(m["from"]); err != nil {
+			return nil, fmt.Errorf("sla.maintenance %d: wrong 'from': %s", n, err.Error())
+		}
+		if window.To, err = parseTime(m["to"]); err != nil {
+			return nil, fmt.Errorf("sla.maintenance %d: wrong 'to': %s", n, err.Error())
 		}
+		if !window.To.After(window.From) {
+			return nil, fmt.Errorf("sla.maintenance %d: 'to' must be after 'from'", n)
+		}
+		result = append(result, window)
 	}
 
-	return c
+	return result, nil
+}
+
+// parseTime - toml datetime or RFC3339 string
+func parseTime(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		return time.Parse(time.RFC3339, v)
+	}
+	return time.Time{}, fmt.Errorf("%v is not a time", value)
 }
@@ -0,0 +1,95 @@
+package ccfg
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// load - load config with given contents from dir; log is written to the same dir
+func load(t *testing.T, dir string, contents string) (*Cfg, error) {
+	path := filepath.Join(dir, "pinger.toml")
+	contents = "[log]\npath = \"" + filepath.Join(dir, "pinger.log") + "\"\n" + contents
+	if err := ioutil.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return Load(path)
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		problems []string // beginnings of expected problems
+	}{
+		{"defaults", "", nil},
+		{"wrong ip", "[listen]\nip = \"localhost\"\n", []string{"listen.ip: 'localhost' is not ip address"}},
+		{"same ports", "[listen]\nport = 8001\ngrpc-port = 8001\n", []string{"listen.grpc-port: should differ from listen.port"}},
+		{"client certs without ssl", "[listen]\nclient-auth = \"require\"\n", []string{"listen.client-auth: "}},
+		{"dns intervals", "[pinger]\ndns-min-interval = 10\ndns-max-interval = 5\n", []string{"pinger.dns-max-interval: should be >= dns-min-interval (10), got 5"}},
+		{"all problems", "[listen]\nip = \"x\"\n[pinger]\ndegraded-loss = 150\nevent-log-size = 0\n", []string{
+			"listen.ip: 'x' is not ip address",
+			"pinger.event-log-size: should be >= 1, got 0",
+			"pinger.degraded-loss: should be percent (0-100), got 150",
+		}},
+		{"unknown notifier type", "[notifiers.a]\ntype = \"nope\"\n", []string{"notifiers: "}},
+		{"topic", "[notifiers.log]\ntype = \"log\"\n[topics.a]\nnotifiers = [\"log\"]\nhosts = [\"10.0.0.1\", {host = \"10.0.0.2\", probes = 5}]\n", nil},
+		{"topic with unknown notifier", "[notifiers.log]\ntype = \"log\"\n[topics.a]\nnotifiers = [\"nope\"]\n", []string{"topics: a.Notifiers: unknown notifier 'nope'"}},
+		{"host with unknown notifier", "[notifiers.log]\ntype = \"log\"\n[topics.a]\nhosts = [\"10.0.0.1\", {host = \"10.0.0.2\", notifiers = [\"nope\"]}]\n", []string{"topics: a.Hosts[1].Notifiers: unknown notifier 'nope'"}},
+		{"wrong update url template", "[topics.a]\nupdate-url = \"http://127.0.0.1/{{.Host\"\n", []string{"topics: a.UpdateURL: "}},
+	}
+	for _, test := range tests {
+		_, err := load(t, t.TempDir(), test.config)
+		var problems []string
+		var ve *ValidationError
+		if errors.As(err, &ve) {
+			problems = ve.Problems
+		} else if err != nil {
+			t.Errorf("%s: error is not validation error: %s", test.name, err.Error())
+			continue
+		}
+		if len(problems) != len(test.problems) {
+			t.Errorf("%s: problems are %q, expected %q", test.name, problems, test.problems)
+			continue
+		}
+		for n, problem := range test.problems {
+			if !strings.HasPrefix(problems[n], problem) {
+				t.Errorf("%s: problem is '%s', expected '%s'", test.name, problems[n], problem)
+			}
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	const base = "[listen]\nport = 8001\n[pinger]\nresult-url = \"http://127.0.0.1/result\"\n[topics.a]\nhosts = [\"10.0.0.1\"]\n"
+	tests := []struct {
+		name    string
+		config  string
+		live    []string
+		restart []string
+	}{
+		{"same", base, nil, nil},
+		{"port", strings.Replace(base, "8001", "8002", 1), nil, []string{"listen.port"}},
+		{"result url", strings.Replace(base, "/result", "/results", 1), []string{"pinger.result-url"}, nil},
+		{"topic host", strings.Replace(base, "10.0.0.1", "10.0.0.2", 1), []string{"topics"}, nil},
+		{"live and restart", "[listen]\nport = 8002\nallowed-origins = [\"https://noc.local\"]\n[pinger]\nresult-url = \"http://127.0.0.1/result\"\nsave-interval = 60\n[topics.a]\nhosts = [\"10.0.0.1\"]\n",
+			[]string{"listen.allowed-origins"}, []string{"listen.port", "pinger.save-interval"}},
+	}
+	dir := t.TempDir()
+	for _, test := range tests {
+		old, err := load(t, dir, base)
+		if err != nil {
+			t.Fatal(err)
+		}
+		changed, err := load(t, dir, test.config)
+		if err != nil {
+			t.Fatalf("%s: %s", test.name, err.Error())
+		}
+		live, restart := Diff(old, changed)
+		if strings.Join(live, ",") != strings.Join(test.live, ",") || strings.Join(restart, ",") != strings.Join(test.restart, ",") {
+			t.Errorf("%s: changed settings are %v (restart %v), expected %v (restart %v)", test.name, live, restart, test.live, test.restart)
+		}
+	}
+}
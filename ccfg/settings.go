@@ -0,0 +1,159 @@
+package ccfg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+/*
+setting - config key with it's value in Cfg. Live settings are applied to running daemon on reload,
+others (listeners, storage paths) take effect only after restart
+*/
+type setting struct {
+	Key   string
+	Live  bool
+	value func(c *Cfg) interface{}
+}
+
+var settings = []setting{
+	{"listen.ip", false, func(c *Cfg) interface{} { return c.ListenIP }},
+	{"listen.port", false, func(c *Cfg) interface{} { return c.ListenPort }},
+	{"listen.grpc-port", false, func(c *Cfg) interface{} { return c.GrpcPort }},
+	{"listen.ssl", false, func(c *Cfg) interface{} { return c.Ssl }},
+	{"listen.cert", true, func(c *Cfg) interface{} { return c.SslCert }},
+	{"listen.key", true, func(c *Cfg) interface{} { return c.SslKey }},
+	{"listen.client-ca", true, func(c *Cfg) interface{} { return c.SslClientCA }},
+	{"listen.client-auth", true, func(c *Cfg) interface{} { return c.SslClientAuth }},
+	{"listen.allowed-origins", true, func(c *Cfg) interface{} { return c.AllowedOrigins }},
+	{"log.path", true, func(c *Cfg) interface{} { return c.LogPath }},
+	{"log.debug", true, func(c *Cfg) interface{} { return c.LogDebug }},
+	{"pinger.result-url", true, func(c *Cfg) interface{} { return c.ResultURL }},
+	{"pinger.result-method", true, func(c *Cfg) interface{} { return c.ResultMethod }},
+	{"pinger.result-headers", true, func(c *Cfg) interface{} { return c.ResultHeaders }},
+	{"pinger.result-body", true, func(c *Cfg) interface{} { return c.ResultBody }},
+	{"pinger.result-secret", true, func(c *Cfg) interface{} { return c.ResultSecret }},
+	{"pinger.update-secret", true, func(c *Cfg) interface{} { return c.UpdateSecret }},
+	{"pinger.default-probes", true, func(c *Cfg) interface{} { return c.DefaultProbes }},
+	{"pinger.default-interval", true, func(c *Cfg) interface{} { return c.DefaultInterval }},
+	{"pinger.updates-interval", true, func(c *Cfg) interface{} { return c.UpdatesInterval }},
+	{"pinger.save-interval", false, func(c *Cfg) interface{} { return c.SaveInterval }},
+	{"pinger.save-path", false, func(c *Cfg) interface{} { return c.SavePath }},
+	{"pinger.notify-spool-path", true, func(c *Cfg) interface{} { return c.NotifySpoolPath }},
+	{"pinger.notify-retry-max", true, func(c *Cfg) interface{} { return c.NotifyRetryMax }},
+	{"pinger.event-log-size", true, func(c *Cfg) interface{} { return c.EventLogSize }},
+	{"pinger.shutdown-timeout", true, func(c *Cfg) interface{} { return c.ShutdownTimeout }},
+	{"pinger.dns-min-interval", true, func(c *Cfg) interface{} { return c.DNSMinInterval }},
+	{"pinger.dns-max-interval", true, func(c *Cfg) interface{} { return c.DNSMaxInterval }},
+	{"pinger.degraded-loss", true, func(c *Cfg) interface{} { return c.DegradedLoss }},
+	{"pinger.degraded-rtt", true, func(c *Cfg) interface{} { return c.DegradedRtt }},
+	{"sla.history-path", false, func(c *Cfg) interface{} { return c.HistoryPath }},
+	{"sla.retention-days", true, func(c *Cfg) interface{} { return c.HistoryRetention }},
+	{"sla.maintenance", true, func(c *Cfg) interface{} { return c.Maintenance }},
+	{"notifiers", true, func(c *Cfg) interface{} { return c.Notifiers }},
+	{"topics", true, func(c *Cfg) interface{} { return c.Topics }},
+	{"auth.tokens", true, func(c *Cfg) interface{} { return c.Tokens }},
+	{"auth.certs", true, func(c *Cfg) interface{} { return c.Certs }},
+	{"auth.token-file", true, func(c *Cfg) interface{} { return c.TokenFile }},
+}
+
+/*
+Diff - keys of settings changed in new config: live ones can be applied on reload, restart ones cannot.
+Values are not returned, they can be secrets
+*/
+func Diff(old *Cfg, new *Cfg) (live []string, restart []string) {
+	for _, s := range settings {
+		if reflect.DeepEqual(s.value(old), s.value(new)) {
+			continue
+		}
+		if s.Live {
+			live = append(live, s.Key)
+		} else {
+			restart = append(restart, s.Key)
+		}
+	}
+	return live, restart
+}
+
+// secretKeys - settings, which values are hidden in Dump
+var secretKeys = map[string]bool{
+	"pinger.result-secret": true,
+	"pinger.update-secret": true,
+}
+
+// secretWords - nested keys (notifier parameters, headers, tokens), which values are hidden in Dump
+var secretWords = []string{"secret", "password", "passphrase", "token", "community", "hash", "authorization", "api-key"}
+
+const hidden = "<hidden>"
+
+/*
+Dump - write effective config (defaults are applied) as "key = value" lines, values are json.
+Secrets, passwords and token hashes are hidden
+*/
+func Dump(w io.Writer, c *Cfg) error {
+	for _, s := range settings {
+		value := s.value(c)
+		if secretKeys[s.Key] {
+			if value != "" {
+				value = hidden
+			}
+		} else {
+			value = hideSecrets(plain(value))
+		}
+		var line bytes.Buffer
+		encoder := json.NewEncoder(&line)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(value); err != nil {
+			return fmt.Errorf("%s: %s", s.Key, err.Error())
+		}
+		// Encode adds newline
+		if _, err := fmt.Fprintf(w, "%s = %s", s.Key, line.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// plain - value as maps, slices and scalars (structs are converted through json)
+func plain(value interface{}) interface{} {
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var result interface{}
+	if json.Unmarshal(bytes, &result) != nil {
+		return value
+	}
+	return result
+}
+
+func hideSecrets(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if isSecret(key) {
+				v[key] = hidden
+			} else {
+				v[key] = hideSecrets(item)
+			}
+		}
+	case []interface{}:
+		for n, item := range v {
+			v[n] = hideSecrets(item)
+		}
+	}
+	return value
+}
+
+func isSecret(key string) bool {
+	key = strings.ToLower(key)
+	for _, word := range secretWords {
+		if strings.Contains(key, word) {
+			return true
+		}
+	}
+	return false
+}
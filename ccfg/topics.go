@@ -0,0 +1,162 @@
+package ccfg
+
+import (
+	"fmt"
+	"pinger/notify"
+	"pinger/pools"
+	"sort"
+)
+
+// topicKeys - keys of [topics.<name>] tables and their names in topic json (see pools.ParseTopics)
+var topicKeys = map[string]string{
+	"probes":         "Probes",
+	"interval":       "Interval",
+	"update-url":     "UpdateURL",
+	"update-secret":  "UpdateSecret",
+	"update-format":  "UpdateFormat",
+	"update-method":  "UpdateMethod",
+	"update-headers": "UpdateHeaders",
+	"update-body":    "UpdateBody",
+	"labels":         "Labels",
+	"notifiers":      "Notifiers",
+	"resolve":        "Resolve",
+}
+
+/*
+parseTopics - convert [topics.<name>] tables with [[topics.<name>.hosts]] to topic json documents,
+which are stored by pools.TopicPool.SetStatic. Hosts can be tables or just ip (dns name) strings
+*/
+func parseTopics(value interface{}) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	if value == nil {
+		return result, nil
+	}
+	tables, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("topics should be table of topic tables")
+	}
+
+	for name, table := range tables {
+		params, ok := table.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("topics.%s should be table", name)
+		}
+		doc, err := convertKeys("topics."+name, params, true)
+		if err != nil {
+			return nil, err
+		}
+
+		hosts := make([]interface{}, 0)
+		for n, item := range tableList(params["hosts"]) {
+			key := fmt.Sprintf("topics.%s.hosts %d", name, n)
+			switch h := item.(type) {
+			case string:
+				item = map[string]interface{}{"host": h}
+			case map[string]interface{}:
+				if item, err = convertKeys(key, h, false); err != nil {
+					return nil, err
+				}
+			default:
+				return nil, fmt.Errorf("%s should be ip, dns name or table", key)
+			}
+			host, _ := item.(map[string]interface{})["host"].(string)
+			if _, ok := pools.HostKey(host); !ok {
+				return nil, fmt.Errorf("%s: host should be ip address or dns name, got '%v'", key, item.(map[string]interface{})["host"])
+			}
+			hosts = append(hosts, item)
+		}
+		if params["hosts"] != nil && len(hosts) == 0 {
+			return nil, fmt.Errorf("topics.%s.hosts should be array of tables, ip addresses or dns names", name)
+		}
+		doc["Hosts"] = hosts
+		result[name] = doc
+	}
+	return result, nil
+}
+
+// convertKeys - check keys and values of topic or host table and rename keys to json ones
+func convertKeys(key string, params map[string]interface{}, topic bool) (map[string]interface{}, error) {
+	doc := make(map[string]interface{})
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := params[name]
+		switch {
+		case topic && name == "hosts":
+			continue
+		case !topic && name == "host":
+			doc["host"] = value
+			continue
+		case topicKeys[name] == "":
+			return nil, fmt.Errorf("%s: unknown key '%s'", key, name)
+		}
+
+		switch name {
+		case "probes", "interval":
+			number, ok := integer(value)
+			if !ok || number < 1 {
+				return nil, fmt.Errorf("%s.%s should be integer >= 1, got %v", key, name, value)
+			}
+		case "update-format":
+			if format, ok := value.(string); !ok || !notify.IsFormat(format) {
+				return nil, fmt.Errorf("%s.%s: unknown format '%v', known formats: %v", key, name, value, notify.Formats)
+			}
+		case "resolve":
+			if mode, ok := value.(string); !ok || !contains(pools.ResolveModes, mode) {
+				return nil, fmt.Errorf("%s.%s: unknown resolve mode '%v', known modes: %v", key, name, value, pools.ResolveModes)
+			}
+		case "update-headers", "labels":
+			if _, ok := value.(map[string]interface{}); !ok {
+				return nil, fmt.Errorf("%s.%s should be table", key, name)
+			}
+		case "notifiers":
+			if _, ok := value.([]interface{}); !ok {
+				return nil, fmt.Errorf("%s.%s should be array of notifier names", key, name)
+			}
+		default:
+			if _, ok := value.(string); !ok {
+				return nil, fmt.Errorf("%s.%s should be string", key, name)
+			}
+		}
+		doc[topicKeys[name]] = value
+	}
+	return doc, nil
+}
+
+// tableList - array of tables, as it's returned by viper ([]map or []interface{})
+func tableList(value interface{}) []interface{} {
+	if maps, ok := value.([]map[string]interface{}); ok {
+		list := make([]interface{}, 0, len(maps))
+		for _, m := range maps {
+			list = append(list, m)
+		}
+		return list
+	}
+	list, _ := value.([]interface{})
+	return list
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func integer(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	case float64:
+		return int64(v), float64(int64(v)) == v
+	}
+	return 0, false
+}
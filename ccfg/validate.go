@@ -0,0 +1,167 @@
+package ccfg
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"pinger/auth"
+	"pinger/notify"
+	"pinger/pinger"
+	"pinger/pools"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ValidationError - all problems found in config, each one is "key: problem"
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid config: " + strings.Join(e.Problems, "; ")
+}
+
+// validator - collects problems of config
+type validator struct {
+	problems []string
+}
+
+func (v *validator) add(key string, format string, args ...interface{}) {
+	v.problems = append(v.problems, key+": "+fmt.Sprintf(format, args...))
+}
+
+func (v *validator) min(key string, value int64, min int64) {
+	if value < min {
+		v.add(key, "should be >= %d, got %d", min, value)
+	}
+}
+
+func (v *validator) port(key string, value string) {
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		v.add(key, "'%s' is not port number (1-65535)", value)
+	}
+}
+
+// writable - file can be written: it's directory exists and is writable, existing file is writable too
+func (v *validator) writable(key string, path string) {
+	if path == "" {
+		return
+	}
+	if info, err := os.Stat(path); err == nil {
+		if info.IsDir() {
+			v.add(key, "'%s' is directory", path)
+			return
+		}
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			v.add(key, "'%s' is not writable: %s", path, err.Error())
+			return
+		}
+		file.Close()
+	} else if !os.IsNotExist(err) {
+		v.add(key, "%s", err.Error())
+		return
+	}
+
+	// files are rewritten through temporary file in the same directory
+	dir := filepath.Dir(path)
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		v.add(key, "directory '%s' doesn't exist", dir)
+		return
+	}
+	file, err := ioutil.TempFile(dir, ".pinger-check-")
+	if err != nil {
+		v.add(key, "directory '%s' is not writable", dir)
+		return
+	}
+	file.Close()
+	os.Remove(file.Name())
+}
+
+/*
+Validate - check values, files and notifiers of config. All problems are returned at once as *ValidationError.
+Files are checked on disk, so config is valid only on this host
+*/
+func (c *Cfg) Validate() error {
+	v := &validator{}
+
+	if net.ParseIP(c.ListenIP) == nil {
+		v.add("listen.ip", "'%s' is not ip address", c.ListenIP)
+	}
+	v.port("listen.port", c.ListenPort)
+	if c.GrpcPort != "" {
+		v.port("listen.grpc-port", c.GrpcPort)
+		if c.GrpcPort == c.ListenPort {
+			v.add("listen.grpc-port", "should differ from listen.port")
+		}
+	}
+	if c.Ssl {
+		if c.SslCert == "" || c.SslKey == "" {
+			v.add("listen.cert", "cert and key are required with listen.ssl")
+		} else if err := auth.CheckTLS(c.SslCert, c.SslKey, c.SslClientCA, c.SslClientAuth); err != nil {
+			v.add("listen.cert", "%s", err.Error())
+		}
+	} else if err := auth.CheckClientAuth(c.SslClientCA, c.SslClientAuth); err != nil {
+		v.add("listen.client-auth", "%s", err.Error())
+	} else if c.SslClientAuth != auth.ClientAuthNone {
+		v.add("listen.client-auth", "client certificates require listen.ssl")
+	}
+
+	v.writable("log.path", c.LogPath)
+
+	if err := (&pinger.PingDaemon{}).SetResultRequest(c.ResultMethod, c.ResultURL, c.ResultHeaders, c.ResultBody, c.ResultSecret); err != nil {
+		v.add("pinger.result-url", "%s", err.Error())
+	}
+	v.min("pinger.default-probes", int64(c.DefaultProbes), 1)
+	v.min("pinger.default-interval", c.DefaultInterval, 1)
+	v.min("pinger.updates-interval", c.UpdatesInterval, 1)
+	v.min("pinger.save-interval", c.SaveInterval, 1)
+	v.min("pinger.notify-retry-max", c.NotifyRetryMax, 0)
+	v.min("pinger.event-log-size", int64(c.EventLogSize), 1)
+	v.min("pinger.shutdown-timeout", c.ShutdownTimeout, 0)
+	v.min("pinger.dns-min-interval", c.DNSMinInterval, 1)
+	if c.DNSMaxInterval < c.DNSMinInterval {
+		v.add("pinger.dns-max-interval", "should be >= dns-min-interval (%d), got %d", c.DNSMinInterval, c.DNSMaxInterval)
+	}
+	if c.DegradedLoss < 0 || c.DegradedLoss > 100 {
+		v.add("pinger.degraded-loss", "should be percent (0-100), got %g", c.DegradedLoss)
+	}
+	if c.DegradedRtt < 0 {
+		v.add("pinger.degraded-rtt", "should be >= 0, got %g", c.DegradedRtt)
+	}
+	v.writable("pinger.save-path", c.SavePath)
+	v.writable("pinger.notify-spool-path", c.NotifySpoolPath)
+
+	v.writable("sla.history-path", c.HistoryPath)
+	v.min("sla.retention-days", c.HistoryRetention, 0)
+
+	names := make([]string, 0, len(c.Notifiers))
+	for name := range c.Notifiers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := notify.Check(name, c.Notifiers[name]); err != nil {
+			v.add("notifiers", "%s", err.Error())
+		}
+	}
+
+	if err := pools.CheckStatic(c.Topics, c.DefaultProbes, c.DefaultInterval, names); err != nil {
+		v.add("topics", "%s", err.Error())
+	}
+
+	if c.TokenFile != "" {
+		if _, _, err := auth.LoadFile(c.TokenFile); err != nil {
+			v.add("auth.token-file", "%s", err.Error())
+		}
+	}
+
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package ctl
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Client - pinger http API client used by ctl commands
+type Client struct {
+	Addr  string // base url, like http://127.0.0.1:8001
+	Token string
+	HTTP  *http.Client
+}
+
+// APIError - failed API request, body is web.Error
+type APIError struct {
+	Status  int
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Field   string `json:"field"`
+}
+
+func (e *APIError) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("%s: %s (%d %s)", e.Field, e.Message, e.Status, e.Code)
+	}
+	return fmt.Sprintf("%s (%d %s)", e.Message, e.Status, e.Code)
+}
+
+// NewClient - client for address; scheme is http if not given
+func NewClient(addr string, token string, insecure bool, timeout time.Duration) *Client {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return &Client{
+		Addr:  strings.TrimSuffix(addr, "/"),
+		Token: token,
+		HTTP:  &http.Client{Timeout: timeout, Transport: transport},
+	}
+}
+
+/*
+Do - send request and decode json response to out (if not nil). Body is sent as json if not nil.
+Error responses are returned as *APIError
+*/
+func (c *Client) Do(method string, path string, query url.Values, body []byte, out interface{}) error {
+	target := c.Addr + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(method, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("X-API-Key", c.Token)
+	}
+
+	response, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode >= 300 {
+		apiErr := &APIError{Status: response.StatusCode}
+		if json.Unmarshal(data, apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(data))
+			if apiErr.Message == "" {
+				apiErr.Message = http.StatusText(response.StatusCode)
+			}
+		}
+		return apiErr
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("cannot parse response of %s: %w", path, err)
+	}
+	return nil
+}
+
+// topicPath - /v1/topics/{name} with optional sub-resources: topicPath("sw", "hosts", "10.0.0.1")
+func topicPath(name string, parts ...string) string {
+	path := "/v1/topics/" + url.PathEscape(name)
+	for _, part := range parts {
+		path += "/" + url.PathEscape(part)
+	}
+	return path
+}
@@ -0,0 +1,496 @@
+package ctl
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"pinger/notify"
+	"pinger/pinger"
+	"pinger/pools"
+	"pinger/web"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Environment variables for address and token flags
+const (
+	EnvAddr     = "PINGER_ADDR"
+	EnvToken    = "PINGER_TOKEN"
+	DefaultAddr = "http://127.0.0.1:8001"
+)
+
+const usage = `Usage: pinger ctl [flags] <command> [command flags] [args]
+
+Commands:
+  topics                                 topics with host counts by state
+  hosts [-topic t] [-state s] [-label l] [-ip prefix] [-sort key]
+                                         hosts with state and last check (filters as in /dump-hosts)
+  add-host [-probes n] [-interval sec] [-label k=v] [-notifier name] <topic> <ip>
+                                         add host to topic or replace it's parameters
+  remove-host <topic> <ip>               remove host from topic
+  ping [-probes n] [-topic t] <host>     ping host right now
+  history [-host ip] [-from t] [-to t] <topic>
+                                         state transitions of topic hosts (time is RFC3339 or unix timestamp)
+  export [-topic t]                      print topics document for import, with update secrets and host state
+                                         (requires write scope)
+  import [-replace] [file]               store topics document from file or stdin with host state;
+                                         with -replace topics are replaced, hosts missing in document are removed
+
+Flags:
+`
+
+// errUsage - wrong command arguments, usage is printed
+var errUsage = errors.New("wrong arguments")
+
+// ctl - command context: api client and output
+type ctl struct {
+	client *Client
+	json   bool
+	in     io.Reader
+	out    io.Writer
+	errOut io.Writer
+}
+
+var commands = map[string]func(c *ctl, args []string) error{
+	"topics":      (*ctl).topics,
+	"hosts":       (*ctl).hosts,
+	"add-host":    (*ctl).addHost,
+	"remove-host": (*ctl).removeHost,
+	"ping":        (*ctl).ping,
+	"history":     (*ctl).history,
+	"export":      (*ctl).export,
+	"import":      (*ctl).importTopics,
+}
+
+/*
+Run - `pinger ctl` entry point: args are arguments after "ctl". Address and token are taken from flags
+or PINGER_ADDR / PINGER_TOKEN. Returns exit code: 1 - request failed, 2 - wrong arguments
+*/
+func Run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("pinger ctl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	addr := flags.String("addr", envOr(EnvAddr, DefaultAddr), "pinger http(s) address, env "+EnvAddr)
+	token := flags.String("token", "", "API token (default env "+EnvToken+")")
+	asJSON := flags.Bool("json", false, "json output instead of table")
+	insecure := flags.Bool("insecure", false, "do not verify server certificate")
+	timeout := flags.Duration("timeout", time.Minute, "request timeout")
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+	run, ok := commands[flags.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command '%s'\n\n", flags.Arg(0))
+		flags.Usage()
+		return 2
+	}
+
+	// token is not flag default: it would be shown in usage
+	if *token == "" {
+		*token = os.Getenv(EnvToken)
+	}
+	c := &ctl{
+		client: NewClient(*addr, *token, *insecure, *timeout),
+		json:   *asJSON,
+		in:     stdin,
+		out:    stdout,
+		errOut: stderr,
+	}
+	if err := run(c, flags.Args()[1:]); errors.Is(err, errUsage) {
+		return 2
+	} else if err != nil {
+		fmt.Fprintf(stderr, "error: %s\n", err.Error())
+		return 1
+	}
+	return 0
+}
+
+func envOr(name string, value string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return value
+}
+
+// parse - parse command flags; errUsage if number of positional arguments is not n (n < 0 - any number)
+func (c *ctl) parse(flags *flag.FlagSet, args []string, synopsis string, n int) error {
+	flags.SetOutput(c.errOut)
+	flags.Usage = func() {
+		fmt.Fprintf(c.errOut, "Usage: pinger ctl %s\n", synopsis)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if n >= 0 && flags.NArg() != n {
+		flags.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+// topics - GET /v1/topics
+func (c *ctl) topics(args []string) error {
+	flags := flag.NewFlagSet("topics", flag.ContinueOnError)
+	if err := c.parse(flags, args, "topics", 0); err != nil {
+		return err
+	}
+	var response web.TopicsResponse
+	if err := c.client.Do(http.MethodGet, "/v1/topics", nil, nil, &response); err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(response.Topics)
+	}
+
+	t := c.table("TOPIC", "HOSTS", "UP", "DEGRADED", "DOWN", "INTERVAL", "PROBES")
+	for _, topic := range response.Topics {
+		t.row(topic.Name, topic.HostCount, topic.States[notify.StateUp], topic.States[notify.StateDegraded],
+			topic.States[notify.StateDown], fmt.Sprintf("%ds", topic.Interval), topic.Probes)
+	}
+	return t.flush()
+}
+
+// hosts - all pages of /dump-hosts
+func (c *ctl) hosts(args []string) error {
+	flags := flag.NewFlagSet("hosts", flag.ContinueOnError)
+	var topics, states, labels stringList
+	flags.Var(&topics, "topic", "topic name (repeatable)")
+	flags.Var(&states, "state", "up, down, degraded, unknown (repeatable)")
+	flags.Var(&labels, "label", "`key:value` or key (repeatable, all must match)")
+	ip := flags.String("ip", "", "ip prefix or network")
+	sortKey := flags.String("sort", "", "ip, topic, state, changed, checked, rtt, loss; - prefix for descending order")
+	if err := c.parse(flags, args, "hosts [flags]", 0); err != nil {
+		return err
+	}
+
+	query := url.Values{"topic": topics, "state": states, "label": labels}
+	if *ip != "" {
+		query.Set("ip", *ip)
+	}
+	if *sortKey != "" {
+		query.Set("sort", *sortKey)
+	}
+	hosts := make([]pools.HostInfo, 0)
+	for {
+		query.Set("limit", strconv.Itoa(web.MaxPageLimit))
+		query.Set("offset", strconv.Itoa(len(hosts)))
+		var page web.InventoryPage
+		if err := c.client.Do(http.MethodGet, "/dump-hosts", query, nil, &page); err != nil {
+			return err
+		}
+		hosts = append(hosts, page.Hosts...)
+		if len(page.Hosts) == 0 || len(hosts) >= page.Total {
+			break
+		}
+	}
+	if c.json {
+		return c.printJSON(hosts)
+	}
+
+	t := c.table("TOPIC", "IP", "STATE", "RTT", "LOSS", "CHECKED", "CHANGED")
+	for _, h := range hosts {
+		rtt, loss := "-", "-"
+		if h.Stats != nil {
+			rtt, loss = formatRtt(h.Stats.AvgRttMs), formatLoss(h.Stats.LossPercent)
+		}
+		t.row(h.Topic, h.IP, h.State, rtt, loss, ago(h.Checked), ago(h.Changed))
+	}
+	return t.flush()
+}
+
+// addHost - PUT /v1/topics/{topic}/hosts/{ip}
+func (c *ctl) addHost(args []string) error {
+	flags := flag.NewFlagSet("add-host", flag.ContinueOnError)
+	probes := flags.Int("probes", 0, "probes per check (default - topic probes)")
+	interval := flags.Int("interval", 0, "check interval in seconds (default - topic interval)")
+	var labels, notifiers stringList
+	flags.Var(&labels, "label", "host label `key=value` (repeatable)")
+	flags.Var(&notifiers, "notifier", "notifier name (repeatable, default - topic notifiers)")
+	if err := c.parse(flags, args, "add-host [flags] <topic> <ip>", 2); err != nil {
+		return err
+	}
+
+	params := make(map[string]interface{})
+	if *probes > 0 {
+		params["Probes"] = *probes
+	}
+	if *interval > 0 {
+		params["Interval"] = *interval
+	}
+	if len(labels) > 0 {
+		m := make(map[string]string)
+		for _, label := range labels {
+			parts := strings.SplitN(label, "=", 2)
+			if len(parts) != 2 {
+				return fmt.Errorf("label '%s' should be key=value", label)
+			}
+			m[parts[0]] = parts[1]
+		}
+		params["Labels"] = m
+	}
+	if len(notifiers) > 0 {
+		params["Notifiers"] = []string(notifiers)
+	}
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	var response web.HostResponse
+	if err := c.client.Do(http.MethodPut, topicPath(flags.Arg(0), "hosts", flags.Arg(1)), nil, body, &response); err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(response.Host)
+	}
+	t := c.table("TOPIC", "IP", "STATE", "PROBES", "INTERVAL")
+	t.row(response.Host.Topic, response.Host.IP, response.Host.State, response.Host.Probes, fmt.Sprintf("%ds", response.Host.Interval))
+	return t.flush()
+}
+
+// removeHost - DELETE /v1/topics/{topic}/hosts/{ip}
+func (c *ctl) removeHost(args []string) error {
+	flags := flag.NewFlagSet("remove-host", flag.ContinueOnError)
+	if err := c.parse(flags, args, "remove-host <topic> <ip>", 2); err != nil {
+		return err
+	}
+	if err := c.client.Do(http.MethodDelete, topicPath(flags.Arg(0), "hosts", flags.Arg(1)), nil, nil, nil); err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(map[string]bool{"ok": true})
+	}
+	fmt.Fprintf(c.out, "%s removed from %s\n", flags.Arg(1), flags.Arg(0))
+	return nil
+}
+
+// ping - GET /ping-now
+func (c *ctl) ping(args []string) error {
+	flags := flag.NewFlagSet("ping", flag.ContinueOnError)
+	probes := flags.Int("probes", 5, "probes")
+	topic := flags.String("topic", "", "topic of host (for tokens limited to topics)")
+	if err := c.parse(flags, args, "ping [flags] <host>", 1); err != nil {
+		return err
+	}
+
+	query := url.Values{"host": {flags.Arg(0)}, "probes": {strconv.Itoa(*probes)}}
+	if *topic != "" {
+		query.Set("topic", *topic)
+	}
+	var result pinger.PingResult
+	if err := c.client.Do(http.MethodGet, "/ping-now", query, nil, &result); err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(result)
+	}
+	t := c.table("HOST", "ALIVE", "RTT", "LOSS")
+	t.row(flags.Arg(0), result.Alive, formatRtt(result.AvgRttMs), formatLoss(100-result.SuccessPercent))
+	return t.flush()
+}
+
+// history - GET /v1/topics/{topic}/history
+func (c *ctl) history(args []string) error {
+	flags := flag.NewFlagSet("history", flag.ContinueOnError)
+	host := flags.String("host", "", "only transitions of host")
+	from := flags.String("from", "", "from time, RFC3339 or unix timestamp")
+	to := flags.String("to", "", "to time, RFC3339 or unix timestamp")
+	if err := c.parse(flags, args, "history [flags] <topic>", 1); err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	for name, value := range map[string]string{"host": *host, "from": *from, "to": *to} {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+	var response web.HistoryResponse
+	if err := c.client.Do(http.MethodGet, topicPath(flags.Arg(0), "history"), query, nil, &response); err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(response.Transitions)
+	}
+	t := c.table("TIME", "HOST", "EVENT")
+	for _, transition := range response.Transitions {
+		t.row(transition.Time.Local().Format("2006-01-02 15:04:05"), transition.Host, transition.Event)
+	}
+	return t.flush()
+}
+
+// export - topics document in /store format, built from /v1/topics/{name}/document (with update secrets and host state)
+func (c *ctl) export(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	var names stringList
+	flags.Var(&names, "topic", "topic name (repeatable, default - all readable topics)")
+	if err := c.parse(flags, args, "export [-topic name]", 0); err != nil {
+		return err
+	}
+
+	if len(names) == 0 {
+		var response web.TopicsResponse
+		if err := c.client.Do(http.MethodGet, "/v1/topics", nil, nil, &response); err != nil {
+			return err
+		}
+		for _, topic := range response.Topics {
+			names = append(names, topic.Name)
+		}
+	}
+	document := make(map[string]interface{})
+	for _, name := range names {
+		var response web.DocumentResponse
+		if err := c.client.Do(http.MethodGet, topicPath(name, "document"), nil, nil, &response); err != nil {
+			return fmt.Errorf("topic %s: %w", name, err)
+		}
+		delete(response.Document, "Name")
+		document[name] = response.Document
+	}
+	return c.printJSON(document)
+}
+
+/*
+importTopics - store topics of document one by one: PATCH /v1/topics/{name} keeps hosts and parameters missing
+in document, with -replace (or for new topic) PUT /v1/topics/{name} replaces topic. Host state is taken from document
+*/
+func (c *ctl) importTopics(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	replace := flags.Bool("replace", false, "remove hosts of imported topics, which are missing in document")
+	if err := c.parse(flags, args, "import [-replace] [file]", -1); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return errUsage
+	}
+
+	var body []byte
+	var err error
+	if path := flags.Arg(0); path != "" && path != "-" {
+		body, err = ioutil.ReadFile(path)
+	} else {
+		body, err = ioutil.ReadAll(c.in)
+	}
+	if err != nil {
+		return err
+	}
+	document := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(body, &document); err != nil {
+		return fmt.Errorf("cannot parse document: %w", err)
+	}
+
+	names := make([]string, 0, len(document))
+	for name := range document {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	topics := make([]pools.TopicInfo, 0, len(names))
+	for _, name := range names {
+		var response web.TopicResponse
+		var apiErr *APIError
+		err = nil
+		if !*replace {
+			err = c.client.Do(http.MethodPatch, topicPath(name), nil, document[name], &response)
+		}
+		if *replace || errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound {
+			err = c.client.Do(http.MethodPut, topicPath(name), nil, document[name], &response)
+		}
+		if err != nil {
+			return fmt.Errorf("topic %s: %w", name, err)
+		}
+		topics = append(topics, response.Topic)
+	}
+	if c.json {
+		return c.printJSON(topics)
+	}
+
+	t := c.table("TOPIC", "HOSTS", "ALIVE")
+	for _, topic := range topics {
+		alive := 0
+		for _, h := range topic.Hosts {
+			if h.Alive {
+				alive++
+			}
+		}
+		t.row(topic.Name, len(topic.Hosts), alive)
+	}
+	return t.flush()
+}
+
+func (c *ctl) printJSON(value interface{}) error {
+	encoder := json.NewEncoder(c.out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// table - tab aligned output with header row
+type table struct {
+	w *tabwriter.Writer
+}
+
+func (c *ctl) table(columns ...interface{}) *table {
+	t := &table{w: tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)}
+	t.row(columns...)
+	return t
+}
+
+func (t *table) row(values ...interface{}) {
+	cells := make([]string, len(values))
+	for i, value := range values {
+		cells[i] = fmt.Sprint(value)
+	}
+	fmt.Fprintln(t.w, strings.Join(cells, "\t"))
+}
+
+func (t *table) flush() error {
+	return t.w.Flush()
+}
+
+func formatRtt(ms float64) string {
+	return strconv.FormatFloat(ms, 'f', 2, 64) + "ms"
+}
+
+func formatLoss(percent float64) string {
+	return strconv.FormatFloat(percent, 'f', 0, 64) + "%"
+}
+
+// ago - time since t, like 1m5s; "-" if not set
+func ago(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return time.Since(*t).Round(time.Second).String()
+}
+
+// stringList - repeatable string flag, values can be comma separated
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
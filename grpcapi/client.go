@@ -0,0 +1,31 @@
+package grpcapi
+
+import (
+	"context"
+)
+
+/*
+Go services use generated client of pinger.v1.Pinger (see pingerpb/pinger.proto) with token credentials:
+
+	conn, err := grpc.NewClient("pinger.local:8002",
+		grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)),
+		grpc.WithPerRPCCredentials(grpcapi.TokenCredentials{Token: token, Secure: true}))
+	client := pingerpb.NewPingerClient(conn)
+	result, err := client.PingNow(ctx, &pingerpb.PingRequest{Host: "10.10.10.1"})
+*/
+
+// TokenCredentials - per-call credentials with API token
+type TokenCredentials struct {
+	Token  string
+	Secure bool // token is sent only over tls
+}
+
+// GetRequestMetadata - authorization metadata
+func (t TokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + t.Token}, nil
+}
+
+// RequireTransportSecurity - implements credentials.PerRPCCredentials
+func (t TokenCredentials) RequireTransportSecurity() bool {
+	return t.Secure
+}
@@ -0,0 +1,46 @@
+package grpcapi
+
+import (
+	"fmt"
+
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+/*
+Codec - optional json codec for grpc messages (content type `application/grpc+json`) for clients without
+protobuf code generation. Protobuf is default; json client selects codec with grpc.CallContentSubtype(CodecName).
+Messages are encoded with protojson, topic and host fields have the same names as in http API
+*/
+type Codec struct{}
+
+// CodecName - grpc content subtype
+const CodecName = "json"
+
+func init() {
+	encoding.RegisterCodec(Codec{})
+}
+
+// Marshal - encode message
+func (Codec) Marshal(v interface{}) ([]byte, error) {
+	message, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("json codec: %T is not protobuf message", v)
+	}
+	return protojson.Marshal(message)
+}
+
+// Unmarshal - decode message; unknown fields are ignored
+func (Codec) Unmarshal(data []byte, v interface{}) error {
+	message, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("json codec: %T is not protobuf message", v)
+	}
+	return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(data, message)
+}
+
+// Name - codec name
+func (Codec) Name() string {
+	return CodecName
+}
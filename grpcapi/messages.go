@@ -0,0 +1,106 @@
+package grpcapi
+
+import (
+	"encoding/json"
+	"pinger/grpcapi/pingerpb"
+	"pinger/notify"
+	"pinger/pinger"
+	"pinger/web"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+/*
+Conversions between pinger.v1 messages (see pingerpb/pinger.proto) and http API types.
+Json names of topic and host fields are the same as in http API, so stored topics are parsed from their json
+*/
+
+// storeDocument - topics of request in the form of decoded json, as pools.ParseTopics expects
+func storeDocument(req *pingerpb.StoreRequest) (map[string]interface{}, error) {
+	bytes, err := protojson.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	document := struct {
+		Topics map[string]interface{} `json:"topics"`
+	}{Topics: make(map[string]interface{})}
+	err = json.Unmarshal(bytes, &document)
+	return document.Topics, err
+}
+
+func storeReply(topics map[string]map[string]bool) *pingerpb.StoreReply {
+	reply := &pingerpb.StoreReply{Topics: make(map[string]*pingerpb.HostStates)}
+	for name, hosts := range topics {
+		reply.Topics[name] = &pingerpb.HostStates{Hosts: hosts}
+	}
+	return reply
+}
+
+func pingResult(result *pinger.PingResult) *pingerpb.PingResult {
+	return &pingerpb.PingResult{
+		Alive:          result.Alive,
+		SuccessPercent: result.SuccessPercent,
+		AvgRttNs:       result.AvgRttNs,
+		AvgRttMs:       result.AvgRttMs,
+	}
+}
+
+func inventoryPage(page *web.InventoryPage) *pingerpb.InventoryPage {
+	result := &pingerpb.InventoryPage{
+		Ok:     page.OK,
+		Total:  int32(page.Total),
+		Offset: int32(page.Offset),
+		Limit:  int32(page.Limit),
+	}
+	for _, h := range page.Hosts {
+		host := &pingerpb.HostInfo{
+			Topic:     h.Topic,
+			Ip:        h.IP,
+			Addresses: h.Addresses,
+			Resolve:   h.Resolve,
+			Probes:    int32(h.Probes),
+			Interval:  int32(h.Interval),
+			UpdateUrl: h.UpdateURL,
+			Notifiers: h.Notifiers,
+			Labels:    h.Labels,
+			State:     h.State,
+			Alive:     h.Alive,
+			Changed:   timeString(h.Changed),
+			Checked:   timeString(h.Checked),
+			Static:    h.Static,
+		}
+		if h.Stats != nil {
+			host.Stats = &pingerpb.HostStats{
+				SuccessPercent: h.Stats.SuccessPercent,
+				LossPercent:    h.Stats.LossPercent,
+				AvgRttMs:       h.Stats.AvgRttMs,
+				AvgRttNs:       h.Stats.AvgRttNs,
+			}
+		}
+		result.Hosts = append(result.Hosts, host)
+	}
+	return result
+}
+
+func stateEvent(e *notify.Event) *pingerpb.Event {
+	return &pingerpb.Event{
+		Topic:    e.Topic,
+		Host:     e.Host,
+		State:    e.State,
+		Previous: e.Previous,
+		Alive:    e.Alive,
+		Result:   pingResult(&e.Result),
+		Time:     e.Time.Format(time.RFC3339Nano),
+		Duration: int64(e.Duration),
+		Labels:   e.Labels,
+	}
+}
+
+// timeString - RFC3339 time or empty string
+func timeString(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
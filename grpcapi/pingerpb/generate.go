@@ -0,0 +1,4 @@
+package pingerpb
+
+// Regenerate messages and grpc stubs after changing pinger.proto (protoc-gen-go and protoc-gen-go-grpc are needed)
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative pinger.proto
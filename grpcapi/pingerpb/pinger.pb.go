@@ -0,0 +1,1356 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: pinger.proto
+
+// grpc API of pinger: the same operations as http API, with the same tokens and scopes.
+// json names of topic and host fields are the same as in http API topics documents,
+// so documents can be shared with `application/grpc+json` clients.
+
+package pingerpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type PingRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Host          string                 `protobuf:"bytes,1,opt,name=host,proto3" json:"host,omitempty"`      // ip or dns name
+	Probes        int32                  `protobuf:"varint,2,opt,name=probes,proto3" json:"probes,omitempty"` // default 5
+	Topic         string                 `protobuf:"bytes,3,opt,name=topic,proto3" json:"topic,omitempty"`    // check ping scope for topic instead of host
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PingRequest) Reset() {
+	*x = PingRequest{}
+	mi := &file_pinger_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PingRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PingRequest) ProtoMessage() {}
+
+func (x *PingRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pinger_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PingRequest.ProtoReflect.Descriptor instead.
+func (*PingRequest) Descriptor() ([]byte, []int) {
+	return file_pinger_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *PingRequest) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
+func (x *PingRequest) GetProbes() int32 {
+	if x != nil {
+		return x.Probes
+	}
+	return 0
+}
+
+func (x *PingRequest) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+type PingResult struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Alive          bool                   `protobuf:"varint,1,opt,name=alive,json=Alive,proto3" json:"alive,omitempty"`
+	SuccessPercent float64                `protobuf:"fixed64,2,opt,name=success_percent,json=SuccessPercent,proto3" json:"success_percent,omitempty"`
+	AvgRttNs       int64                  `protobuf:"varint,3,opt,name=avg_rtt_ns,json=AvgRttNs,proto3" json:"avg_rtt_ns,omitempty"`
+	AvgRttMs       float64                `protobuf:"fixed64,4,opt,name=avg_rtt_ms,json=AvgRttMs,proto3" json:"avg_rtt_ms,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *PingResult) Reset() {
+	*x = PingResult{}
+	mi := &file_pinger_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PingResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PingResult) ProtoMessage() {}
+
+func (x *PingResult) ProtoReflect() protoreflect.Message {
+	mi := &file_pinger_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PingResult.ProtoReflect.Descriptor instead.
+func (*PingResult) Descriptor() ([]byte, []int) {
+	return file_pinger_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *PingResult) GetAlive() bool {
+	if x != nil {
+		return x.Alive
+	}
+	return false
+}
+
+func (x *PingResult) GetSuccessPercent() float64 {
+	if x != nil {
+		return x.SuccessPercent
+	}
+	return 0
+}
+
+func (x *PingResult) GetAvgRttNs() int64 {
+	if x != nil {
+		return x.AvgRttNs
+	}
+	return 0
+}
+
+func (x *PingResult) GetAvgRttMs() float64 {
+	if x != nil {
+		return x.AvgRttMs
+	}
+	return 0
+}
+
+// host of topic, same as host in /get-or-store json; empty parameters are inherited from topic
+type Host struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Host          string                 `protobuf:"bytes,1,opt,name=host,proto3" json:"host,omitempty"`       // ip or dns name
+	Alive         bool                   `protobuf:"varint,2,opt,name=alive,proto3" json:"alive,omitempty"`    // host state in your DB
+	Changed       string                 `protobuf:"bytes,3,opt,name=changed,proto3" json:"changed,omitempty"` // RFC3339 time of last state change
+	Probes        int32                  `protobuf:"varint,4,opt,name=probes,json=Probes,proto3" json:"probes,omitempty"`
+	Interval      int32                  `protobuf:"varint,5,opt,name=interval,json=Interval,proto3" json:"interval,omitempty"` // seconds
+	UpdateUrl     string                 `protobuf:"bytes,6,opt,name=update_url,json=UpdateURL,proto3" json:"update_url,omitempty"`
+	UpdateSecret  string                 `protobuf:"bytes,7,opt,name=update_secret,json=UpdateSecret,proto3" json:"update_secret,omitempty"`
+	UpdateFormat  string                 `protobuf:"bytes,8,opt,name=update_format,json=UpdateFormat,proto3" json:"update_format,omitempty"`
+	UpdateMethod  string                 `protobuf:"bytes,9,opt,name=update_method,json=UpdateMethod,proto3" json:"update_method,omitempty"`
+	UpdateHeaders map[string]string      `protobuf:"bytes,10,rep,name=update_headers,json=UpdateHeaders,proto3" json:"update_headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	UpdateBody    string                 `protobuf:"bytes,11,opt,name=update_body,json=UpdateBody,proto3" json:"update_body,omitempty"`
+	Labels        map[string]string      `protobuf:"bytes,12,rep,name=labels,json=Labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Notifiers     []string               `protobuf:"bytes,13,rep,name=notifiers,json=Notifiers,proto3" json:"notifiers,omitempty"`
+	Resolve       string                 `protobuf:"bytes,14,opt,name=resolve,json=Resolve,proto3" json:"resolve,omitempty"` // addresses of dns name to ping: first, any or all
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Host) Reset() {
+	*x = Host{}
+	mi := &file_pinger_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Host) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Host) ProtoMessage() {}
+
+func (x *Host) ProtoReflect() protoreflect.Message {
+	mi := &file_pinger_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Host.ProtoReflect.Descriptor instead.
+func (*Host) Descriptor() ([]byte, []int) {
+	return file_pinger_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Host) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
+func (x *Host) GetAlive() bool {
+	if x != nil {
+		return x.Alive
+	}
+	return false
+}
+
+func (x *Host) GetChanged() string {
+	if x != nil {
+		return x.Changed
+	}
+	return ""
+}
+
+func (x *Host) GetProbes() int32 {
+	if x != nil {
+		return x.Probes
+	}
+	return 0
+}
+
+func (x *Host) GetInterval() int32 {
+	if x != nil {
+		return x.Interval
+	}
+	return 0
+}
+
+func (x *Host) GetUpdateUrl() string {
+	if x != nil {
+		return x.UpdateUrl
+	}
+	return ""
+}
+
+func (x *Host) GetUpdateSecret() string {
+	if x != nil {
+		return x.UpdateSecret
+	}
+	return ""
+}
+
+func (x *Host) GetUpdateFormat() string {
+	if x != nil {
+		return x.UpdateFormat
+	}
+	return ""
+}
+
+func (x *Host) GetUpdateMethod() string {
+	if x != nil {
+		return x.UpdateMethod
+	}
+	return ""
+}
+
+func (x *Host) GetUpdateHeaders() map[string]string {
+	if x != nil {
+		return x.UpdateHeaders
+	}
+	return nil
+}
+
+func (x *Host) GetUpdateBody() string {
+	if x != nil {
+		return x.UpdateBody
+	}
+	return ""
+}
+
+func (x *Host) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *Host) GetNotifiers() []string {
+	if x != nil {
+		return x.Notifiers
+	}
+	return nil
+}
+
+func (x *Host) GetResolve() string {
+	if x != nil {
+		return x.Resolve
+	}
+	return ""
+}
+
+// topic parameters and hosts, same as topic in /get-or-store json
+type Topic struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Probes        int32                  `protobuf:"varint,1,opt,name=probes,json=Probes,proto3" json:"probes,omitempty"`
+	Interval      int32                  `protobuf:"varint,2,opt,name=interval,json=Interval,proto3" json:"interval,omitempty"` // seconds
+	UpdateUrl     string                 `protobuf:"bytes,3,opt,name=update_url,json=UpdateURL,proto3" json:"update_url,omitempty"`
+	UpdateSecret  string                 `protobuf:"bytes,4,opt,name=update_secret,json=UpdateSecret,proto3" json:"update_secret,omitempty"`
+	UpdateFormat  string                 `protobuf:"bytes,5,opt,name=update_format,json=UpdateFormat,proto3" json:"update_format,omitempty"`
+	UpdateMethod  string                 `protobuf:"bytes,6,opt,name=update_method,json=UpdateMethod,proto3" json:"update_method,omitempty"`
+	UpdateHeaders map[string]string      `protobuf:"bytes,7,rep,name=update_headers,json=UpdateHeaders,proto3" json:"update_headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	UpdateBody    string                 `protobuf:"bytes,8,opt,name=update_body,json=UpdateBody,proto3" json:"update_body,omitempty"`
+	Labels        map[string]string      `protobuf:"bytes,9,rep,name=labels,json=Labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Notifiers     []string               `protobuf:"bytes,10,rep,name=notifiers,json=Notifiers,proto3" json:"notifiers,omitempty"`
+	Resolve       string                 `protobuf:"bytes,11,opt,name=resolve,json=Resolve,proto3" json:"resolve,omitempty"`
+	Hosts         []*Host                `protobuf:"bytes,12,rep,name=hosts,json=Hosts,proto3" json:"hosts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Topic) Reset() {
+	*x = Topic{}
+	mi := &file_pinger_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Topic) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Topic) ProtoMessage() {}
+
+func (x *Topic) ProtoReflect() protoreflect.Message {
+	mi := &file_pinger_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Topic.ProtoReflect.Descriptor instead.
+func (*Topic) Descriptor() ([]byte, []int) {
+	return file_pinger_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Topic) GetProbes() int32 {
+	if x != nil {
+		return x.Probes
+	}
+	return 0
+}
+
+func (x *Topic) GetInterval() int32 {
+	if x != nil {
+		return x.Interval
+	}
+	return 0
+}
+
+func (x *Topic) GetUpdateUrl() string {
+	if x != nil {
+		return x.UpdateUrl
+	}
+	return ""
+}
+
+func (x *Topic) GetUpdateSecret() string {
+	if x != nil {
+		return x.UpdateSecret
+	}
+	return ""
+}
+
+func (x *Topic) GetUpdateFormat() string {
+	if x != nil {
+		return x.UpdateFormat
+	}
+	return ""
+}
+
+func (x *Topic) GetUpdateMethod() string {
+	if x != nil {
+		return x.UpdateMethod
+	}
+	return ""
+}
+
+func (x *Topic) GetUpdateHeaders() map[string]string {
+	if x != nil {
+		return x.UpdateHeaders
+	}
+	return nil
+}
+
+func (x *Topic) GetUpdateBody() string {
+	if x != nil {
+		return x.UpdateBody
+	}
+	return ""
+}
+
+func (x *Topic) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *Topic) GetNotifiers() []string {
+	if x != nil {
+		return x.Notifiers
+	}
+	return nil
+}
+
+func (x *Topic) GetResolve() string {
+	if x != nil {
+		return x.Resolve
+	}
+	return ""
+}
+
+func (x *Topic) GetHosts() []*Host {
+	if x != nil {
+		return x.Hosts
+	}
+	return nil
+}
+
+type StoreRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Topics        map[string]*Topic      `protobuf:"bytes,1,rep,name=topics,proto3" json:"topics,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // topic name => topic
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StoreRequest) Reset() {
+	*x = StoreRequest{}
+	mi := &file_pinger_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StoreRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StoreRequest) ProtoMessage() {}
+
+func (x *StoreRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pinger_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StoreRequest.ProtoReflect.Descriptor instead.
+func (*StoreRequest) Descriptor() ([]byte, []int) {
+	return file_pinger_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *StoreRequest) GetTopics() map[string]*Topic {
+	if x != nil {
+		return x.Topics
+	}
+	return nil
+}
+
+type HostStates struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hosts         map[string]bool        `protobuf:"bytes,1,rep,name=hosts,proto3" json:"hosts,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"` // host => alive
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HostStates) Reset() {
+	*x = HostStates{}
+	mi := &file_pinger_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HostStates) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HostStates) ProtoMessage() {}
+
+func (x *HostStates) ProtoReflect() protoreflect.Message {
+	mi := &file_pinger_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HostStates.ProtoReflect.Descriptor instead.
+func (*HostStates) Descriptor() ([]byte, []int) {
+	return file_pinger_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *HostStates) GetHosts() map[string]bool {
+	if x != nil {
+		return x.Hosts
+	}
+	return nil
+}
+
+type StoreReply struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Topics        map[string]*HostStates `protobuf:"bytes,1,rep,name=topics,proto3" json:"topics,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // topic name => hosts
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StoreReply) Reset() {
+	*x = StoreReply{}
+	mi := &file_pinger_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StoreReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StoreReply) ProtoMessage() {}
+
+func (x *StoreReply) ProtoReflect() protoreflect.Message {
+	mi := &file_pinger_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StoreReply.ProtoReflect.Descriptor instead.
+func (*StoreReply) Descriptor() ([]byte, []int) {
+	return file_pinger_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *StoreReply) GetTopics() map[string]*HostStates {
+	if x != nil {
+		return x.Topics
+	}
+	return nil
+}
+
+// same as /dump-hosts parameters
+type ListHostsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Topics        []string               `protobuf:"bytes,1,rep,name=topics,proto3" json:"topics,omitempty"`
+	States        []string               `protobuf:"bytes,2,rep,name=states,proto3" json:"states,omitempty"`
+	Labels        []string               `protobuf:"bytes,3,rep,name=labels,proto3" json:"labels,omitempty"` // `key:value` or `key`
+	Ip            string                 `protobuf:"bytes,4,opt,name=ip,proto3" json:"ip,omitempty"`         // prefix or network
+	Sort          string                 `protobuf:"bytes,5,opt,name=sort,proto3" json:"sort,omitempty"`
+	Limit         int32                  `protobuf:"varint,6,opt,name=limit,proto3" json:"limit,omitempty"`
+	Offset        int32                  `protobuf:"varint,7,opt,name=offset,proto3" json:"offset,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListHostsRequest) Reset() {
+	*x = ListHostsRequest{}
+	mi := &file_pinger_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListHostsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListHostsRequest) ProtoMessage() {}
+
+func (x *ListHostsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pinger_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListHostsRequest.ProtoReflect.Descriptor instead.
+func (*ListHostsRequest) Descriptor() ([]byte, []int) {
+	return file_pinger_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ListHostsRequest) GetTopics() []string {
+	if x != nil {
+		return x.Topics
+	}
+	return nil
+}
+
+func (x *ListHostsRequest) GetStates() []string {
+	if x != nil {
+		return x.States
+	}
+	return nil
+}
+
+func (x *ListHostsRequest) GetLabels() []string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *ListHostsRequest) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+func (x *ListHostsRequest) GetSort() string {
+	if x != nil {
+		return x.Sort
+	}
+	return ""
+}
+
+func (x *ListHostsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ListHostsRequest) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+type HostStats struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	SuccessPercent float64                `protobuf:"fixed64,1,opt,name=success_percent,json=successPercent,proto3" json:"success_percent,omitempty"`
+	LossPercent    float64                `protobuf:"fixed64,2,opt,name=loss_percent,json=lossPercent,proto3" json:"loss_percent,omitempty"`
+	AvgRttMs       float64                `protobuf:"fixed64,3,opt,name=avg_rtt_ms,json=avgRttMs,proto3" json:"avg_rtt_ms,omitempty"`
+	AvgRttNs       int64                  `protobuf:"varint,4,opt,name=avg_rtt_ns,json=avgRttNs,proto3" json:"avg_rtt_ns,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *HostStats) Reset() {
+	*x = HostStats{}
+	mi := &file_pinger_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HostStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HostStats) ProtoMessage() {}
+
+func (x *HostStats) ProtoReflect() protoreflect.Message {
+	mi := &file_pinger_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HostStats.ProtoReflect.Descriptor instead.
+func (*HostStats) Descriptor() ([]byte, []int) {
+	return file_pinger_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *HostStats) GetSuccessPercent() float64 {
+	if x != nil {
+		return x.SuccessPercent
+	}
+	return 0
+}
+
+func (x *HostStats) GetLossPercent() float64 {
+	if x != nil {
+		return x.LossPercent
+	}
+	return 0
+}
+
+func (x *HostStats) GetAvgRttMs() float64 {
+	if x != nil {
+		return x.AvgRttMs
+	}
+	return 0
+}
+
+func (x *HostStats) GetAvgRttNs() int64 {
+	if x != nil {
+		return x.AvgRttNs
+	}
+	return 0
+}
+
+// inventory record of topic host, same as in /dump-hosts
+type HostInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Topic         string                 `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	Ip            string                 `protobuf:"bytes,2,opt,name=ip,proto3" json:"ip,omitempty"`               // ip or dns name
+	Addresses     []string               `protobuf:"bytes,3,rep,name=addresses,proto3" json:"addresses,omitempty"` // resolved addresses of dns name
+	Resolve       string                 `protobuf:"bytes,4,opt,name=resolve,proto3" json:"resolve,omitempty"`
+	Probes        int32                  `protobuf:"varint,5,opt,name=probes,proto3" json:"probes,omitempty"`
+	Interval      int32                  `protobuf:"varint,6,opt,name=interval,proto3" json:"interval,omitempty"`
+	UpdateUrl     string                 `protobuf:"bytes,7,opt,name=update_url,json=updateURL,proto3" json:"update_url,omitempty"`
+	Notifiers     []string               `protobuf:"bytes,8,rep,name=notifiers,proto3" json:"notifiers,omitempty"`
+	Labels        map[string]string      `protobuf:"bytes,9,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	State         string                 `protobuf:"bytes,10,opt,name=state,proto3" json:"state,omitempty"`
+	Alive         bool                   `protobuf:"varint,11,opt,name=alive,proto3" json:"alive,omitempty"`
+	Changed       string                 `protobuf:"bytes,12,opt,name=changed,proto3" json:"changed,omitempty"` // RFC3339
+	Checked       string                 `protobuf:"bytes,13,opt,name=checked,proto3" json:"checked,omitempty"` // RFC3339
+	Stats         *HostStats             `protobuf:"bytes,14,opt,name=stats,proto3" json:"stats,omitempty"`
+	Static        bool                   `protobuf:"varint,15,opt,name=static,proto3" json:"static,omitempty"` // declared in config file
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HostInfo) Reset() {
+	*x = HostInfo{}
+	mi := &file_pinger_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HostInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HostInfo) ProtoMessage() {}
+
+func (x *HostInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_pinger_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HostInfo.ProtoReflect.Descriptor instead.
+func (*HostInfo) Descriptor() ([]byte, []int) {
+	return file_pinger_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *HostInfo) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+func (x *HostInfo) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+func (x *HostInfo) GetAddresses() []string {
+	if x != nil {
+		return x.Addresses
+	}
+	return nil
+}
+
+func (x *HostInfo) GetResolve() string {
+	if x != nil {
+		return x.Resolve
+	}
+	return ""
+}
+
+func (x *HostInfo) GetProbes() int32 {
+	if x != nil {
+		return x.Probes
+	}
+	return 0
+}
+
+func (x *HostInfo) GetInterval() int32 {
+	if x != nil {
+		return x.Interval
+	}
+	return 0
+}
+
+func (x *HostInfo) GetUpdateUrl() string {
+	if x != nil {
+		return x.UpdateUrl
+	}
+	return ""
+}
+
+func (x *HostInfo) GetNotifiers() []string {
+	if x != nil {
+		return x.Notifiers
+	}
+	return nil
+}
+
+func (x *HostInfo) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *HostInfo) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *HostInfo) GetAlive() bool {
+	if x != nil {
+		return x.Alive
+	}
+	return false
+}
+
+func (x *HostInfo) GetChanged() string {
+	if x != nil {
+		return x.Changed
+	}
+	return ""
+}
+
+func (x *HostInfo) GetChecked() string {
+	if x != nil {
+		return x.Checked
+	}
+	return ""
+}
+
+func (x *HostInfo) GetStats() *HostStats {
+	if x != nil {
+		return x.Stats
+	}
+	return nil
+}
+
+func (x *HostInfo) GetStatic() bool {
+	if x != nil {
+		return x.Static
+	}
+	return false
+}
+
+type InventoryPage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	Total         int32                  `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`
+	Offset        int32                  `protobuf:"varint,3,opt,name=offset,proto3" json:"offset,omitempty"`
+	Limit         int32                  `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`
+	Hosts         []*HostInfo            `protobuf:"bytes,5,rep,name=hosts,proto3" json:"hosts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InventoryPage) Reset() {
+	*x = InventoryPage{}
+	mi := &file_pinger_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InventoryPage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InventoryPage) ProtoMessage() {}
+
+func (x *InventoryPage) ProtoReflect() protoreflect.Message {
+	mi := &file_pinger_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InventoryPage.ProtoReflect.Descriptor instead.
+func (*InventoryPage) Descriptor() ([]byte, []int) {
+	return file_pinger_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *InventoryPage) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+func (x *InventoryPage) GetTotal() int32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *InventoryPage) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *InventoryPage) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *InventoryPage) GetHosts() []*HostInfo {
+	if x != nil {
+		return x.Hosts
+	}
+	return nil
+}
+
+// without topics all readable topics are watched
+type WatchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Topics        []string               `protobuf:"bytes,1,rep,name=topics,proto3" json:"topics,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchRequest) Reset() {
+	*x = WatchRequest{}
+	mi := &file_pinger_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchRequest) ProtoMessage() {}
+
+func (x *WatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pinger_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchRequest.ProtoReflect.Descriptor instead.
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return file_pinger_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *WatchRequest) GetTopics() []string {
+	if x != nil {
+		return x.Topics
+	}
+	return nil
+}
+
+// host state change in topic, same as notifiers get
+type Event struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Topic         string                 `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	Host          string                 `protobuf:"bytes,2,opt,name=host,proto3" json:"host,omitempty"`
+	State         string                 `protobuf:"bytes,3,opt,name=state,proto3" json:"state,omitempty"`
+	Previous      string                 `protobuf:"bytes,4,opt,name=previous,proto3" json:"previous,omitempty"`
+	Alive         bool                   `protobuf:"varint,5,opt,name=alive,proto3" json:"alive,omitempty"`
+	Result        *PingResult            `protobuf:"bytes,6,opt,name=result,proto3" json:"result,omitempty"`
+	Time          string                 `protobuf:"bytes,7,opt,name=time,proto3" json:"time,omitempty"`          // RFC3339
+	Duration      int64                  `protobuf:"varint,8,opt,name=duration,proto3" json:"duration,omitempty"` // nanoseconds in previous state, 0 if unknown
+	Labels        map[string]string      `protobuf:"bytes,9,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_pinger_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_pinger_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_pinger_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *Event) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+func (x *Event) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
+func (x *Event) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *Event) GetPrevious() string {
+	if x != nil {
+		return x.Previous
+	}
+	return ""
+}
+
+func (x *Event) GetAlive() bool {
+	if x != nil {
+		return x.Alive
+	}
+	return false
+}
+
+func (x *Event) GetResult() *PingResult {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+func (x *Event) GetTime() string {
+	if x != nil {
+		return x.Time
+	}
+	return ""
+}
+
+func (x *Event) GetDuration() int64 {
+	if x != nil {
+		return x.Duration
+	}
+	return 0
+}
+
+func (x *Event) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+var File_pinger_proto protoreflect.FileDescriptor
+
+const file_pinger_proto_rawDesc = "" +
+	"\n" +
+	"\fpinger.proto\x12\tpinger.v1\"O\n" +
+	"\vPingRequest\x12\x12\n" +
+	"\x04host\x18\x01 \x01(\tR\x04host\x12\x16\n" +
+	"\x06probes\x18\x02 \x01(\x05R\x06probes\x12\x14\n" +
+	"\x05topic\x18\x03 \x01(\tR\x05topic\"\x87\x01\n" +
+	"\n" +
+	"PingResult\x12\x14\n" +
+	"\x05alive\x18\x01 \x01(\bR\x05Alive\x12'\n" +
+	"\x0fsuccess_percent\x18\x02 \x01(\x01R\x0eSuccessPercent\x12\x1c\n" +
+	"\n" +
+	"avg_rtt_ns\x18\x03 \x01(\x03R\bAvgRttNs\x12\x1c\n" +
+	"\n" +
+	"avg_rtt_ms\x18\x04 \x01(\x01R\bAvgRttMs\"\xe2\x04\n" +
+	"\x04Host\x12\x12\n" +
+	"\x04host\x18\x01 \x01(\tR\x04host\x12\x14\n" +
+	"\x05alive\x18\x02 \x01(\bR\x05alive\x12\x18\n" +
+	"\achanged\x18\x03 \x01(\tR\achanged\x12\x16\n" +
+	"\x06probes\x18\x04 \x01(\x05R\x06Probes\x12\x1a\n" +
+	"\binterval\x18\x05 \x01(\x05R\bInterval\x12\x1d\n" +
+	"\n" +
+	"update_url\x18\x06 \x01(\tR\tUpdateURL\x12#\n" +
+	"\rupdate_secret\x18\a \x01(\tR\fUpdateSecret\x12#\n" +
+	"\rupdate_format\x18\b \x01(\tR\fUpdateFormat\x12#\n" +
+	"\rupdate_method\x18\t \x01(\tR\fUpdateMethod\x12I\n" +
+	"\x0eupdate_headers\x18\n" +
+	" \x03(\v2\".pinger.v1.Host.UpdateHeadersEntryR\rUpdateHeaders\x12\x1f\n" +
+	"\vupdate_body\x18\v \x01(\tR\n" +
+	"UpdateBody\x123\n" +
+	"\x06labels\x18\f \x03(\v2\x1b.pinger.v1.Host.LabelsEntryR\x06Labels\x12\x1c\n" +
+	"\tnotifiers\x18\r \x03(\tR\tNotifiers\x12\x18\n" +
+	"\aresolve\x18\x0e \x01(\tR\aResolve\x1a@\n" +
+	"\x12UpdateHeadersEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xc8\x04\n" +
+	"\x05Topic\x12\x16\n" +
+	"\x06probes\x18\x01 \x01(\x05R\x06Probes\x12\x1a\n" +
+	"\binterval\x18\x02 \x01(\x05R\bInterval\x12\x1d\n" +
+	"\n" +
+	"update_url\x18\x03 \x01(\tR\tUpdateURL\x12#\n" +
+	"\rupdate_secret\x18\x04 \x01(\tR\fUpdateSecret\x12#\n" +
+	"\rupdate_format\x18\x05 \x01(\tR\fUpdateFormat\x12#\n" +
+	"\rupdate_method\x18\x06 \x01(\tR\fUpdateMethod\x12J\n" +
+	"\x0eupdate_headers\x18\a \x03(\v2#.pinger.v1.Topic.UpdateHeadersEntryR\rUpdateHeaders\x12\x1f\n" +
+	"\vupdate_body\x18\b \x01(\tR\n" +
+	"UpdateBody\x124\n" +
+	"\x06labels\x18\t \x03(\v2\x1c.pinger.v1.Topic.LabelsEntryR\x06Labels\x12\x1c\n" +
+	"\tnotifiers\x18\n" +
+	" \x03(\tR\tNotifiers\x12\x18\n" +
+	"\aresolve\x18\v \x01(\tR\aResolve\x12%\n" +
+	"\x05hosts\x18\f \x03(\v2\x0f.pinger.v1.HostR\x05Hosts\x1a@\n" +
+	"\x12UpdateHeadersEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x98\x01\n" +
+	"\fStoreRequest\x12;\n" +
+	"\x06topics\x18\x01 \x03(\v2#.pinger.v1.StoreRequest.TopicsEntryR\x06topics\x1aK\n" +
+	"\vTopicsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12&\n" +
+	"\x05value\x18\x02 \x01(\v2\x10.pinger.v1.TopicR\x05value:\x028\x01\"~\n" +
+	"\n" +
+	"HostStates\x126\n" +
+	"\x05hosts\x18\x01 \x03(\v2 .pinger.v1.HostStates.HostsEntryR\x05hosts\x1a8\n" +
+	"\n" +
+	"HostsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\bR\x05value:\x028\x01\"\x99\x01\n" +
+	"\n" +
+	"StoreReply\x129\n" +
+	"\x06topics\x18\x01 \x03(\v2!.pinger.v1.StoreReply.TopicsEntryR\x06topics\x1aP\n" +
+	"\vTopicsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12+\n" +
+	"\x05value\x18\x02 \x01(\v2\x15.pinger.v1.HostStatesR\x05value:\x028\x01\"\xac\x01\n" +
+	"\x10ListHostsRequest\x12\x16\n" +
+	"\x06topics\x18\x01 \x03(\tR\x06topics\x12\x16\n" +
+	"\x06states\x18\x02 \x03(\tR\x06states\x12\x16\n" +
+	"\x06labels\x18\x03 \x03(\tR\x06labels\x12\x0e\n" +
+	"\x02ip\x18\x04 \x01(\tR\x02ip\x12\x12\n" +
+	"\x04sort\x18\x05 \x01(\tR\x04sort\x12\x14\n" +
+	"\x05limit\x18\x06 \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06offset\x18\a \x01(\x05R\x06offset\"\x93\x01\n" +
+	"\tHostStats\x12'\n" +
+	"\x0fsuccess_percent\x18\x01 \x01(\x01R\x0esuccessPercent\x12!\n" +
+	"\floss_percent\x18\x02 \x01(\x01R\vlossPercent\x12\x1c\n" +
+	"\n" +
+	"avg_rtt_ms\x18\x03 \x01(\x01R\bavgRttMs\x12\x1c\n" +
+	"\n" +
+	"avg_rtt_ns\x18\x04 \x01(\x03R\bavgRttNs\"\xf1\x03\n" +
+	"\bHostInfo\x12\x14\n" +
+	"\x05topic\x18\x01 \x01(\tR\x05topic\x12\x0e\n" +
+	"\x02ip\x18\x02 \x01(\tR\x02ip\x12\x1c\n" +
+	"\taddresses\x18\x03 \x03(\tR\taddresses\x12\x18\n" +
+	"\aresolve\x18\x04 \x01(\tR\aresolve\x12\x16\n" +
+	"\x06probes\x18\x05 \x01(\x05R\x06probes\x12\x1a\n" +
+	"\binterval\x18\x06 \x01(\x05R\binterval\x12\x1d\n" +
+	"\n" +
+	"update_url\x18\a \x01(\tR\tupdateURL\x12\x1c\n" +
+	"\tnotifiers\x18\b \x03(\tR\tnotifiers\x127\n" +
+	"\x06labels\x18\t \x03(\v2\x1f.pinger.v1.HostInfo.LabelsEntryR\x06labels\x12\x14\n" +
+	"\x05state\x18\n" +
+	" \x01(\tR\x05state\x12\x14\n" +
+	"\x05alive\x18\v \x01(\bR\x05alive\x12\x18\n" +
+	"\achanged\x18\f \x01(\tR\achanged\x12\x18\n" +
+	"\achecked\x18\r \x01(\tR\achecked\x12*\n" +
+	"\x05stats\x18\x0e \x01(\v2\x14.pinger.v1.HostStatsR\x05stats\x12\x16\n" +
+	"\x06static\x18\x0f \x01(\bR\x06static\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x8e\x01\n" +
+	"\rInventoryPage\x12\x0e\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\x12\x14\n" +
+	"\x05total\x18\x02 \x01(\x05R\x05total\x12\x16\n" +
+	"\x06offset\x18\x03 \x01(\x05R\x06offset\x12\x14\n" +
+	"\x05limit\x18\x04 \x01(\x05R\x05limit\x12)\n" +
+	"\x05hosts\x18\x05 \x03(\v2\x13.pinger.v1.HostInfoR\x05hosts\"&\n" +
+	"\fWatchRequest\x12\x16\n" +
+	"\x06topics\x18\x01 \x03(\tR\x06topics\"\xc9\x02\n" +
+	"\x05Event\x12\x14\n" +
+	"\x05topic\x18\x01 \x01(\tR\x05topic\x12\x12\n" +
+	"\x04host\x18\x02 \x01(\tR\x04host\x12\x14\n" +
+	"\x05state\x18\x03 \x01(\tR\x05state\x12\x1a\n" +
+	"\bprevious\x18\x04 \x01(\tR\bprevious\x12\x14\n" +
+	"\x05alive\x18\x05 \x01(\bR\x05alive\x12-\n" +
+	"\x06result\x18\x06 \x01(\v2\x15.pinger.v1.PingResultR\x06result\x12\x12\n" +
+	"\x04time\x18\a \x01(\tR\x04time\x12\x1a\n" +
+	"\bduration\x18\b \x01(\x03R\bduration\x124\n" +
+	"\x06labels\x18\t \x03(\v2\x1c.pinger.v1.Event.LabelsEntryR\x06labels\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x012\xbf\x02\n" +
+	"\x06Pinger\x128\n" +
+	"\aPingNow\x12\x16.pinger.v1.PingRequest\x1a\x15.pinger.v1.PingResult\x12<\n" +
+	"\n" +
+	"GetOrStore\x12\x17.pinger.v1.StoreRequest\x1a\x15.pinger.v1.StoreReply\x127\n" +
+	"\x05Store\x12\x17.pinger.v1.StoreRequest\x1a\x15.pinger.v1.StoreReply\x12B\n" +
+	"\tListHosts\x12\x1b.pinger.v1.ListHostsRequest\x1a\x18.pinger.v1.InventoryPage\x12@\n" +
+	"\x11WatchStateChanges\x12\x17.pinger.v1.WatchRequest\x1a\x10.pinger.v1.Event0\x01B\"Z pinger/grpcapi/pingerpb;pingerpbb\x06proto3"
+
+var (
+	file_pinger_proto_rawDescOnce sync.Once
+	file_pinger_proto_rawDescData []byte
+)
+
+func file_pinger_proto_rawDescGZIP() []byte {
+	file_pinger_proto_rawDescOnce.Do(func() {
+		file_pinger_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_pinger_proto_rawDesc), len(file_pinger_proto_rawDesc)))
+	})
+	return file_pinger_proto_rawDescData
+}
+
+var file_pinger_proto_msgTypes = make([]protoimpl.MessageInfo, 22)
+var file_pinger_proto_goTypes = []any{
+	(*PingRequest)(nil),      // 0: pinger.v1.PingRequest
+	(*PingResult)(nil),       // 1: pinger.v1.PingResult
+	(*Host)(nil),             // 2: pinger.v1.Host
+	(*Topic)(nil),            // 3: pinger.v1.Topic
+	(*StoreRequest)(nil),     // 4: pinger.v1.StoreRequest
+	(*HostStates)(nil),       // 5: pinger.v1.HostStates
+	(*StoreReply)(nil),       // 6: pinger.v1.StoreReply
+	(*ListHostsRequest)(nil), // 7: pinger.v1.ListHostsRequest
+	(*HostStats)(nil),        // 8: pinger.v1.HostStats
+	(*HostInfo)(nil),         // 9: pinger.v1.HostInfo
+	(*InventoryPage)(nil),    // 10: pinger.v1.InventoryPage
+	(*WatchRequest)(nil),     // 11: pinger.v1.WatchRequest
+	(*Event)(nil),            // 12: pinger.v1.Event
+	nil,                      // 13: pinger.v1.Host.UpdateHeadersEntry
+	nil,                      // 14: pinger.v1.Host.LabelsEntry
+	nil,                      // 15: pinger.v1.Topic.UpdateHeadersEntry
+	nil,                      // 16: pinger.v1.Topic.LabelsEntry
+	nil,                      // 17: pinger.v1.StoreRequest.TopicsEntry
+	nil,                      // 18: pinger.v1.HostStates.HostsEntry
+	nil,                      // 19: pinger.v1.StoreReply.TopicsEntry
+	nil,                      // 20: pinger.v1.HostInfo.LabelsEntry
+	nil,                      // 21: pinger.v1.Event.LabelsEntry
+}
+var file_pinger_proto_depIdxs = []int32{
+	13, // 0: pinger.v1.Host.update_headers:type_name -> pinger.v1.Host.UpdateHeadersEntry
+	14, // 1: pinger.v1.Host.labels:type_name -> pinger.v1.Host.LabelsEntry
+	15, // 2: pinger.v1.Topic.update_headers:type_name -> pinger.v1.Topic.UpdateHeadersEntry
+	16, // 3: pinger.v1.Topic.labels:type_name -> pinger.v1.Topic.LabelsEntry
+	2,  // 4: pinger.v1.Topic.hosts:type_name -> pinger.v1.Host
+	17, // 5: pinger.v1.StoreRequest.topics:type_name -> pinger.v1.StoreRequest.TopicsEntry
+	18, // 6: pinger.v1.HostStates.hosts:type_name -> pinger.v1.HostStates.HostsEntry
+	19, // 7: pinger.v1.StoreReply.topics:type_name -> pinger.v1.StoreReply.TopicsEntry
+	20, // 8: pinger.v1.HostInfo.labels:type_name -> pinger.v1.HostInfo.LabelsEntry
+	8,  // 9: pinger.v1.HostInfo.stats:type_name -> pinger.v1.HostStats
+	9,  // 10: pinger.v1.InventoryPage.hosts:type_name -> pinger.v1.HostInfo
+	1,  // 11: pinger.v1.Event.result:type_name -> pinger.v1.PingResult
+	21, // 12: pinger.v1.Event.labels:type_name -> pinger.v1.Event.LabelsEntry
+	3,  // 13: pinger.v1.StoreRequest.TopicsEntry.value:type_name -> pinger.v1.Topic
+	5,  // 14: pinger.v1.StoreReply.TopicsEntry.value:type_name -> pinger.v1.HostStates
+	0,  // 15: pinger.v1.Pinger.PingNow:input_type -> pinger.v1.PingRequest
+	4,  // 16: pinger.v1.Pinger.GetOrStore:input_type -> pinger.v1.StoreRequest
+	4,  // 17: pinger.v1.Pinger.Store:input_type -> pinger.v1.StoreRequest
+	7,  // 18: pinger.v1.Pinger.ListHosts:input_type -> pinger.v1.ListHostsRequest
+	11, // 19: pinger.v1.Pinger.WatchStateChanges:input_type -> pinger.v1.WatchRequest
+	1,  // 20: pinger.v1.Pinger.PingNow:output_type -> pinger.v1.PingResult
+	6,  // 21: pinger.v1.Pinger.GetOrStore:output_type -> pinger.v1.StoreReply
+	6,  // 22: pinger.v1.Pinger.Store:output_type -> pinger.v1.StoreReply
+	10, // 23: pinger.v1.Pinger.ListHosts:output_type -> pinger.v1.InventoryPage
+	12, // 24: pinger.v1.Pinger.WatchStateChanges:output_type -> pinger.v1.Event
+	20, // [20:25] is the sub-list for method output_type
+	15, // [15:20] is the sub-list for method input_type
+	15, // [15:15] is the sub-list for extension type_name
+	15, // [15:15] is the sub-list for extension extendee
+	0,  // [0:15] is the sub-list for field type_name
+}
+
+func init() { file_pinger_proto_init() }
+func file_pinger_proto_init() {
+	if File_pinger_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pinger_proto_rawDesc), len(file_pinger_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   22,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_pinger_proto_goTypes,
+		DependencyIndexes: file_pinger_proto_depIdxs,
+		MessageInfos:      file_pinger_proto_msgTypes,
+	}.Build()
+	File_pinger_proto = out.File
+	file_pinger_proto_goTypes = nil
+	file_pinger_proto_depIdxs = nil
+}
@@ -0,0 +1,291 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: pinger.proto
+
+// grpc API of pinger: the same operations as http API, with the same tokens and scopes.
+// json names of topic and host fields are the same as in http API topics documents,
+// so documents can be shared with `application/grpc+json` clients.
+
+package pingerpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Pinger_PingNow_FullMethodName           = "/pinger.v1.Pinger/PingNow"
+	Pinger_GetOrStore_FullMethodName        = "/pinger.v1.Pinger/GetOrStore"
+	Pinger_Store_FullMethodName             = "/pinger.v1.Pinger/Store"
+	Pinger_ListHosts_FullMethodName         = "/pinger.v1.Pinger/ListHosts"
+	Pinger_WatchStateChanges_FullMethodName = "/pinger.v1.Pinger/WatchStateChanges"
+)
+
+// PingerClient is the client API for Pinger service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PingerClient interface {
+	// ping host right now, same as /ping-now (ping scope)
+	PingNow(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PingResult, error)
+	// store topics, hosts missing in request are removed, same as /get-or-store (write scope)
+	GetOrStore(ctx context.Context, in *StoreRequest, opts ...grpc.CallOption) (*StoreReply, error)
+	// store topics, hosts missing in request are kept, same as /store (write scope)
+	Store(ctx context.Context, in *StoreRequest, opts ...grpc.CallOption) (*StoreReply, error)
+	// page of hosts inventory, same as /dump-hosts (read scope)
+	ListHosts(ctx context.Context, in *ListHostsRequest, opts ...grpc.CallOption) (*InventoryPage, error)
+	// host state changes of given or all readable topics, starting from the moment of call (read scope)
+	WatchStateChanges(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error)
+}
+
+type pingerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPingerClient(cc grpc.ClientConnInterface) PingerClient {
+	return &pingerClient{cc}
+}
+
+func (c *pingerClient) PingNow(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PingResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PingResult)
+	err := c.cc.Invoke(ctx, Pinger_PingNow_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pingerClient) GetOrStore(ctx context.Context, in *StoreRequest, opts ...grpc.CallOption) (*StoreReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StoreReply)
+	err := c.cc.Invoke(ctx, Pinger_GetOrStore_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pingerClient) Store(ctx context.Context, in *StoreRequest, opts ...grpc.CallOption) (*StoreReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StoreReply)
+	err := c.cc.Invoke(ctx, Pinger_Store_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pingerClient) ListHosts(ctx context.Context, in *ListHostsRequest, opts ...grpc.CallOption) (*InventoryPage, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(InventoryPage)
+	err := c.cc.Invoke(ctx, Pinger_ListHosts_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pingerClient) WatchStateChanges(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Pinger_ServiceDesc.Streams[0], Pinger_WatchStateChanges_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchRequest, Event]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Pinger_WatchStateChangesClient = grpc.ServerStreamingClient[Event]
+
+// PingerServer is the server API for Pinger service.
+// All implementations must embed UnimplementedPingerServer
+// for forward compatibility.
+type PingerServer interface {
+	// ping host right now, same as /ping-now (ping scope)
+	PingNow(context.Context, *PingRequest) (*PingResult, error)
+	// store topics, hosts missing in request are removed, same as /get-or-store (write scope)
+	GetOrStore(context.Context, *StoreRequest) (*StoreReply, error)
+	// store topics, hosts missing in request are kept, same as /store (write scope)
+	Store(context.Context, *StoreRequest) (*StoreReply, error)
+	// page of hosts inventory, same as /dump-hosts (read scope)
+	ListHosts(context.Context, *ListHostsRequest) (*InventoryPage, error)
+	// host state changes of given or all readable topics, starting from the moment of call (read scope)
+	WatchStateChanges(*WatchRequest, grpc.ServerStreamingServer[Event]) error
+	mustEmbedUnimplementedPingerServer()
+}
+
+// UnimplementedPingerServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPingerServer struct{}
+
+func (UnimplementedPingerServer) PingNow(context.Context, *PingRequest) (*PingResult, error) {
+	return nil, status.Error(codes.Unimplemented, "method PingNow not implemented")
+}
+func (UnimplementedPingerServer) GetOrStore(context.Context, *StoreRequest) (*StoreReply, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetOrStore not implemented")
+}
+func (UnimplementedPingerServer) Store(context.Context, *StoreRequest) (*StoreReply, error) {
+	return nil, status.Error(codes.Unimplemented, "method Store not implemented")
+}
+func (UnimplementedPingerServer) ListHosts(context.Context, *ListHostsRequest) (*InventoryPage, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListHosts not implemented")
+}
+func (UnimplementedPingerServer) WatchStateChanges(*WatchRequest, grpc.ServerStreamingServer[Event]) error {
+	return status.Error(codes.Unimplemented, "method WatchStateChanges not implemented")
+}
+func (UnimplementedPingerServer) mustEmbedUnimplementedPingerServer() {}
+func (UnimplementedPingerServer) testEmbeddedByValue()                {}
+
+// UnsafePingerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PingerServer will
+// result in compilation errors.
+type UnsafePingerServer interface {
+	mustEmbedUnimplementedPingerServer()
+}
+
+func RegisterPingerServer(s grpc.ServiceRegistrar, srv PingerServer) {
+	// If the following call panics, it indicates UnimplementedPingerServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Pinger_ServiceDesc, srv)
+}
+
+func _Pinger_PingNow_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PingerServer).PingNow(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Pinger_PingNow_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PingerServer).PingNow(ctx, req.(*PingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Pinger_GetOrStore_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StoreRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PingerServer).GetOrStore(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Pinger_GetOrStore_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PingerServer).GetOrStore(ctx, req.(*StoreRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Pinger_Store_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StoreRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PingerServer).Store(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Pinger_Store_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PingerServer).Store(ctx, req.(*StoreRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Pinger_ListHosts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListHostsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PingerServer).ListHosts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Pinger_ListHosts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PingerServer).ListHosts(ctx, req.(*ListHostsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Pinger_WatchStateChanges_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PingerServer).WatchStateChanges(m, &grpc.GenericServerStream[WatchRequest, Event]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Pinger_WatchStateChangesServer = grpc.ServerStreamingServer[Event]
+
+// Pinger_ServiceDesc is the grpc.ServiceDesc for Pinger service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Pinger_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "pinger.v1.Pinger",
+	HandlerType: (*PingerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "PingNow",
+			Handler:    _Pinger_PingNow_Handler,
+		},
+		{
+			MethodName: "GetOrStore",
+			Handler:    _Pinger_GetOrStore_Handler,
+		},
+		{
+			MethodName: "Store",
+			Handler:    _Pinger_Store_Handler,
+		},
+		{
+			MethodName: "ListHosts",
+			Handler:    _Pinger_ListHosts_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchStateChanges",
+			Handler:       _Pinger_WatchStateChanges_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "pinger.proto",
+}
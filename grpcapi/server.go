@@ -0,0 +1,291 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/url"
+	"pinger/auth"
+	"pinger/grpcapi/pingerpb"
+	"pinger/logger"
+	"pinger/notify"
+	"pinger/pinger"
+	"pinger/pools"
+	"pinger/web"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+)
+
+/*
+grpc API - the same operations as http API, with the same tokens and scopes, on separate port (`listen.grpc-port`):
+
+	PingNow            - /ping-now                  (ping scope)
+	GetOrStore, Store  - /get-or-store, /store      (write scope)
+	ListHosts          - /dump-hosts                (read scope)
+	WatchStateChanges  - stream of host state changes (read scope)
+
+Token is sent in `authorization: Bearer <token>` or `x-api-key` metadata, or client certificate is used.
+Messages and stubs are generated from pingerpb/pinger.proto; server reflection is available with read scope.
+*/
+
+// ServiceName - full grpc service name
+const ServiceName = "pinger.v1.Pinger"
+
+// watchBuffer - events kept for slow WatchStateChanges client before it is disconnected
+const watchBuffer = 1000
+
+// methodScopes - scope required for method
+var methodScopes = map[string]string{
+	pingerpb.Pinger_PingNow_FullMethodName:                                       auth.ScopePing,
+	pingerpb.Pinger_GetOrStore_FullMethodName:                                    auth.ScopeWrite,
+	pingerpb.Pinger_Store_FullMethodName:                                         auth.ScopeWrite,
+	pingerpb.Pinger_ListHosts_FullMethodName:                                     auth.ScopeRead,
+	pingerpb.Pinger_WatchStateChanges_FullMethodName:                             auth.ScopeRead,
+	grpc_reflection_v1.ServerReflection_ServerReflectionInfo_FullMethodName:      auth.ScopeRead,
+	grpc_reflection_v1alpha.ServerReflection_ServerReflectionInfo_FullMethodName: auth.ScopeRead,
+}
+
+// Server - pinger.v1.Pinger implementation; works with global pinger.Pinger and pools.TopicPool
+type Server struct {
+	pingerpb.UnimplementedPingerServer
+	Web *web.Params // default probes and interval of stored topics
+}
+
+/*
+Serve - start grpc server on listener; tlsConfig is optional. Blocks until listener is closed
+*/
+func Serve(listener net.Listener, server *Server, tlsConfig *tls.Config) error {
+	return NewServer(server, tlsConfig).Serve(listener)
+}
+
+// NewServer - grpc server with registered service and reflection; it's Serve, GracefulStop and Stop are called by owner
+func NewServer(server *Server, tlsConfig *tls.Config) *grpc.Server {
+	options := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unaryInterceptor),
+		grpc.ChainStreamInterceptor(streamInterceptor),
+	}
+	if tlsConfig != nil {
+		options = append(options, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	s := grpc.NewServer(options...)
+	pingerpb.RegisterPingerServer(s, server)
+	reflection.Register(s)
+	return s
+}
+
+// PingNow - ping host and return result
+func (s *Server) PingNow(ctx context.Context, req *pingerpb.PingRequest) (*pingerpb.PingResult, error) {
+	if req.Host == "" {
+		return nil, fieldError("host", "missing parameter")
+	}
+	// tokens limited to topics can ping only hosts of their topics
+	principal := auth.FromContext(ctx)
+	if (req.Topic != "" && !principal.Can(auth.ScopePing, req.Topic)) || !web.HostAllowed(principal, auth.ScopePing, req.Host) {
+		return nil, deny(codes.PermissionDenied)
+	}
+	probes := int(req.Probes)
+	if probes == 0 {
+		probes = 5
+	} else if probes < 0 {
+		return nil, fieldError("probes", "should be positive integer")
+	}
+
+	result, err := pinger.Pinger.PingNow(req.Host, probes)
+	if errors.Is(err, pinger.ErrResolve) {
+		return nil, fieldError("host", err.Error())
+	} else if errors.Is(err, pinger.ErrJobRunning) {
+		return nil, status.Error(codes.Aborted, err.Error())
+	} else if errors.Is(err, pinger.ErrStopping) {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	} else if err != nil {
+		return nil, status.Errorf(codes.Internal, "Ping : %s", err.Error())
+	}
+	return pingResult(result), nil
+}
+
+// GetOrStore - store topics, hosts missing in request are removed
+func (s *Server) GetOrStore(ctx context.Context, req *pingerpb.StoreRequest) (*pingerpb.StoreReply, error) {
+	return s.store(ctx, req, true)
+}
+
+// Store - store topics, hosts missing in request are kept
+func (s *Server) Store(ctx context.Context, req *pingerpb.StoreRequest) (*pingerpb.StoreReply, error) {
+	return s.store(ctx, req, false)
+}
+
+func (s *Server) store(ctx context.Context, req *pingerpb.StoreRequest, removeOld bool) (*pingerpb.StoreReply, error) {
+	params, err := storeDocument(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	// the same checks as for http body, field names are the same
+	if err := web.Spec.Validate(&web.Schema{Ref: "#/components/schemas/Topics"}, params, "topics"); err != nil {
+		return nil, validationError(err)
+	}
+	result, err := s.Web.StoreTopics(auth.FromContext(ctx), params, removeOld)
+	if errors.Is(err, web.ErrForbidden) {
+		return nil, deny(codes.PermissionDenied)
+	} else if err != nil {
+		return nil, validationError(err)
+	}
+	return storeReply(result), nil
+}
+
+// ListHosts - page of hosts inventory
+func (s *Server) ListHosts(ctx context.Context, req *pingerpb.ListHostsRequest) (*pingerpb.InventoryPage, error) {
+	query := url.Values{}
+	if len(req.Topics) > 0 {
+		query.Set("topic", strings.Join(req.Topics, ","))
+	}
+	if len(req.States) > 0 {
+		query.Set("state", strings.Join(req.States, ","))
+	}
+	query["label"] = req.Labels
+	query.Set("ip", req.Ip)
+	query.Set("sort", req.Sort)
+	if req.Limit != 0 {
+		query.Set("limit", strconv.Itoa(int(req.Limit)))
+	}
+	if req.Offset != 0 {
+		query.Set("offset", strconv.Itoa(int(req.Offset)))
+	}
+
+	page, err := web.QueryInventory(auth.FromContext(ctx), query)
+	if err != nil {
+		return nil, validationError(err)
+	}
+	return inventoryPage(page), nil
+}
+
+/*
+WatchStateChanges - send host state changes of readable topics until client cancels the call.
+Events, which happened before the call, are not sent
+*/
+func (s *Server) WatchStateChanges(req *pingerpb.WatchRequest, stream pingerpb.Pinger_WatchStateChangesServer) error {
+	principal := auth.FromContext(stream.Context())
+	topics := make(map[string]bool)
+	for _, topic := range req.Topics {
+		if !principal.Can(auth.ScopeRead, topic) {
+			return deny(codes.PermissionDenied)
+		}
+		topics[topic] = true
+	}
+
+	subscription, _, _ := notify.Events.Subscribe(watchBuffer, func(e notify.StreamEvent) bool {
+		return e.Kind == notify.KindState && principal.Can(auth.ScopeRead, e.Topic) && (len(topics) == 0 || topics[e.Topic])
+	}, 0)
+	defer notify.Events.Unsubscribe(subscription)
+	logger.Debug("[grpc]: %s watches state changes", principal.Name)
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-subscription.C:
+			if !ok && notify.Events.Closed() {
+				return status.Error(codes.Unavailable, "server is shutting down")
+			} else if !ok {
+				return status.Error(codes.ResourceExhausted, "client is too slow, events are dropped")
+			}
+			if err := stream.Send(stateEvent(&event.Event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+/*
+authenticate - principal by token from metadata or by client certificate; check method scope
+*/
+func authenticate(ctx context.Context, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	token := auth.RequestToken(first(md.Get("x-api-key")), first(md.Get("authorization")))
+	var state *tls.ConnectionState
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			state = &info.State
+		}
+	}
+
+	principal, ok := auth.AuthenticateToken(token, state)
+	if !ok {
+		return ctx, deny(codes.Unauthenticated)
+	}
+	scope, ok := methodScopes[method]
+	if !ok || !principal.HasScope(scope) {
+		return ctx, deny(codes.PermissionDenied)
+	}
+	return auth.WithPrincipal(ctx, principal), nil
+}
+
+func unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer recoverPanic(info.FullMethod, &err)
+	if ctx, err = authenticate(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func streamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer recoverPanic(info.FullMethod, &err)
+	ctx, err := authenticate(stream.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &principalStream{ServerStream: stream, ctx: ctx})
+}
+
+// recoverPanic - same as http Middleware: log panic and return internal error
+func recoverPanic(method string, err *error) {
+	if r := recover(); r != nil {
+		logger.Err("[grpc]: recovered in %s: %v", method, r)
+		*err = status.Error(codes.Internal, "Internal error")
+	}
+}
+
+// principalStream - server stream with authenticated context
+type principalStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *principalStream) Context() context.Context {
+	return s.ctx
+}
+
+// deny - Unauthenticated or PermissionDenied error, counted like http 401/403
+func deny(code codes.Code) error {
+	if code == codes.Unauthenticated {
+		auth.Rejected("unauthorized")
+		return status.Error(code, "Missing or invalid token")
+	}
+	auth.Rejected("forbidden")
+	return status.Error(code, "Access denied")
+}
+
+// validationError - InvalidArgument error; message is `field: message` for *pools.FieldError
+func validationError(err error) error {
+	return status.Error(codes.InvalidArgument, err.Error())
+}
+
+func fieldError(field string, message string) error {
+	return validationError(&pools.FieldError{Field: field, Message: message})
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
@@ -0,0 +1,218 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"pinger/logger"
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+History - journal of host state transitions per topic.
+Transitions are kept in memory and appended to `sla.history-path` (json lines), so availability
+reports survive restarts. Records older than retention are dropped, but the last known state
+before retention border is always kept.
+*/
+
+// Transition events
+const (
+	EventUp      = "up"      // host became alive (or was added alive)
+	EventDown    = "down"    // host became dead (or was added dead)
+	EventRemoved = "removed" // host was removed from topic; not monitored anymore
+)
+
+// Transition - single state change of host in topic
+type Transition struct {
+	Topic string    `json:"topic"`
+	Host  string    `json:"host"`
+	Event string    `json:"event"`
+	Time  time.Time `json:"time"`
+}
+
+type journal struct {
+	Path      string
+	Retention time.Duration
+	hosts     map[string][]Transition // topic|host => transitions ordered by time
+	file      *os.File
+	mx        sync.Mutex
+}
+
+// Journal - global transitions journal
+var Journal = journal{hosts: make(map[string][]Transition)}
+
+func key(topic string, host string) string {
+	return topic + "|" + host
+}
+
+// StateEvent - returns EventUp or EventDown for given state
+func StateEvent(alive bool) string {
+	if alive {
+		return EventUp
+	}
+	return EventDown
+}
+
+/*
+Init - load stored transitions from path (if set), drop expired ones and start appending new records
+*/
+func (j *journal) Init(path string, retentionDays int64) {
+	j.mx.Lock()
+	defer j.mx.Unlock()
+
+	j.Path = path
+	j.Retention = time.Duration(retentionDays) * 24 * time.Hour
+	if j.Path == "" {
+		return
+	}
+
+	if f, err := os.Open(j.Path); err == nil {
+		loaded := 0
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var t Transition
+			if err := json.Unmarshal(scanner.Bytes(), &t); err != nil {
+				logger.Err("[history]: skipping broken record: %s", err.Error())
+				continue
+			}
+			k := key(t.Topic, t.Host)
+			j.hosts[k] = append(j.hosts[k], t)
+			loaded++
+		}
+		f.Close()
+		for k := range j.hosts {
+			sort.SliceStable(j.hosts[k], func(a, b int) bool { return j.hosts[k][a].Time.Before(j.hosts[k][b].Time) })
+		}
+		logger.Log("Loaded %d state transitions from '%s'", loaded, j.Path)
+	} else if !os.IsNotExist(err) {
+		logger.Err("Cannot read history: %s", err.Error())
+	}
+
+	j.compact()
+	go j.startCompactor()
+}
+
+// SetRetention - change retention (on config reload); expired records are dropped on next compaction
+func (j *journal) SetRetention(retentionDays int64) {
+	j.mx.Lock()
+	defer j.mx.Unlock()
+	j.Retention = time.Duration(retentionDays) * 24 * time.Hour
+}
+
+func (j *journal) startCompactor() {
+	ticker := time.NewTicker(time.Hour)
+	for range ticker.C {
+		j.mx.Lock()
+		j.compact()
+		j.mx.Unlock()
+	}
+}
+
+// compact - drop expired transitions and rewrite file. Must be called under lock.
+func (j *journal) compact() {
+	if j.Retention > 0 {
+		border := time.Now().Add(-j.Retention)
+		for k, list := range j.hosts {
+			// keep last transition before border: it holds state at the border
+			n := sort.Search(len(list), func(i int) bool { return !list[i].Time.Before(border) })
+			if n > 1 {
+				j.hosts[k] = append([]Transition{}, list[n-1:]...)
+			}
+			if last := j.hosts[k][len(j.hosts[k])-1]; last.Event == EventRemoved && last.Time.Before(border) {
+				delete(j.hosts, k)
+			}
+		}
+	}
+
+	if j.Path == "" {
+		return
+	}
+	if j.file != nil {
+		j.file.Close()
+		j.file = nil
+	}
+
+	tmp := j.Path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		logger.Err("Cannot write history: %s", err.Error())
+		return
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, list := range j.hosts {
+		for _, t := range list {
+			enc.Encode(t)
+		}
+	}
+	w.Flush()
+	f.Close()
+	if err := os.Rename(tmp, j.Path); err != nil {
+		logger.Err("Cannot write history: %s", err.Error())
+		return
+	}
+
+	j.file, err = os.OpenFile(j.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		logger.Err("Cannot open history for writing: %s", err.Error())
+	}
+}
+
+/*
+Record - store new transition. Transition is ignored if host is already in this state.
+*/
+func (j *journal) Record(topic string, host string, event string, tm time.Time) {
+	j.mx.Lock()
+	defer j.mx.Unlock()
+
+	k := key(topic, host)
+	list := j.hosts[k]
+	if len(list) > 0 && list[len(list)-1].Event == event {
+		return
+	}
+	if len(list) == 0 && event == EventRemoved {
+		return
+	}
+
+	t := Transition{Topic: topic, Host: host, Event: event, Time: tm}
+	j.hosts[k] = append(list, t)
+
+	if j.file != nil {
+		bytes, err := json.Marshal(t)
+		if err != nil {
+			logger.Err("[history]: cannot marshal transition: %s", err.Error())
+			return
+		}
+		if _, err := j.file.Write(append(bytes, '\n')); err != nil {
+			logger.Err("[history]: cannot write transition: %s", err.Error())
+		}
+	}
+}
+
+// Transitions - copy of host transitions in topic
+func (j *journal) Transitions(topic string, host string) []Transition {
+	j.mx.Lock()
+	defer j.mx.Unlock()
+
+	list := j.hosts[key(topic, host)]
+	result := make([]Transition, len(list))
+	copy(result, list)
+	return result
+}
+
+// Hosts - all hosts ever recorded in topic (within retention)
+func (j *journal) Hosts(topic string) []string {
+	j.mx.Lock()
+	defer j.mx.Unlock()
+
+	hosts := make([]string, 0)
+	for _, list := range j.hosts {
+		if len(list) > 0 && list[0].Topic == topic {
+			hosts = append(hosts, list[0].Host)
+		}
+	}
+	sort.Strings(hosts)
+	return hosts
+}
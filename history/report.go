@@ -0,0 +1,223 @@
+package history
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"time"
+)
+
+/*
+Maintenance - planned maintenance window. Empty Topic matches all topics, empty Host matches all hosts.
+*/
+type Maintenance struct {
+	Topic string    `json:"topic" mapstructure:"topic"`
+	Host  string    `json:"host" mapstructure:"host"`
+	From  time.Time `json:"from" mapstructure:"from"`
+	To    time.Time `json:"to" mapstructure:"to"`
+}
+
+// Matches - if maintenance window applies to host in topic
+func (m Maintenance) Matches(topic string, host string) bool {
+	return (m.Topic == "" || m.Topic == topic) && (m.Host == "" || m.Host == host)
+}
+
+/*
+Report - availability report for host or whole topic over [From, To).
+Durations are in seconds. Monitored is time with known host state, minus excluded maintenance.
+MTTR is total downtime divided by number of outages.
+*/
+type Report struct {
+	Topic         string    `json:"topic"`
+	Host          string    `json:"host,omitempty"`
+	From          time.Time `json:"from"`
+	To            time.Time `json:"to"`
+	Monitored     float64   `json:"monitored"`
+	Downtime      float64   `json:"downtime"`
+	Maintenance   float64   `json:"maintenance"`
+	Uptime        float64   `json:"uptime"`
+	Outages       int       `json:"outages"`
+	MTTR          float64   `json:"mttr"`
+	LongestOutage float64   `json:"longestOutage"`
+	Hosts         []*Report `json:"hosts,omitempty"`
+}
+
+type interval struct {
+	from time.Time
+	to   time.Time
+}
+
+func (i interval) overlap(o interval) time.Duration {
+	from, to := i.from, i.to
+	if o.from.After(from) {
+		from = o.from
+	}
+	if o.to.Before(to) {
+		to = o.to
+	}
+	if !to.After(from) {
+		return 0
+	}
+	return to.Sub(from)
+}
+
+// excluded - part of interval covered by maintenance windows; windows must not overlap each other
+func excluded(i interval, windows []interval) time.Duration {
+	var d time.Duration
+	for _, w := range windows {
+		d += i.overlap(w)
+	}
+	return d
+}
+
+// merge - merge overlapping maintenance windows
+func merge(windows []Maintenance, topic string, host string) []interval {
+	result := make([]interval, 0)
+	for _, m := range windows {
+		if !m.Matches(topic, host) || !m.To.After(m.From) {
+			continue
+		}
+		cur := interval{m.From, m.To}
+		merged := make([]interval, 0, len(result)+1)
+		for _, r := range result {
+			if r.to.Before(cur.from) || cur.to.Before(r.from) {
+				merged = append(merged, r)
+				continue
+			}
+			if r.from.Before(cur.from) {
+				cur.from = r.from
+			}
+			if r.to.After(cur.to) {
+				cur.to = r.to
+			}
+		}
+		result = append(merged, cur)
+	}
+	return result
+}
+
+/*
+HostReport - compute report for single host in topic. maintenance can be nil
+*/
+func (j *journal) HostReport(topic string, host string, from time.Time, to time.Time, maintenance []Maintenance) *Report {
+	report := Report{Topic: topic, Host: host, From: from, To: to}
+	windows := merge(maintenance, topic, host)
+	window := interval{from, to}
+
+	transitions := j.Transitions(topic, host)
+	var outage time.Duration
+	inOutage := false
+	closeOutage := func() {
+		if inOutage && outage > 0 {
+			report.Outages++
+			if outage.Seconds() > report.LongestOutage {
+				report.LongestOutage = outage.Seconds()
+			}
+		}
+		inOutage = false
+		outage = 0
+	}
+
+	for n, t := range transitions {
+		end := to
+		if n+1 < len(transitions) {
+			end = transitions[n+1].Time
+		}
+		segment := interval{t.Time, end}
+		length := segment.overlap(window)
+		if t.Event == EventRemoved || length == 0 {
+			closeOutage()
+			continue
+		}
+		maint := excluded(interval{maxTime(t.Time, from), minTime(end, to)}, windows)
+		report.Maintenance += maint.Seconds()
+		report.Monitored += (length - maint).Seconds()
+
+		if t.Event == EventDown {
+			down := length - maint
+			report.Downtime += down.Seconds()
+			outage += down
+			inOutage = true
+		} else {
+			closeOutage()
+		}
+	}
+	closeOutage()
+	report.finish()
+
+	return &report
+}
+
+/*
+TopicReport - compute report for every host ever recorded in topic and sum them up
+*/
+func (j *journal) TopicReport(topic string, from time.Time, to time.Time, maintenance []Maintenance) *Report {
+	report := Report{Topic: topic, From: from, To: to, Hosts: make([]*Report, 0)}
+	for _, host := range j.Hosts(topic) {
+		hr := j.HostReport(topic, host, from, to, maintenance)
+		if hr.Monitored == 0 && hr.Maintenance == 0 {
+			continue
+		}
+		report.Hosts = append(report.Hosts, hr)
+		report.Monitored += hr.Monitored
+		report.Downtime += hr.Downtime
+		report.Maintenance += hr.Maintenance
+		report.Outages += hr.Outages
+		if hr.LongestOutage > report.LongestOutage {
+			report.LongestOutage = hr.LongestOutage
+		}
+	}
+	report.finish()
+
+	return &report
+}
+
+func (r *Report) finish() {
+	if r.Monitored > 0 {
+		r.Uptime = 100 * (r.Monitored - r.Downtime) / r.Monitored
+	}
+	if r.Outages > 0 {
+		r.MTTR = r.Downtime / float64(r.Outages)
+	}
+}
+
+/*
+WriteCSV - write report as csv: one row per host, topic total row last
+*/
+func (r *Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"topic", "host", "from", "to", "monitored", "downtime", "maintenance", "uptime", "outages", "mttr", "longest_outage"})
+	rows := r.Hosts
+	rows = append(rows, r)
+	for _, row := range rows {
+		cw.Write([]string{
+			row.Topic,
+			row.Host,
+			row.From.Format(time.RFC3339),
+			row.To.Format(time.RFC3339),
+			fmt.Sprintf("%.0f", row.Monitored),
+			fmt.Sprintf("%.0f", row.Downtime),
+			fmt.Sprintf("%.0f", row.Maintenance),
+			fmt.Sprintf("%.4f", row.Uptime),
+			fmt.Sprintf("%d", row.Outages),
+			fmt.Sprintf("%.0f", row.MTTR),
+			fmt.Sprintf("%.0f", row.LongestOutage),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func maxTime(a time.Time, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func minTime(a time.Time, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,147 @@
+package history
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+)
+
+var reportStart = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// at - time in hours from report start
+func at(hours float64) time.Time {
+	return reportStart.Add(time.Duration(hours * float64(time.Hour)))
+}
+
+type record struct {
+	hours float64
+	event string
+}
+
+func testJournal(topic string, hosts map[string][]record) *journal {
+	j := &journal{hosts: make(map[string][]Transition)}
+	for host, records := range hosts {
+		for _, r := range records {
+			j.Record(topic, host, r.event, at(r.hours))
+		}
+	}
+	return j
+}
+
+func TestHostReport(t *testing.T) {
+	tests := []struct {
+		name        string
+		records     []record
+		maintenance []Maintenance
+		expected    Report
+	}{
+		{
+			name:     "always up",
+			records:  []record{{-1, EventUp}},
+			expected: Report{Monitored: 36000, Uptime: 100},
+		},
+		{
+			name:     "one outage",
+			records:  []record{{0, EventUp}, {2, EventDown}, {3, EventUp}},
+			expected: Report{Monitored: 36000, Downtime: 3600, Uptime: 90, Outages: 1, MTTR: 3600, LongestOutage: 3600},
+		},
+		{
+			name:     "outage started before report",
+			records:  []record{{-2, EventDown}, {1, EventUp}},
+			expected: Report{Monitored: 36000, Downtime: 3600, Uptime: 90, Outages: 1, MTTR: 3600, LongestOutage: 3600},
+		},
+		{
+			name:     "repeated state is ignored",
+			records:  []record{{0, EventUp}, {1, EventDown}, {2, EventDown}, {3, EventUp}},
+			expected: Report{Monitored: 36000, Downtime: 7200, Uptime: 80, Outages: 1, MTTR: 7200, LongestOutage: 7200},
+		},
+		{
+			name:     "two outages",
+			records:  []record{{0, EventUp}, {1, EventDown}, {2, EventUp}, {5, EventDown}, {8, EventUp}},
+			expected: Report{Monitored: 36000, Downtime: 14400, Uptime: 60, Outages: 2, MTTR: 7200, LongestOutage: 10800},
+		},
+		{
+			name:     "not monitored before first record",
+			records:  []record{{5, EventUp}},
+			expected: Report{Monitored: 18000, Uptime: 100},
+		},
+		{
+			name:     "removed host",
+			records:  []record{{0, EventUp}, {5, EventDown}, {6, EventRemoved}},
+			expected: Report{Monitored: 21600, Downtime: 3600, Uptime: 100 * 18000.0 / 21600, Outages: 1, MTTR: 3600, LongestOutage: 3600},
+		},
+		{
+			name:        "outage partly in maintenance",
+			records:     []record{{0, EventUp}, {2, EventDown}, {4, EventUp}},
+			maintenance: []Maintenance{{From: at(2), To: at(3)}},
+			expected:    Report{Monitored: 32400, Downtime: 3600, Maintenance: 3600, Uptime: 100 * 28800.0 / 32400, Outages: 1, MTTR: 3600, LongestOutage: 3600},
+		},
+		{
+			name:        "outage in maintenance",
+			records:     []record{{0, EventUp}, {2, EventDown}, {3, EventUp}},
+			maintenance: []Maintenance{{From: at(1), To: at(4)}},
+			expected:    Report{Monitored: 25200, Maintenance: 10800, Uptime: 100},
+		},
+		{
+			name:    "overlapping maintenance windows",
+			records: []record{{0, EventUp}},
+			maintenance: []Maintenance{
+				{From: at(1), To: at(3)},
+				{From: at(2), To: at(4)},
+			},
+			expected: Report{Monitored: 25200, Maintenance: 10800, Uptime: 100},
+		},
+		{
+			name:    "maintenance of other host and topic",
+			records: []record{{0, EventUp}, {2, EventDown}, {3, EventUp}},
+			maintenance: []Maintenance{
+				{Host: "10.10.10.2", From: at(0), To: at(10)},
+				{Topic: "other", From: at(0), To: at(10)},
+			},
+			expected: Report{Monitored: 36000, Downtime: 3600, Uptime: 90, Outages: 1, MTTR: 3600, LongestOutage: 3600},
+		},
+	}
+	for _, test := range tests {
+		j := testJournal("t", map[string][]record{"10.10.10.1": test.records})
+		report := j.HostReport("t", "10.10.10.1", at(0), at(10), test.maintenance)
+		e := test.expected
+		if report.Monitored != e.Monitored || report.Downtime != e.Downtime || report.Maintenance != e.Maintenance ||
+			report.Uptime != e.Uptime || report.Outages != e.Outages || report.MTTR != e.MTTR || report.LongestOutage != e.LongestOutage {
+			t.Errorf("%s: report is %+v, expected %+v", test.name, *report, e)
+		}
+	}
+}
+
+func TestTopicReport(t *testing.T) {
+	j := testJournal("t", map[string][]record{
+		"10.10.10.1": {{0, EventUp}, {2, EventDown}, {3, EventUp}},
+		"10.10.10.2": {{0, EventDown}, {4, EventUp}},
+		"10.10.10.3": {{-5, EventUp}, {-1, EventRemoved}},
+	})
+	report := j.TopicReport("t", at(0), at(10), nil)
+
+	if len(report.Hosts) != 2 {
+		t.Fatalf("%d hosts in report, expected 2 (host removed before report is skipped)", len(report.Hosts))
+	}
+	if report.Monitored != 72000 || report.Downtime != 18000 || report.Outages != 2 || report.Uptime != 75 ||
+		report.MTTR != 9000 || report.LongestOutage != 14400 {
+		t.Errorf("topic report is %+v", *report)
+	}
+
+	var out bytes.Buffer
+	if err := report.WriteCSV(&out); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// header, hosts, total
+	if len(rows) != 4 {
+		t.Fatalf("%d csv rows, expected 4", len(rows))
+	}
+	if total := rows[3]; total[1] != "" || total[4] != "72000" || total[7] != "75.0000" {
+		t.Errorf("total row is %v", total)
+	}
+}
@@ -0,0 +1,69 @@
+package httpclient
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+Request signing - outbound requests (updates and result callbacks) are signed with HMAC-SHA256 when secret is set.
+
+	X-Pinger-Timestamp: <unix seconds>
+	X-Pinger-Signature: v1=<hex(hmac_sha256(secret, timestamp + "\n" + METHOD + "\n" + request-uri + "\n" + body))>
+
+request-uri is path with query string, as sent in http request line (e.g. `/pingresult?host=10.10.10.1`).
+Receiver should recompute signature with constant-time compare and reject requests with timestamp
+older than a few minutes (replay protection).
+*/
+
+// Signature headers
+const (
+	HeaderTimestamp = "X-Pinger-Timestamp"
+	HeaderSignature = "X-Pinger-Signature"
+	SignatureVersion = "v1"
+)
+
+// Signature - compute signature value
+func Signature(secret string, timestamp string, method string, uri string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + strings.ToUpper(method) + "\n" + uri + "\n"))
+	mac.Write(body)
+	return SignatureVersion + "=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Sign - add timestamp and signature headers to request. Does nothing if secret is empty
+func Sign(req *http.Request, body []byte, secret string) {
+	if secret == "" {
+		return
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Signature(secret, timestamp, req.Method, req.URL.RequestURI(), body))
+}
+
+/*
+Verify - check request signature on receiving side; maxAge limits timestamp skew in both directions
+*/
+func Verify(req *http.Request, body []byte, secret string, maxAge time.Duration) error {
+	timestamp := req.Header.Get(HeaderTimestamp)
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("missing or wrong %s header", HeaderTimestamp)
+	}
+	skew := time.Since(time.Unix(ts, 0))
+	if skew > maxAge || skew < -maxAge {
+		return fmt.Errorf("request timestamp is out of allowed window")
+	}
+
+	expected := Signature(secret, timestamp, req.Method, req.URL.RequestURI(), body)
+	if !hmac.Equal([]byte(expected), []byte(req.Header.Get(HeaderSignature))) {
+		return fmt.Errorf("wrong signature")
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package httpclient
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSignature(t *testing.T) {
+	tests := []struct {
+		method    string
+		uri       string
+		body      string
+		signature string
+	}{
+		{"POST", "/pingresult?host=10.10.10.1", `{"a":1}`, "v1=1e2f313ec9b1c433511fab2177e0da98aa3d464295a6b04ea80e49cbe36d5916"},
+		{"get", "/", "", "v1=ed0742ad965cf6eaf97659b8d2fff76e76c3655edb9aaf7d9e87b4fa806a1f3d"},
+	}
+	for _, test := range tests {
+		signature := Signature("secret", "1700000000", test.method, test.uri, []byte(test.body))
+		if signature != test.signature {
+			t.Errorf("%s %s: signature is %s, expected %s", test.method, test.uri, signature, test.signature)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	const body = `{"10.10.10.1":true}`
+	tests := []struct {
+		name   string
+		change func(req *http.Request) (string, string) // returns body and secret for Verify
+		valid  bool
+	}{
+		{"signed", func(req *http.Request) (string, string) { return body, "secret" }, true},
+		{"wrong secret", func(req *http.Request) (string, string) { return body, "other" }, false},
+		{"changed body", func(req *http.Request) (string, string) { return `{"10.10.10.1":false}`, "secret" }, false},
+		{"changed query", func(req *http.Request) (string, string) {
+			req.URL.RawQuery = "host=10.10.10.2"
+			return body, "secret"
+		}, false},
+		{"changed method", func(req *http.Request) (string, string) {
+			req.Method = http.MethodPut
+			return body, "secret"
+		}, false},
+		{"old timestamp", func(req *http.Request) (string, string) {
+			timestamp := strconv.FormatInt(time.Now().Add(-10*time.Minute).Unix(), 10)
+			req.Header.Set(HeaderTimestamp, timestamp)
+			req.Header.Set(HeaderSignature, Signature("secret", timestamp, req.Method, req.URL.RequestURI(), []byte(body)))
+			return body, "secret"
+		}, false},
+		{"future timestamp", func(req *http.Request) (string, string) {
+			timestamp := strconv.FormatInt(time.Now().Add(10*time.Minute).Unix(), 10)
+			req.Header.Set(HeaderTimestamp, timestamp)
+			req.Header.Set(HeaderSignature, Signature("secret", timestamp, req.Method, req.URL.RequestURI(), []byte(body)))
+			return body, "secret"
+		}, false},
+		{"missing timestamp", func(req *http.Request) (string, string) {
+			req.Header.Del(HeaderTimestamp)
+			return body, "secret"
+		}, false},
+		{"missing signature", func(req *http.Request) (string, string) {
+			req.Header.Del(HeaderSignature)
+			return body, "secret"
+		}, false},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest(http.MethodPost, "http://127.0.0.1/updates?host=10.10.10.1", nil)
+		Sign(req, []byte(body), "secret")
+		received, secret := test.change(req)
+		err := Verify(req, []byte(received), secret, 5*time.Minute)
+		if (err == nil) != test.valid {
+			t.Errorf("%s: verify error is %v, expected valid %v", test.name, err, test.valid)
+		}
+	}
+}
+
+func TestSignWithoutSecret(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "http://127.0.0.1/updates", nil)
+	Sign(req, []byte("{}"), "")
+	if req.Header.Get(HeaderTimestamp) != "" || req.Header.Get(HeaderSignature) != "" {
+		t.Errorf("request without secret is signed: %v", req.Header)
+	}
+}
@@ -0,0 +1,149 @@
+package httpclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+/*
+RequestTemplate - http request with method, url, headers and body defined as text/template.
+Besides text/template builtins, templates can use:
+
+	query    - escape string for url query value (url.QueryEscape)
+	path     - escape string for url path segment (url.PathEscape)
+	json     - encode value as json
+	rfc3339  - format time as RFC3339
+	unix     - time as unix timestamp
+	ms       - time.Duration in milliseconds
+	default  - `{{default "none" .Labels.site}}`, fallback for empty value
+*/
+type RequestTemplate struct {
+	Method  *template.Template
+	URL     *template.Template
+	Headers map[string]*template.Template
+	Body    *template.Template
+}
+
+// TemplateFuncs - functions available in request templates
+var TemplateFuncs = template.FuncMap{
+	"query":   url.QueryEscape,
+	"path":    url.PathEscape,
+	"json":    templateJSON,
+	"rfc3339": func(t time.Time) string { return t.Format(time.RFC3339) },
+	"unix":    func(t time.Time) int64 { return t.Unix() },
+	"ms":      func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) },
+	"default": templateDefault,
+}
+
+// ParseRequestTemplate - parse request parts; empty method means GET (POST if body is set)
+func ParseRequestTemplate(method string, rawURL string, headers map[string]string, body string) (*RequestTemplate, error) {
+	if method == "" {
+		method = "GET"
+		if body != "" {
+			method = "POST"
+		}
+	}
+
+	var err error
+	t := &RequestTemplate{Headers: make(map[string]*template.Template)}
+	if t.Method, err = parseTemplate("method", method); err != nil {
+		return nil, err
+	}
+	if t.URL, err = parseTemplate("url", rawURL); err != nil {
+		return nil, err
+	}
+	for name, value := range headers {
+		if t.Headers[name], err = parseTemplate("header "+name, value); err != nil {
+			return nil, err
+		}
+	}
+	if body != "" {
+		if t.Body, err = parseTemplate("body", body); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// NewRequest - render template with data into http request. Rendered body is returned for signing
+func (t *RequestTemplate) NewRequest(data interface{}) (*http.Request, []byte, error) {
+	method, err := execute(t.Method, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	rawURL, err := execute(t.URL, data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var body []byte
+	if t.Body != nil {
+		rendered, err := execute(t.Body, data)
+		if err != nil {
+			return nil, nil, err
+		}
+		body = []byte(rendered)
+	}
+
+	req, err := http.NewRequest(strings.ToUpper(strings.TrimSpace(method)), strings.TrimSpace(rawURL), bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	names := make([]string, 0, len(t.Headers))
+	for name := range t.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value, err := execute(t.Headers[name], data)
+		if err != nil {
+			return nil, nil, err
+		}
+		req.Header.Set(name, value)
+	}
+	return req, body, nil
+}
+
+// HasBody - body template is set
+func (t *RequestTemplate) HasBody() bool {
+	return t.Body != nil
+}
+
+func parseTemplate(name string, text string) (*template.Template, error) {
+	t, err := template.New(name).Funcs(TemplateFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse %s template: %s", name, err.Error())
+	}
+	return t, nil
+}
+
+func execute(t *template.Template, data interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("cannot render %s", err.Error())
+	}
+	return buf.String(), nil
+}
+
+func templateJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+func templateDefault(def interface{}, value interface{}) interface{} {
+	if value == nil {
+		return def
+	}
+	if s, ok := value.(string); ok && s == "" {
+		return def
+	}
+	return value
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"sync"
 	"time"
 )
 
@@ -12,6 +13,7 @@ type logger struct {
 	DebugLock    bool
 	LogPath      string
 	LogFile      *os.File
+	mx           sync.RWMutex // DebugEnabled, LogPath and LogFile are changed on config reload
 }
 
 var mlog = logger{
@@ -30,6 +32,9 @@ func (l *logger) Write(format string, args ...interface{}) {
 	tm := fmt.Sprintf("[%d.%02d.%02d %02d:%02d:%02d]: ", t.Day(), t.Month(), t.Year(), t.Hour(), t.Minute(), t.Second())
 
 	fmt.Printf(tm+format+"\n", args...)
+	// file is not closed while it's written, see OpenPath
+	mlog.mx.RLock()
+	defer mlog.mx.RUnlock()
 	if mlog.LogFile != nil {
 		log.Printf(format+"\n", args...)
 	}
@@ -39,6 +44,8 @@ func (l *logger) Write(format string, args ...interface{}) {
 WriteAsIs - write log without any formatting
 */
 func (l *logger) WriteAsIs(format string, args ...interface{}) {
+	mlog.mx.RLock()
+	defer mlog.mx.RUnlock()
 	if mlog.LogFile != nil {
 		//fmt.Printf(format, args...)
 		log.Printf(format, args...)
@@ -56,7 +63,7 @@ func Log(format string, args ...interface{}) {
 DebugAsIs - debug without any formatting
 */
 func DebugAsIs(format string, args ...interface{}) {
-	if !mlog.DebugEnabled {
+	if !debugEnabled() {
 		return
 	}
 	mlog.WriteAsIs(format, args...)
@@ -66,7 +73,7 @@ func DebugAsIs(format string, args ...interface{}) {
 Debug - write in debug mode
 */
 func Debug(format string, args ...interface{}) {
-	if !mlog.DebugEnabled {
+	if !debugEnabled() {
 		return
 	}
 	mlog.Write("[DEBUG]: "+format, args...)
@@ -88,10 +95,19 @@ func DebugLock(format string, args ...interface{}) {
 	}
 }
 
+// debugEnabled - debug mode, see SetDebug
+func debugEnabled() bool {
+	mlog.mx.RLock()
+	defer mlog.mx.RUnlock()
+	return mlog.DebugEnabled
+}
+
 /*
 SetDebug - enable/disable debug
 */
 func SetDebug(val bool) {
+	mlog.mx.Lock()
+	defer mlog.mx.Unlock()
 	mlog.DebugEnabled = val
 }
 
@@ -99,15 +115,39 @@ func SetDebug(val bool) {
 SetPath - set logfile path
 */
 func SetPath(path string) {
-	mlog.LogPath = path
+	if err := OpenPath(path); err != nil {
+		log.Fatalf("Cannot open logfile %s!", path)
+	}
+}
 
-	if mlog.LogPath != "" {
+/*
+OpenPath - switch logfile (on config reload); previous file is closed after new one is used. Empty path disables logfile.
+On error previous file is kept
+*/
+func OpenPath(path string) error {
+	var file *os.File
+	if path != "" {
 		var err error
-		mlog.LogFile, err = os.OpenFile(mlog.LogPath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+		file, err = os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
 		if err != nil {
-			log.Fatalf("Cannot open logfile %s!", mlog.LogPath)
+			return err
 		}
+	}
+
+	mlog.mx.Lock()
+	if file != nil {
+		log.SetOutput(file)
+	} else {
+		log.SetOutput(os.Stderr)
+	}
+	previous := mlog.LogFile
+	mlog.LogPath = path
+	mlog.LogFile = file
+	mlog.mx.Unlock()
 
-		log.SetOutput(mlog.LogFile)
+	// writes hold read lock: nothing is written to previous file now
+	if previous != nil {
+		previous.Close()
 	}
+	return nil
 }
@@ -1,17 +1,24 @@
 package main
 
 import (
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"github.com/gorilla/mux"
-	"io"
+	"google.golang.org/grpc"
 	"log"
 	"net"
 	"net/http"
+	"os"
+	"pinger/auth"
 	"pinger/ccfg"
+	"pinger/ctl"
+	"pinger/grpcapi"
+	"pinger/history"
 	"pinger/logger"
+	"pinger/metrics"
 	"pinger/pinger"
 	"pinger/pools"
 	"pinger/web"
@@ -25,11 +32,37 @@ import (
 var cfg *ccfg.Cfg
 
 func main() {
+	// `pinger ctl ...` - command line client of running daemon
+	if len(os.Args) > 1 && os.Args[1] == "ctl" {
+		os.Exit(ctl.Run(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+	}
+
 	configPath := flag.String("c", "./pinger.toml", "Config file location")
+	checkConfig := flag.Bool("check-config", false, "Validate config file and exit")
+	dumpConfig := flag.Bool("dump-config", false, "Validate config file, print effective config (with defaults) and exit")
 	flag.Parse()
 
-	logger.Debug("Reading config file '%s'...", *configPath)
-	cfg = ccfg.New(configPath)
+	if !*checkConfig && !*dumpConfig {
+		// stdout is for check results
+		logger.Debug("Reading config file '%s'...", *configPath)
+	}
+	var err error
+	cfg, err = ccfg.Load(*configPath)
+	if err != nil {
+		printConfigError(*configPath, err)
+		os.Exit(1)
+	}
+	if *checkConfig {
+		fmt.Printf("Config '%s' is valid\n", *configPath)
+		return
+	}
+	if *dumpConfig {
+		if err := ccfg.Dump(os.Stdout, cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot dump config: %s\n", err.Error())
+			os.Exit(1)
+		}
+		return
+	}
 	logger.SetPath(cfg.LogPath)
 	logger.SetDebug(cfg.LogDebug)
 
@@ -40,10 +73,29 @@ func main() {
 
 	// Init random sequence
 	rand.Seed(time.Now().UTC().UnixNano())
-	// Init notify buffer
+	// Init notifiers and notify buffer
+	if err := notify.Configure(cfg.Notifiers); err != nil {
+		panic(err)
+	}
+	notify.SetDefaultSecret(cfg.UpdateSecret)
+	notify.Buffer.SpoolPath = cfg.NotifySpoolPath
+	notify.Buffer.RetryMaxSec = cfg.NotifyRetryMax
 	go notify.Buffer.Start(cfg.UpdatesInterval)
+	notify.Events.LogSize = cfg.EventLogSize
+	// Init API tokens
+	if err := auth.Init(cfg.Tokens, cfg.Certs, cfg.TokenFile); err != nil {
+		panic(err)
+	}
+	// Init state transitions journal
+	history.Journal.Init(cfg.HistoryPath, cfg.HistoryRetention)
 	// Init global pools
+	pools.TopicPool.SetDegraded(cfg.DegradedLoss, cfg.DegradedRtt)
+	pools.PingPool.SetResolveLimits(time.Duration(cfg.DNSMinInterval)*time.Second, time.Duration(cfg.DNSMaxInterval)*time.Second)
 	pools.TopicPool.Init(cfg.SavePath, cfg.SaveInterval, cfg.DefaultProbes, cfg.DefaultInterval)
+	if err := pools.TopicPool.SetStatic(cfg.Topics, cfg.DefaultProbes, cfg.DefaultInterval); err != nil {
+		logger.Err("Cannot load topics from config: %s", err.Error())
+		os.Exit(1)
+	}
 
 	logger.Log("Listening on %s://%s:%s", proto, cfg.ListenIP, cfg.ListenPort)
 	listener, err := net.Listen("tcp4", fmt.Sprintf("%s:%s", cfg.ListenIP, cfg.ListenPort))
@@ -52,34 +104,96 @@ func main() {
 	}
 
 	Web := web.NewWeb(cfg.DefaultProbes, cfg.DefaultInterval, cfg.ResultURL)
+	Web.SetMaintenance(cfg.Maintenance)
+	Web.SetAllowedOrigins(cfg.AllowedOrigins)
+	reload := &reloader{path: *configPath, started: cfg, web: Web}
 
 	// Serve http(s)
 	router := mux.NewRouter().StrictSlash(true)
-	router.HandleFunc("/ping-now", PingNow)
-	router.HandleFunc("/ping-api", PingAPI)
-	router.HandleFunc("/store-host", StoreHost)
-	router.HandleFunc("/remove-host", RemoveHost)
-	router.HandleFunc("/dump-hosts", DumpHosts)
-	router.HandleFunc("/get-or-store", Web.GetOrStore)
-	router.HandleFunc("/store", Web.Store)
+	router.HandleFunc("/ping-now", PingNow).Methods(http.MethodGet)
+	router.HandleFunc("/ping-api", PingAPI).Methods(http.MethodGet)
+	router.HandleFunc("/store-host", StoreHost).Methods(http.MethodGet)
+	router.HandleFunc("/remove-host", RemoveHost).Methods(http.MethodGet)
+	router.HandleFunc("/dump-hosts", Web.DumpHosts).Methods(http.MethodGet)
+	router.HandleFunc("/get-or-store", Web.GetOrStore).Methods(http.MethodPost)
+	router.HandleFunc("/store", Web.Store).Methods(http.MethodPost)
+	router.HandleFunc("/metrics", metrics.Handler).Methods(http.MethodGet)
+	router.HandleFunc("/sla", Web.SLA).Methods(http.MethodGet)
+	router.HandleFunc("/sla.csv", Web.SLACSV).Methods(http.MethodGet)
+	router.HandleFunc("/openapi.json", web.OpenAPIHandler).Methods(http.MethodGet)
+	router.HandleFunc("/events", Web.Events).Methods(http.MethodGet)
+	router.HandleFunc("/events/ws", Web.EventsWebSocket).Methods(http.MethodGet)
+	router.HandleFunc("/v1/topics", Web.Topics).Methods(http.MethodGet)
+	router.HandleFunc("/v1/topics/{name}", Web.Topic).Methods(http.MethodGet, http.MethodPut, http.MethodPatch, http.MethodDelete)
+	router.HandleFunc("/v1/topics/{name}/hosts/{ip}", Web.TopicHost).Methods(http.MethodGet, http.MethodPut, http.MethodPatch, http.MethodDelete)
+	router.HandleFunc("/v1/topics/{name}/hosts/{ip}/results", Web.TopicHostResults).Methods(http.MethodGet)
+	router.HandleFunc("/v1/topics/{name}/document", Web.TopicDocument).Methods(http.MethodGet)
+	router.HandleFunc("/v1/topics/{name}/history", Web.TopicHistory).Methods(http.MethodGet)
+	router.HandleFunc("/admin/reload", reload.Handler).Methods(http.MethodPost)
+	router.PathPrefix("/dashboard").Handler(web.Dashboard()).Methods(http.MethodGet, http.MethodHead)
+	router.NotFoundHandler = http.HandlerFunc(web.NotFound)
+	router.MethodNotAllowedHandler = http.HandlerFunc(web.MethodNotAllowed)
 	router.Use(Middleware)
 
+	if err := pinger.Pinger.SetResultRequest(cfg.ResultMethod, cfg.ResultURL, cfg.ResultHeaders, cfg.ResultBody, cfg.ResultSecret); err != nil {
+		panic(fmt.Sprintf("pinger.result-url: %s", err.Error()))
+	}
 	if err := pinger.Pinger.Init(); err != nil {
 		logger.Debug("Cannot initialize pinger: %s", err.Error())
 		return
 	}
+	var tlsConfig *tls.Config
 	if cfg.Ssl {
-		log.Fatal(http.ServeTLS(listener, router, cfg.SslCert, cfg.SslKey))
-	} else {
-		log.Fatal(http.Serve(listener, router))
+		reloader, err := auth.NewTLSReloader(cfg.SslCert, cfg.SslKey, cfg.SslClientCA, cfg.SslClientAuth)
+		if err != nil {
+			panic(err)
+		}
+		go reloader.Watch()
+		tlsConfig = reloader.Config()
+		reload.tls = reloader
 	}
+	go reload.HandleSignals()
+	var grpcServer *grpc.Server
+	if cfg.GrpcPort != "" {
+		grpcListener, err := net.Listen("tcp4", fmt.Sprintf("%s:%s", cfg.ListenIP, cfg.GrpcPort))
+		if err != nil {
+			panic(err)
+		}
+		logger.Log("Listening grpc on %s:%s", cfg.ListenIP, cfg.GrpcPort)
+		grpcServer = grpcapi.NewServer(&grpcapi.Server{Web: Web}, tlsConfig)
+		go func() {
+			// nil after GracefulStop/Stop
+			if err := grpcServer.Serve(grpcListener); err != nil {
+				log.Fatal(err)
+			}
+		}()
+	}
+	server := &http.Server{Handler: router, TLSConfig: tlsConfig}
+	go func() {
+		var err error
+		if cfg.Ssl {
+			err = server.ServeTLS(listener, "", "")
+		} else {
+			err = server.Serve(listener)
+		}
+		if !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+	waitShutdown(server, grpcServer)
 }
 
-/*
-DumpHosts Output all in-memory hosts
-*/
-func DumpHosts(w http.ResponseWriter, r *http.Request) {
-	//
+// printConfigError - config problems to stderr, one per line
+func printConfigError(path string, err error) {
+	var invalid *ccfg.ValidationError
+	if !errors.As(err, &invalid) {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Config '%s' is invalid:\n", path)
+	for _, problem := range invalid.Problems {
+		fmt.Fprintf(os.Stderr, "  %s\n", problem)
+	}
 }
 
 /*
@@ -89,7 +203,7 @@ func RemoveHost(w http.ResponseWriter, r *http.Request) {
 	params := GetParams(r)
 	hostParam, ok := params["host"]
 	if !ok {
-		ReturnError(w, r, "Missing 'host' parameter", http.StatusBadRequest)
+		web.ReturnFieldError(w, r, "host", "missing parameter")
 		return
 	}
 
@@ -100,7 +214,7 @@ func RemoveHost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ReturnError(w, r, "Host not found", http.StatusBadRequest)
+	web.ReturnError(w, r, "Host not found", http.StatusNotFound)
 }
 
 /*
@@ -108,24 +222,23 @@ StoreHost stores host into pool
 */
 func StoreHost(w http.ResponseWriter, r *http.Request) {
 	params := GetParams(r)
-	err := CheckParams(params, []string{"host", "interval"})
-	if err != nil {
-		ReturnError(w, r, err.Error(), http.StatusBadRequest)
+	if field := CheckParams(params, []string{"host", "interval"}); field != "" {
+		web.ReturnFieldError(w, r, field, "missing parameter")
 		return
 	}
 
 	ip := net.ParseIP(params["host"])
 	if ip == nil {
-		ReturnError(w, r, fmt.Sprintf("Cannot parpse '%s' into IP address", params["host"]), http.StatusBadRequest)
+		web.ReturnFieldError(w, r, "host", fmt.Sprintf("cannot parse '%s' into IP address", params["host"]))
 		return
 	}
 
 	probesStr, ok := params["probes"]
-	probes := cfg.DefaultProbes
+	probes := config().DefaultProbes
 	if ok {
 		p, e := strconv.ParseInt(probesStr, 10, 32)
 		if e != nil {
-			ReturnError(w, r, "Cannot parse 'probes', not integer?", http.StatusBadRequest)
+			web.ReturnFieldError(w, r, "probes", "should be integer")
 			return
 		}
 		probes = int(p)
@@ -133,18 +246,18 @@ func StoreHost(w http.ResponseWriter, r *http.Request) {
 
 	i, e := strconv.ParseInt(params["interval"], 10, 32)
 	if e != nil {
-		ReturnError(w, r, fmt.Sprintf("Cannot parse '%s' into interval integer", params["interval"]), http.StatusBadRequest)
+		web.ReturnFieldError(w, r, "interval", "should be integer")
 		return
 	}
 	if i < 30 {
-		ReturnError(w, r, fmt.Sprintf("Minimal interval is 30 sec, %d given", i), http.StatusBadRequest)
+		web.ReturnFieldError(w, r, "interval", fmt.Sprintf("minimal interval is 30 sec, %d given", i))
 		return
 	}
 	interval := i
 
-	err = pools.PingPool.AddHost(params["host"], probes, interval, cfg.ResultURL)
+	err := pools.PingPool.AddHost(params["host"], probes, interval, config().ResultURL, "")
 	if err != nil {
-		ReturnError(w, r, fmt.Sprintf("Failed to add host: %s", err.Error()), http.StatusInternalServerError)
+		web.ReturnError(w, r, fmt.Sprintf("Failed to add host: %s", err.Error()), http.StatusInternalServerError)
 		return
 	}
 
@@ -169,11 +282,17 @@ func PingAPI(w http.ResponseWriter, r *http.Request) {
 /*
 Ping calls ping now or ping api
 */
-func Ping(w io.Writer, r *http.Request, pingType string) {
+func Ping(w http.ResponseWriter, r *http.Request, pingType string) {
 	params := GetParams(r)
 	host, ok := params["host"]
 	if !ok {
-		ReturnError(w, r, "Missing host parameter", http.StatusBadRequest)
+		web.ReturnFieldError(w, r, "host", "missing parameter")
+		return
+	}
+	// tokens limited to topics can ping only hosts of their topics
+	principal := auth.FromRequest(r)
+	if topicName, ok := params["topic"]; (ok && !principal.Can(auth.ScopePing, topicName)) || !web.HostAllowed(principal, auth.ScopePing, host) {
+		web.Deny(w, r, http.StatusForbidden)
 		return
 	}
 
@@ -181,7 +300,7 @@ func Ping(w io.Writer, r *http.Request, pingType string) {
 	if probesStr, ok := params["probes"]; ok {
 		p, err := strconv.ParseInt(probesStr, 10, 32)
 		if err != nil {
-			ReturnError(w, r, "Cannot parse 'probes'", http.StatusBadRequest)
+			web.ReturnFieldError(w, r, "probes", "should be integer")
 			return
 		}
 		probes = int(p)
@@ -189,40 +308,49 @@ func Ping(w io.Writer, r *http.Request, pingType string) {
 
 	if "now" == pingType {
 		result, err := pinger.Pinger.PingNow(host, probes)
-		if err != nil {
-			ReturnError(w, r, fmt.Sprintf("Ping : %s", err.Error()), http.StatusInternalServerError)
+		if errors.Is(err, pinger.ErrResolve) {
+			web.ReturnFieldError(w, r, "host", err.Error())
+			return
+		} else if errors.Is(err, pinger.ErrJobRunning) {
+			web.ReturnError(w, r, err.Error(), http.StatusConflict)
+			return
+		} else if errors.Is(err, pinger.ErrStopping) {
+			web.ReturnError(w, r, err.Error(), http.StatusServiceUnavailable)
+			return
+		} else if err != nil {
+			web.ReturnError(w, r, fmt.Sprintf("Ping : %s", err.Error()), http.StatusInternalServerError)
 			return
 		}
 		bytes, e := json.Marshal(result)
 		if e != nil {
-			ReturnError(w, r, fmt.Sprintf("Internal error: %s", e.Error()), http.StatusInternalServerError)
+			web.ReturnError(w, r, fmt.Sprintf("Internal error: %s", e.Error()), http.StatusInternalServerError)
 			return
 		}
 
 		fmt.Fprintf(w, "%s", string(bytes))
 		return
 	} else if "api" == pingType {
-		if "" == cfg.ResultURL {
-			ReturnError(w, r, "Missing result.api-url in config", http.StatusInternalServerError)
+		if "" == config().ResultURL {
+			web.ReturnError(w, r, "Missing pinger.result-url in config", http.StatusServiceUnavailable)
 			return
 		}
 
-		go pinger.Pinger.PingResultURL(host, probes, cfg.ResultURL)
+		data := pinger.ResultData{}
+		if topicName, ok := params["topic"]; ok {
+			data.Topic = topicName
+			if topic, ok := pools.TopicPool.Topics.Load(topicName); ok {
+				data.Labels = topic.(*pools.Topic).Labels
+				if dbHost, ok := topic.(*pools.Topic).Hosts.Load(host); ok {
+					data.Labels = dbHost.(*pools.DBHost).Labels
+				}
+			}
+		}
+		go pinger.Pinger.PingResultURL(host, probes, data)
 		fmt.Fprintf(w, `{"ok":true}`)
 		return
 	}
 }
 
-/*
-ReturnError returns an http error and logs it into error log
-*/
-//func ReturnError(w http.ResponseWriter, r *http.Request, err string, status int) {
-func ReturnError(w io.Writer, r *http.Request, err string, status int) {
-	//http.Error(w, err, status)
-	fmt.Fprintf(w, fmt.Sprintf(`{"ok":false, "message":"%s"}`, err))
-	logger.Err("[web]: Error: %s (%s)", err, r.URL)
-}
-
 /*
 GetParams parses query string into parameters map
 */
@@ -240,15 +368,96 @@ func GetParams(r *http.Request) map[string]string {
 }
 
 /*
-CheckParams checking url parameters for set of required ones
+CheckParams checking url parameters for set of required ones; returns first missing parameter
 */
-func CheckParams(params map[string]string, required []string) error {
+func CheckParams(params map[string]string, required []string) string {
 	for _, p := range required {
 		if _, exist := params[p]; !exist {
-			return fmt.Errorf("Missing parameter '%s'", p)
+			return p
 		}
 	}
-	return nil
+	return ""
+}
+
+// routeScopes - scope required for route; routes in globalRoutes require it for all topics
+var routeScopes = map[string]string{
+	"/ping-now":     auth.ScopePing,
+	"/ping-api":     auth.ScopePing,
+	"/store-host":   auth.ScopeWrite,
+	"/remove-host":  auth.ScopeWrite,
+	"/dump-hosts":   auth.ScopeRead,
+	"/get-or-store": auth.ScopeWrite,
+	"/store":        auth.ScopeWrite,
+	"/metrics":      auth.ScopeRead,
+	"/sla":          auth.ScopeRead,
+	"/sla.csv":      auth.ScopeRead,
+	// topic resources: GET requires read, other methods - write scope for topic (checked in handlers)
+	"/v1/topics":                           auth.ScopeRead,
+	"/v1/topics/{name}":                    auth.ScopeRead,
+	"/v1/topics/{name}/hosts/{ip}":         auth.ScopeRead,
+	"/v1/topics/{name}/hosts/{ip}/results": auth.ScopeRead,
+	"/v1/topics/{name}/document":           auth.ScopeWrite,
+	"/v1/topics/{name}/history":            auth.ScopeRead,
+	"/events":                              auth.ScopeRead,
+	"/events/ws":                           auth.ScopeRead,
+	"/admin/reload":                        auth.ScopeAdmin,
+}
+
+// publicRoutes - routes served without authentication: dashboard static files (dashboard uses API with user's token)
+var publicRoutes = map[string]bool{
+	"/dashboard": true,
+}
+
+// streamRoutes - routes, which accept token in `access_token` parameter: EventSource and WebSocket cannot send headers
+var streamRoutes = map[string]bool{
+	"/events":    true,
+	"/events/ws": true,
+}
+
+// globalRoutes - routes, which are not limited to topics (pingpool hosts, all metrics)
+var globalRoutes = map[string]bool{
+	"/store-host":   true,
+	"/remove-host":  true,
+	"/metrics":      true,
+	"/admin/reload": true,
+}
+
+/*
+authorize - authenticate request and check route scope; principal is stored in request context
+*/
+func authorize(w http.ResponseWriter, req *http.Request) (*http.Request, bool) {
+	path := routePath(req)
+	token := auth.RequestToken(req.Header.Get("X-API-Key"), req.Header.Get("Authorization"))
+	if token == "" && streamRoutes[path] {
+		token = req.URL.Query().Get("access_token")
+	}
+	principal, ok := auth.AuthenticateToken(token, req.TLS)
+	if !ok {
+		web.Deny(w, req, http.StatusUnauthorized)
+		return req, false
+	}
+	req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+
+	if scope, ok := routeScopes[path]; ok {
+		if scope == auth.ScopeRead && strings.HasPrefix(path, "/v1/") && req.Method != http.MethodGet {
+			scope = auth.ScopeWrite
+		}
+		if (globalRoutes[path] && !principal.CanAll(scope)) || !principal.HasScope(scope) {
+			web.Deny(w, req, http.StatusForbidden)
+			return req, false
+		}
+	}
+	return req, true
+}
+
+// routePath - path template of matched route (`/v1/topics/{name}`), request path if there is no route
+func routePath(req *http.Request) string {
+	if route := mux.CurrentRoute(req); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return req.URL.Path
 }
 
 /*
@@ -268,10 +477,22 @@ func Middleware(next http.Handler) http.Handler {
 					e = errors.New("Unknown panic")
 				}
 				logger.Err("[web]: recovered in middleware: %#v", e.Error())
-				http.Error(w, "Internal error", 500)
+				web.ReturnError(w, req, "Internal error", http.StatusInternalServerError)
 			}
 		}()
 
+		if publicRoutes[routePath(req)] {
+			next.ServeHTTP(w, req)
+			return
+		}
+		req, ok := authorize(w, req)
+		if !ok {
+			return
+		}
+		if !web.ValidateRequest(w, req) {
+			return
+		}
+
 		w.Header().Set("Content-Type", "application/json")
 		next.ServeHTTP(w, req)
 	})
@@ -0,0 +1,257 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/*
+Metrics - tiny in-memory registry, rendered in prometheus text exposition format on /metrics.
+Every package declares its own metric families as package variables and updates them in place.
+*/
+
+// Metric family kinds
+const (
+	KindGauge     = "gauge"
+	KindCounter   = "counter"
+	KindHistogram = "histogram"
+)
+
+// DefaultBuckets - default histogram buckets (seconds)
+var DefaultBuckets = []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Labels - metric labels, name => value
+type Labels map[string]string
+
+type series struct {
+	labels  Labels
+	value   float64
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+/*
+Family - single metric family (name, help, type) with all of it's labelled series
+*/
+type Family struct {
+	Name    string
+	Help    string
+	Kind    string
+	buckets []float64
+	fn      func() float64
+	series  map[string]*series
+	mx      sync.Mutex
+}
+
+type registry struct {
+	families map[string]*Family
+	mx       sync.Mutex
+}
+
+var reg = registry{families: make(map[string]*Family)}
+
+func register(f *Family) *Family {
+	reg.mx.Lock()
+	defer reg.mx.Unlock()
+	if old, ok := reg.families[f.Name]; ok {
+		return old
+	}
+	f.series = make(map[string]*series)
+	reg.families[f.Name] = f
+	return f
+}
+
+// NewGauge - register new gauge family
+func NewGauge(name string, help string) *Family {
+	return register(&Family{Name: name, Help: help, Kind: KindGauge})
+}
+
+// NewCounter - register new counter family
+func NewCounter(name string, help string) *Family {
+	return register(&Family{Name: name, Help: help, Kind: KindCounter})
+}
+
+// NewHistogram - register new histogram family; nil buckets means DefaultBuckets
+func NewHistogram(name string, help string, buckets []float64) *Family {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	b := make([]float64, len(buckets))
+	copy(b, buckets)
+	sort.Float64s(b)
+	return register(&Family{Name: name, Help: help, Kind: KindHistogram, buckets: b})
+}
+
+// NewGaugeFunc - register gauge, which value is taken from fn on every scrape
+func NewGaugeFunc(name string, help string, fn func() float64) *Family {
+	return register(&Family{Name: name, Help: help, Kind: KindGauge, fn: fn})
+}
+
+func (f *Family) get(labels Labels) *series {
+	key := labels.String()
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labels: labels}
+		if f.Kind == KindHistogram {
+			s.buckets = make([]uint64, len(f.buckets))
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+// Set - set gauge value
+func (f *Family) Set(labels Labels, value float64) {
+	f.mx.Lock()
+	f.get(labels).value = value
+	f.mx.Unlock()
+}
+
+// Add - add delta to gauge or counter
+func (f *Family) Add(labels Labels, delta float64) {
+	f.mx.Lock()
+	f.get(labels).value += delta
+	f.mx.Unlock()
+}
+
+// Inc - increment gauge or counter by one
+func (f *Family) Inc(labels Labels) {
+	f.Add(labels, 1)
+}
+
+// Observe - add histogram observation
+func (f *Family) Observe(labels Labels, value float64) {
+	f.mx.Lock()
+	s := f.get(labels)
+	for i, le := range f.buckets {
+		if value <= le {
+			s.buckets[i]++
+		}
+	}
+	s.count++
+	s.sum += value
+	f.mx.Unlock()
+}
+
+// Delete - remove series with given labels (i.e. when host is removed)
+func (f *Family) Delete(labels Labels) {
+	f.mx.Lock()
+	delete(f.series, labels.String())
+	f.mx.Unlock()
+}
+
+// Write - write family in text exposition format
+func (f *Family) Write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", f.Name, escapeHelp(f.Help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.Name, f.Kind)
+
+	if f.fn != nil {
+		fmt.Fprintf(w, "%s %s\n", f.Name, formatFloat(f.fn()))
+		return
+	}
+
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	keys := make([]string, 0, len(f.series))
+	for k := range f.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		s := f.series[k]
+		if f.Kind != KindHistogram {
+			fmt.Fprintf(w, "%s%s %s\n", f.Name, k, formatFloat(s.value))
+			continue
+		}
+		for i, le := range f.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", f.Name, s.labels.with("le", formatFloat(le)), s.buckets[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", f.Name, s.labels.with("le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", f.Name, k, formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", f.Name, k, s.count)
+	}
+}
+
+// String - render labels as `{a="b",c="d"}` with sorted keys
+func (l Labels) String() string {
+	if len(l) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(l))
+	for k := range l {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, k, escapeValue(l[k])))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func (l Labels) with(name string, value string) string {
+	n := Labels{name: value}
+	for k, v := range l {
+		n[k] = v
+	}
+	return n.String()
+}
+
+/*
+Handler - http handler for /metrics
+*/
+func Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	Write(w)
+}
+
+// Write - write all registered families
+func Write(w io.Writer) {
+	reg.mx.Lock()
+	names := make([]string, 0, len(reg.families))
+	for name := range reg.families {
+		names = append(names, name)
+	}
+	reg.mx.Unlock()
+	sort.Strings(names)
+
+	for _, name := range names {
+		reg.mx.Lock()
+		f := reg.families[name]
+		reg.mx.Unlock()
+		f.Write(w)
+	}
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var valueReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+var helpReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func escapeValue(v string) string {
+	return valueReplacer.Replace(v)
+}
+
+func escapeHelp(v string) string {
+	return helpReplacer.Replace(v)
+}
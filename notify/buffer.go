@@ -1,32 +1,56 @@
 package notify
 
 import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
 	"sync"
 	"time"
-	"pinger/pinger"
-	"encoding/json"
 	"pinger/logger"
-	"pinger/httpclient"
-	"net/http"
-	"bytes"
+	"pinger/metrics"
 )
 
 /*
 Notify buffer - buffer host's changes for some period (given in config); every time ticker - sends updates
-to update url's
+to notifiers (update url's are implicit webhook notifiers)
+
+store: [notifier] => [topic|host=>event][topic|host=>event]
 
-store: [updateurls] => [host=>state][host=>state]
+Failed deliveries are put back into buffer (unless there is newer event for the same host, latest state wins)
+and retried with exponential backoff: interval, 2*interval, 4*interval... up to RetryMaxSec.
+If SpoolPath is set, undelivered events are stored there after each flush and loaded on start.
 */
 
 type buffer struct {
 	IntervalSec		int64
-	Urls			sync.Map
+	RetryMaxSec		int64
+	SpoolPath		string
+	Notifiers		sync.Map		// name => *sync.Map[topic|host => Event]
+	retries			sync.Map		// name => *retry
+	ticker			*time.Ticker
+	immediate		sync.WaitGroup	// running deliveries of immediate notifiers
 	mx				sync.Mutex
 }
 
+// retry - backoff state of failing notifier
+type retry struct {
+	Attempts	int
+	Next		time.Time
+}
+
 // Buffer - global buffer struct instance
 var Buffer buffer
 
+var errUnknownNotifier = errors.New("unknown notifier")
+
+var (
+	flushes = metrics.NewCounter("pinger_notify_flush_total", "Notifier deliveries by result")
+	queued  = metrics.NewGauge("pinger_notify_queued_events", "Undelivered events per notifier after last flush")
+)
+
 func (b *buffer) Lock() {
 	b.mx.Lock()
 }
@@ -34,69 +58,250 @@ func (b *buffer) Unlock() {
 	b.mx.Unlock()
 }
 
-// BufferResult - add new ping result to result map for furture updates
-func (b *buffer) BufferResult(url string, ip string, result pinger.PingResult) {
-	resultMap, _ := b.Urls.LoadOrStore(url, &sync.Map{})
-	resultMap.(*sync.Map).Store(ip, result)
+// BufferURL - add new event for update url
+func (b *buffer) BufferURL(url string, options WebhookOptions, event Event) {
+	b.BufferEvent(URLNotifier(url, options).Name(), event)
+}
+
+// BufferEvent - add new event to notifier's map for furture updates. Newer event for same host replaces older one
+func (b *buffer) BufferEvent(notifier string, event Event) {
+	if n, ok := Get(notifier); ok {
+		if i, ok := n.(Immediate); ok && i.IsImmediate() {
+			b.immediate.Add(1)
+			go func() {
+				defer b.immediate.Done()
+				if err := b.deliver(notifier, []Event{event}); err != nil {
+					b.requeue(notifier, []Event{event})
+				}
+			}()
+			return
+		}
+	}
+	b.events(notifier).Store(eventKey(event), event)
+}
+
+func (b *buffer) events(notifier string) *sync.Map {
+	eventMap, _ := b.Notifiers.LoadOrStore(notifier, &sync.Map{})
+	return eventMap.(*sync.Map)
+}
+
+func eventKey(e Event) string {
+	return e.Topic + "|" + e.Host
+}
+
+// requeue - put undelivered events back, if there are no newer events for same hosts
+func (b *buffer) requeue(notifier string, events []Event) {
+	eventMap := b.events(notifier)
+	for _, e := range events {
+		eventMap.LoadOrStore(eventKey(e), e)
+	}
 }
 
 func (b *buffer) Start(interval int64) {
+	b.loadSpool()
+	b.Lock()
 	b.IntervalSec = interval
-	ticker := time.NewTicker(time.Duration(b.IntervalSec) * time.Second)
+	b.ticker = time.NewTicker(time.Duration(b.IntervalSec) * time.Second)
+	ticker := b.ticker
+	b.Unlock()
 
 	for {
 		select {
 		case <- ticker.C:
-			//logger.Debug("[buffer.Start]: <- ticker.C")
-			// todo: loop over all urls => hosts
-			b.Urls.Range(func(k, v interface{}) bool {							// url->results[ip->result]
-				//logger.Debug("[buffer.Start]: Update url: %s", k.(string))
-				url := k.(string)
-				//url := "https://w-tech.ip-home.net/pingupdate404"
-				values := make(map[string]bool)
-				hostupdates := v.(*sync.Map)
-				hostupdates.Range(func(ipInterface, u interface{}) bool {		// ip->result
-					ip := ipInterface.(string)
-					update := u.(pinger.PingResult)
-					values[ip] = update.Alive
-					hostupdates.Delete(ip)
-					return true
-				})
-
-				// send json to url
-				jsonValues, err := json.Marshal(values)
-				if err != nil {
-					logger.Err("buffer.Ticker: Cannot marshal results to json: %s\nValues: %+v", err.Error(), values)
-					return true
-				}
-				logger.Debug("JSON UPDATES for '%s': %+v", url, string(jsonValues))
-
-				if len(values) > 0 {
-					client := httpclient.NewTimeoutClient(15 * time.Second, 15 * time.Second)
-					req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonValues))
-					req.Close = true
-					if err != nil {
-						logger.Err("[buffer.Ticker]: failed to make new http request: %s", err.Error())
-						return true
-					}
-					req.Header.Set("Content-Type", "application/json")
-					response, err := client.Do(req)
-					if err != nil {
-						logger.Err("[buffer.Ticker]: Failed to make update request: %s", err.Error())
-						return true
-					}
-					defer response.Body.Close()
-					response.Close = true
-
-					req.Body.Close()
-					if response.StatusCode != http.StatusOK {
-						logger.Err("[buffer.Ticker]: Update request on '%s' failed: status %d (%s)", url, response.StatusCode, response.Status)
-						return true
-					}
-				}
+			b.Flush()
+		}
+	}
+}
+
+// SetOptions - change flush interval, retry limit and spool path of started buffer (on config reload)
+func (b *buffer) SetOptions(interval int64, retryMaxSec int64, spoolPath string) {
+	b.Lock()
+	defer b.Unlock()
+	b.IntervalSec = interval
+	b.RetryMaxSec = retryMaxSec
+	b.SpoolPath = spoolPath
+	if b.ticker != nil {
+		b.ticker.Reset(time.Duration(interval) * time.Second)
+	}
+}
 
-				return true
-			})
+/*
+Stop - final flush on shutdown: wait for immediate deliveries, send buffered events (notifiers waiting for retry
+get the last attempt too) and store undelivered ones to spool; without spool they are dropped and counted in log.
+Events buffered after Stop are delivered only if pinger is not stopped yet
+*/
+func (b *buffer) Stop() {
+	b.Lock()
+	if b.ticker != nil {
+		b.ticker.Stop()
+	}
+	b.Unlock()
+	b.immediate.Wait()
+	b.flush(true)
+
+	b.Lock()
+	defer b.Unlock()
+	if b.SpoolPath != "" {
+		return
+	}
+	for name, events := range b.Pending() {
+		logger.Err("[buffer.Stop]: notifier '%s' failed, %d updates dropped (set pinger.notify-spool-path to keep them)", name, len(events))
+	}
+}
+
+// Flush - send all buffered events to their notifiers (except ones waiting for retry)
+func (b *buffer) Flush() {
+	b.flush(false)
+}
+
+// flush - send buffered events; with all=true notifiers waiting for retry are tried too
+func (b *buffer) flush(all bool) {
+	b.Lock()
+	defer b.Unlock()
+
+	now := time.Now()
+	b.Notifiers.Range(func(k, v interface{}) bool {							// notifier->events[topic|ip->event]
+		name := k.(string)
+		if r, ok := b.retries.Load(name); ok && !all && now.Before(r.(*retry).Next) {
+			return true
+		}
+
+		events := make([]Event, 0)
+		hostupdates := v.(*sync.Map)
+		hostupdates.Range(func(key, _ interface{}) bool {
+			if e, ok := hostupdates.LoadAndDelete(key); ok {
+				events = append(events, e.(Event))
+			}
+			return true
+		})
+		if len(events) == 0 {
+			return true
+		}
+		sort.SliceStable(events, func(a, b int) bool { return events[a].Time.Before(events[b].Time) })
+
+		if err := b.deliver(name, events); err != nil {
+			if _, known := Get(name); known {
+				b.requeue(name, events)
+				b.backoff(name)
+			}
+			return true
 		}
+		b.retries.Delete(name)
+		return true
+	})
+
+	b.saveSpool()
+}
+
+// backoff - schedule next attempt for failed notifier
+func (b *buffer) backoff(name string) {
+	r := &retry{}
+	if old, ok := b.retries.Load(name); ok {
+		r = old.(*retry)
 	}
+	r.Attempts++
+
+	delay := time.Duration(b.IntervalSec) * time.Second
+	max := time.Duration(b.RetryMaxSec) * time.Second
+	for i := 1; i < r.Attempts && (max <= 0 || delay < max); i++ {
+		delay *= 2
+	}
+	if max > 0 && delay > max {
+		delay = max
+	}
+	r.Next = time.Now().Add(delay)
+	b.retries.Store(name, r)
+	logger.Err("[buffer.Flush]: notifier '%s' failed %d times, next attempt in %s", name, r.Attempts, delay.String())
+}
+
+// deliver - send events to notifier
+func (b *buffer) deliver(name string, events []Event) error {
+	n, ok := Get(name)
+	if !ok {
+		logger.Err("[buffer.Flush]: unknown notifier '%s', %d updates dropped", name, len(events))
+		flushes.Inc(metrics.Labels{"notifier": name, "result": "failure"})
+		return errUnknownNotifier
+	}
+	if err := n.Notify(events); err != nil {
+		logger.Err("[buffer.Flush]: notifier '%s': %s", name, err.Error())
+		flushes.Inc(metrics.Labels{"notifier": name, "result": "failure"})
+		return err
+	}
+	flushes.Inc(metrics.Labels{"notifier": name, "result": "success"})
+	return nil
+}
+
+// Pending - copy of all buffered events: notifier => events ordered by time
+func (b *buffer) Pending() map[string][]Event {
+	pending := make(map[string][]Event)
+	b.Notifiers.Range(func(k, v interface{}) bool {
+		events := make([]Event, 0)
+		v.(*sync.Map).Range(func(_, e interface{}) bool {
+			events = append(events, e.(Event))
+			return true
+		})
+		sort.SliceStable(events, func(a, b int) bool { return events[a].Time.Before(events[b].Time) })
+		queued.Set(metrics.Labels{"notifier": k.(string)}, float64(len(events)))
+		if len(events) > 0 {
+			pending[k.(string)] = events
+		}
+		return true
+	})
+	return pending
+}
+
+// saveSpool - store undelivered events to SpoolPath
+func (b *buffer) saveSpool() {
+	pending := b.Pending()
+	if b.SpoolPath == "" {
+		return
+	}
+
+	bytes, err := json.Marshal(pending)
+	if err != nil {
+		logger.Err("[buffer]: cannot marshal spool: %s", err.Error())
+		return
+	}
+	tmp := b.SpoolPath + ".tmp"
+	if err := ioutil.WriteFile(tmp, bytes, 0600); err != nil {
+		logger.Err("[buffer]: cannot write spool: %s", err.Error())
+		return
+	}
+	if err := os.Rename(tmp, b.SpoolPath); err != nil {
+		logger.Err("[buffer]: cannot write spool: %s", err.Error())
+	}
+}
+
+// loadSpool - load undelivered events stored before restart
+func (b *buffer) loadSpool() {
+	if b.SpoolPath == "" {
+		return
+	}
+	contents, err := ioutil.ReadFile(b.SpoolPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Err("[buffer]: cannot read spool: %s", err.Error())
+		}
+		return
+	}
+
+	pending := make(map[string][]Event)
+	if err := json.Unmarshal(contents, &pending); err != nil {
+		logger.Err("[buffer]: cannot parse spool: %s", err.Error())
+		return
+	}
+	loaded := 0
+	for name, events := range pending {
+		if _, ok := Get(name); !ok && isURL(name) {
+			// implicit update url webhook, options are set when topics are loaded
+			notifiers.LoadOrStore(name, NewWebhook(name, urlOfName(name)))
+		}
+		b.requeue(name, events)
+		loaded += len(events)
+	}
+	logger.Log("Loaded %d undelivered updates from '%s'", loaded, b.SpoolPath)
+}
+
+func isURL(name string) bool {
+	return strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://")
 }
@@ -0,0 +1,166 @@
+package notify
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testNotifier - notifier, which fails given number of deliveries and keeps delivered events
+type testNotifier struct {
+	name      string
+	failures  int
+	delivered []Event
+	mx        sync.Mutex
+}
+
+func (n *testNotifier) Name() string {
+	return n.name
+}
+
+func (n *testNotifier) Notify(events []Event) error {
+	n.mx.Lock()
+	defer n.mx.Unlock()
+	if n.failures > 0 {
+		n.failures--
+		return errors.New("failed")
+	}
+	n.delivered = append(n.delivered, events...)
+	return nil
+}
+
+func (n *testNotifier) count() int {
+	n.mx.Lock()
+	defer n.mx.Unlock()
+	return len(n.delivered)
+}
+
+func testEvent(host string, alive bool) Event {
+	return Event{Topic: "t", Host: host, Alive: alive, Time: time.Now()}
+}
+
+func TestStopRetriesNotifiersInBackoff(t *testing.T) {
+	n := &testNotifier{name: "test-stop", failures: 1}
+	Add(n)
+	b := &buffer{IntervalSec: 3600, RetryMaxSec: 3600}
+
+	b.BufferEvent(n.name, testEvent("10.10.10.1", true))
+	b.Flush()
+	if n.count() != 0 {
+		t.Fatal("failed delivery is counted")
+	}
+	// next attempt is in an hour: usual flush skips notifier, stop does not
+	b.Flush()
+	if n.count() != 0 {
+		t.Fatal("notifier in backoff is flushed")
+	}
+	b.Stop()
+	if n.count() != 1 {
+		t.Errorf("%d events are delivered on stop, expected 1", n.count())
+	}
+	if len(b.Pending()) != 0 {
+		t.Errorf("events are left in buffer: %v", b.Pending())
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	b := &buffer{IntervalSec: 10, RetryMaxSec: 60}
+	tests := []struct {
+		attempt int
+		delay   time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{3, 40 * time.Second},
+		{4, 60 * time.Second},
+		{5, 60 * time.Second},
+	}
+	for _, test := range tests {
+		started := time.Now()
+		b.backoff("test-backoff")
+		r, _ := b.retries.Load("test-backoff")
+		next := r.(*retry).Next
+		if r.(*retry).Attempts != test.attempt {
+			t.Errorf("attempt %d: attempts are counted as %d", test.attempt, r.(*retry).Attempts)
+		}
+		if next.Before(started.Add(test.delay)) || next.After(time.Now().Add(test.delay)) {
+			t.Errorf("attempt %d: next attempt in %s, expected %s", test.attempt, next.Sub(started), test.delay)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		newer    bool // newer event of the same host is buffered after failure
+		alive    bool // delivered state
+	}{
+		{"delivered", 0, false, true},
+		{"retried", 1, false, true},
+		{"newer state wins", 1, true, false},
+	}
+	for _, test := range tests {
+		n := &testNotifier{name: "test-retry-" + test.name, failures: test.failures}
+		Add(n)
+		// zero interval: no delay between attempts
+		b := &buffer{}
+
+		b.BufferEvent(n.name, testEvent("10.10.10.1", true))
+		b.Flush()
+		if test.newer {
+			b.BufferEvent(n.name, testEvent("10.10.10.1", false))
+		}
+		b.Flush()
+
+		if len(n.delivered) != 1 {
+			t.Errorf("%s: %d events are delivered, expected 1", test.name, len(n.delivered))
+			continue
+		}
+		if n.delivered[0].Alive != test.alive {
+			t.Errorf("%s: delivered state is %v, expected %v", test.name, n.delivered[0].Alive, test.alive)
+		}
+		if _, ok := b.retries.Load(n.name); ok {
+			t.Errorf("%s: retry state is kept after delivery", test.name)
+		}
+	}
+}
+
+func TestSpool(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spool.json")
+	n := &testNotifier{name: "test-spool"}
+	Add(n)
+	url := URLNotifierName("http://127.0.0.1:9/spool", WebhookOptions{Format: FormatV1})
+
+	b := &buffer{IntervalSec: 3600, SpoolPath: path}
+	b.BufferEvent(n.name, testEvent("10.10.10.1", true))
+	b.BufferEvent(n.name, testEvent("10.10.10.2", false))
+	// url webhook of previous run, it's not registered yet
+	b.events(url).Store("t|10.10.10.3", testEvent("10.10.10.3", true))
+	b.saveSpool()
+
+	loaded := &buffer{SpoolPath: path}
+	loaded.loadSpool()
+	pending := loaded.Pending()
+	tests := []struct {
+		notifier string
+		events   int
+	}{
+		{n.name, 2},
+		{url, 1},
+	}
+	for _, test := range tests {
+		if len(pending[test.notifier]) != test.events {
+			t.Errorf("%s: %d events are loaded, expected %d", test.notifier, len(pending[test.notifier]), test.events)
+		}
+	}
+	w, ok := Get(url)
+	if !ok {
+		t.Fatalf("url webhook '%s' is not created from spool", url)
+	}
+	if w.(*Webhook).URL != "http://127.0.0.1:9/spool" {
+		t.Errorf("url of webhook from spool is '%s'", w.(*Webhook).URL)
+	}
+}
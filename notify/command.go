@@ -0,0 +1,65 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+/*
+Command - run external command with json array of events on stdin.
+
+	[notifiers.script]
+	type = "command"
+	command = "/usr/local/bin/pinger-hook"
+	args = ["--verbose"]
+	timeout = 10
+*/
+type Command struct {
+	name    string
+	Command string
+	Args    []string
+	Timeout time.Duration
+}
+
+func init() {
+	Register("command", func(name string, params map[string]interface{}) (Notifier, error) {
+		c := &Command{
+			name:    name,
+			Command: ParamString(params, "command", ""),
+			Args:    ParamStrings(params, "args"),
+			Timeout: time.Duration(ParamInt(params, "timeout", 10)) * time.Second,
+		}
+		if c.Command == "" {
+			return nil, fmt.Errorf("missing 'command'")
+		}
+		return c, nil
+	})
+}
+
+// Name - notifier name
+func (c *Command) Name() string {
+	return c.name
+}
+
+// Notify - run command
+func (c *Command) Notify(events []Event) error {
+	input, err := json.Marshal(events)
+	if err != nil {
+		return fmt.Errorf("cannot marshal events: %s", err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, c.Command, c.Args...)
+	cmd.Stdin = bytes.NewReader(input)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("command '%s' failed: %s: %s", c.Command, err.Error(), string(output))
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package notify
+
+import (
+	"pinger/metrics"
+	"sync"
+	"time"
+)
+
+/*
+Event bus - host state changes and check results for API streams (/events, grpc WatchStateChanges).
+Unlike notifiers, events are not buffered per client: every event gets increasing ID, and last LogSize
+state changes are kept in memory, so reconnected client can resume from the last received ID.
+IDs start from boot epoch (start time in seconds << epochShift), so IDs of previous run are lower than
+any ID of current one and client resuming after restart gets gap instead of silently missed events.
+Check results are not kept, address changes are kept with state changes. Slow subscriber (channel is full) is disconnected, it never blocks hosts.
+*/
+
+// Stream event kinds
+const (
+	KindState   = "state"   // host state is changed
+	KindCheck   = "check"   // host is checked, state can be the same
+	KindResolve = "resolve" // addresses of host given by dns name are changed
+	KindGap     = "gap"     // events after requested ID are not in log anymore
+)
+
+// DefaultLogSize - state changes kept for resume
+const DefaultLogSize = 1000
+
+// epochShift - bits of event counter in ID; IDs stay below 2^53, so javascript clients get them exactly
+const epochShift = 20
+
+// StreamEvent - event with ID and kind; json is flat: {"id":1,"kind":"state","topic":...}
+type StreamEvent struct {
+	ID   uint64 `json:"id"`
+	Kind string `json:"kind"`
+	Event
+}
+
+type eventBus struct {
+	LogSize     int
+	subscribers map[*Subscription]bool
+	log         []StreamEvent
+	lastID      uint64
+	evicted     uint64 // ID of the last state change removed from log, boot epoch if nothing is removed
+	closed      bool
+	mx          sync.Mutex
+}
+
+// Subscription - channel of published events; filter is optional. Channel is closed on Unsubscribe or when it is full
+type Subscription struct {
+	C      chan StreamEvent
+	filter func(StreamEvent) bool
+}
+
+// Events - global event bus instance
+var Events = newEventBus(time.Now())
+
+func newEventBus(boot time.Time) *eventBus {
+	epoch := uint64(boot.Unix()) << epochShift
+	return &eventBus{LogSize: DefaultLogSize, subscribers: make(map[*Subscription]bool), lastID: epoch, evicted: epoch}
+}
+
+var (
+	published = metrics.NewCounter("pinger_events_published_total", "Events published to API streams by kind")
+	dropped   = metrics.NewCounter("pinger_events_slow_subscribers_total", "API stream subscribers disconnected because of full channel")
+	_         = metrics.NewGaugeFunc("pinger_events_subscribers", "Active API stream subscribers", func() float64 {
+		Events.mx.Lock()
+		defer Events.mx.Unlock()
+		return float64(len(Events.subscribers))
+	})
+)
+
+// SetLogSize - change number of kept state changes; log is trimmed on next state change
+func (b *eventBus) SetLogSize(size int) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	b.LogSize = size
+}
+
+/*
+Subscribe - new subscription with channel of given size; events, which don't match filter (if set), are skipped.
+With since > 0 logged state changes after this ID are returned for replay (they are not sent to channel);
+gap is true if some of them are not in log anymore or since is unknown (ID of previous run is below boot epoch)
+*/
+func (b *eventBus) Subscribe(size int, filter func(StreamEvent) bool, since uint64) (s *Subscription, replay []StreamEvent, gap bool) {
+	s = &Subscription{C: make(chan StreamEvent, size), filter: filter}
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	if b.closed {
+		close(s.C)
+		return s, nil, false
+	}
+	b.subscribers[s] = true
+
+	if since == 0 {
+		return s, nil, false
+	}
+	gap = since < b.evicted || since > b.lastID
+	for _, e := range b.log {
+		if e.ID > since && (filter == nil || filter(e)) {
+			replay = append(replay, e)
+		}
+	}
+	return s, replay, gap
+}
+
+// Unsubscribe - remove subscription and close it's channel
+func (b *eventBus) Unsubscribe(s *Subscription) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	b.unsubscribe(s)
+}
+
+func (b *eventBus) unsubscribe(s *Subscription) {
+	if b.subscribers[s] {
+		delete(b.subscribers, s)
+		close(s.C)
+	}
+}
+
+// Close - close all subscriptions on shutdown, so streams are finished; new subscriptions are closed at once
+func (b *eventBus) Close() {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	b.closed = true
+	for s := range b.subscribers {
+		b.unsubscribe(s)
+	}
+}
+
+// Closed - true after Close: subscription is closed by shutdown, not because client is slow
+func (b *eventBus) Closed() bool {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	return b.closed
+}
+
+// Publish - assign ID to event, keep state changes in log and send event to all subscribers
+func (b *eventBus) Publish(kind string, event Event) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	if kind == KindCheck && len(b.subscribers) == 0 {
+		return
+	}
+	b.lastID++
+	e := StreamEvent{ID: b.lastID, Kind: kind, Event: event}
+	published.Inc(metrics.Labels{"kind": kind})
+
+	if kind == KindState || kind == KindResolve {
+		b.log = append(b.log, e)
+		if over := len(b.log) - b.LogSize; over > 0 {
+			b.evicted = b.log[over-1].ID
+			b.log = b.log[over:]
+		}
+	}
+
+	for s := range b.subscribers {
+		if s.filter != nil && !s.filter(e) {
+			continue
+		}
+		select {
+		case s.C <- e:
+		default:
+			// client will reconnect and resume from log
+			dropped.Inc(nil)
+			b.unsubscribe(s)
+		}
+	}
+}
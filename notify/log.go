@@ -0,0 +1,34 @@
+package notify
+
+import (
+	"pinger/logger"
+)
+
+/*
+Log - write state changes into daemon log.
+
+	[notifiers.log]
+	type = "log"
+*/
+type Log struct {
+	name string
+}
+
+func init() {
+	Register("log", func(name string, params map[string]interface{}) (Notifier, error) {
+		return &Log{name: name}, nil
+	})
+}
+
+// Name - notifier name
+func (l *Log) Name() string {
+	return l.name
+}
+
+// Notify - log events
+func (l *Log) Notify(events []Event) error {
+	for _, e := range events {
+		logger.Log("[notify:%s]: %s: %s is %s (loss %.0f%%, rtt %.3f ms)", l.name, e.Topic, e.Host, e.State, 100-e.Result.SuccessPercent, e.Result.AvgRttMs)
+	}
+	return nil
+}
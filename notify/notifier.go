@@ -0,0 +1,243 @@
+package notify
+
+import (
+	"fmt"
+	"pinger/pinger"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Host states
+const (
+	StateUp       = "up"
+	StateDown     = "down"
+	StateDegraded = "degraded" // alive, but loss or rtt is above configured threshold
+)
+
+// StateOf - state name for alive/degraded flags
+func StateOf(alive bool, degraded bool) string {
+	if !alive {
+		return StateDown
+	}
+	if degraded {
+		return StateDegraded
+	}
+	return StateUp
+}
+
+/*
+Event - host state change in topic.
+Duration is how long host was in previous state (outage duration for `up` events), 0 if unknown.
+*/
+type Event struct {
+	Topic    string            `json:"topic"`
+	Host     string            `json:"host"`
+	State    string            `json:"state"`
+	Previous string            `json:"previous"`
+	Alive    bool              `json:"alive"`
+	Result   pinger.PingResult `json:"result"`
+	Time     time.Time         `json:"time"`
+	Duration time.Duration     `json:"duration"`
+	Labels   map[string]string `json:"labels,omitempty"`
+
+	// resolve events: new and previous addresses of host given by dns name
+	Addresses         []string `json:"addresses,omitempty"`
+	PreviousAddresses []string `json:"previousAddresses,omitempty"`
+}
+
+/*
+Notifier - delivery channel for state changes (webhook, command, log, ...).
+Notify is called from buffer every `updates-interval` with all events buffered for this notifier.
+*/
+type Notifier interface {
+	Name() string
+	Notify(events []Event) error
+}
+
+/*
+Immediate - optional interface: if IsImmediate() returns true, events are not buffered,
+but delivered to notifier right away
+*/
+type Immediate interface {
+	IsImmediate() bool
+}
+
+/*
+Factory - creates notifier instance with given name from config parameters ([notifiers.<name>] table)
+*/
+type Factory func(name string, params map[string]interface{}) (Notifier, error)
+
+var (
+	factories   = make(map[string]Factory)
+	factoriesMx sync.Mutex
+	notifiers   sync.Map                // name => Notifier
+	configured  = make(map[string]bool) // names of notifiers created by Configure
+	configureMx sync.Mutex
+)
+
+/*
+Register - register notifier type. Should be called from init() of notifier implementation
+*/
+func Register(kind string, factory Factory) {
+	factoriesMx.Lock()
+	defer factoriesMx.Unlock()
+	factories[kind] = factory
+}
+
+// Kinds - list of registered notifier types
+func Kinds() []string {
+	factoriesMx.Lock()
+	defer factoriesMx.Unlock()
+	kinds := make([]string, 0, len(factories))
+	for k := range factories {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+/*
+Configure - create notifier instances from config: name => parameters. Each parameters map must have `type`.
+Replaces all previously configured notifiers; on error they are kept.
+Notifiers added otherwise (update url webhooks, see URLNotifier) are not touched
+*/
+func Configure(configs map[string]map[string]interface{}) error {
+	created := make([]Notifier, 0, len(configs))
+	for name, params := range configs {
+		n, err := create(name, params)
+		if err != nil {
+			return err
+		}
+		created = append(created, n)
+	}
+
+	configureMx.Lock()
+	defer configureMx.Unlock()
+	for name := range configured {
+		if _, ok := configs[name]; !ok {
+			notifiers.Delete(name)
+			delete(configured, name)
+		}
+	}
+	for _, n := range created {
+		Add(n)
+		configured[n.Name()] = true
+	}
+	return nil
+}
+
+// Check - validate parameters of notifier without creating it (config check)
+func Check(name string, params map[string]interface{}) error {
+	_, err := create(name, params)
+	return err
+}
+
+// create - notifier instance of params type; it's not added to configured notifiers
+func create(name string, params map[string]interface{}) (Notifier, error) {
+	kind, _ := params["type"].(string)
+	factoriesMx.Lock()
+	factory, ok := factories[kind]
+	factoriesMx.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("notifier '%s': unknown type '%s', known types: %v", name, kind, Kinds())
+	}
+
+	n, err := factory(name, params)
+	if err != nil {
+		return nil, fmt.Errorf("notifier '%s': %s", name, err.Error())
+	}
+	return n, nil
+}
+
+// Add - add (or replace) notifier instance
+func Add(n Notifier) {
+	notifiers.Store(n.Name(), n)
+}
+
+// Get - find notifier instance by name
+func Get(name string) (Notifier, bool) {
+	n, ok := notifiers.Load(name)
+	if !ok {
+		return nil, false
+	}
+	return n.(Notifier), true
+}
+
+// Names - names of all notifier instances
+func Names() []string {
+	names := make([]string, 0)
+	notifiers.Range(func(k, _ interface{}) bool {
+		names = append(names, k.(string))
+		return true
+	})
+	sort.Strings(names)
+	return names
+}
+
+/*
+Config parameter helpers for notifier factories
+*/
+
+// ParamString - string parameter or default
+func ParamString(params map[string]interface{}, name string, def string) string {
+	if v, ok := params[name].(string); ok {
+		return v
+	}
+	return def
+}
+
+// ParamInt - integer parameter or default
+func ParamInt(params map[string]interface{}, name string, def int64) int64 {
+	switch v := params[name].(type) {
+	case int:
+		return int64(v)
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	}
+	return def
+}
+
+// ParamBool - boolean parameter or default
+func ParamBool(params map[string]interface{}, name string, def bool) bool {
+	if v, ok := params[name].(bool); ok {
+		return v
+	}
+	return def
+}
+
+// ParamStrings - string slice parameter
+func ParamStrings(params map[string]interface{}, name string) []string {
+	result := make([]string, 0)
+	switch v := params[name].(type) {
+	case []string:
+		result = append(result, v...)
+	case []interface{}:
+		for _, s := range v {
+			if str, ok := s.(string); ok {
+				result = append(result, str)
+			}
+		}
+	case string:
+		result = append(result, v)
+	}
+	return result
+}
+
+// ParamStringMap - table of string values (e.g. http headers)
+func ParamStringMap(params map[string]interface{}, name string) map[string]string {
+	result := make(map[string]string)
+	switch v := params[name].(type) {
+	case map[string]string:
+		for key, value := range v {
+			result[key] = value
+		}
+	case map[string]interface{}:
+		for key, value := range v {
+			result[key] = fmt.Sprintf("%v", value)
+		}
+	}
+	return result
+}
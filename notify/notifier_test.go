@@ -0,0 +1,48 @@
+package notify
+
+import (
+	"testing"
+)
+
+func TestConfigureKeepsURLNotifiers(t *testing.T) {
+	url := URLNotifier("http://127.0.0.1/updates", WebhookOptions{Secret: "s"}).Name()
+
+	steps := []struct {
+		name    string
+		configs map[string]map[string]interface{}
+		present []string
+		absent  []string
+	}{
+		{"add", map[string]map[string]interface{}{"a": {"type": "log"}, "b": {"type": "log"}}, []string{"a", "b", url}, nil},
+		{"remove one", map[string]map[string]interface{}{"a": {"type": "log"}}, []string{"a", url}, []string{"b"}},
+		{"remove all", map[string]map[string]interface{}{}, []string{url}, []string{"a", "b"}},
+	}
+	for _, step := range steps {
+		if err := Configure(step.configs); err != nil {
+			t.Fatalf("%s: %s", step.name, err.Error())
+		}
+		for _, name := range step.present {
+			if _, ok := Get(name); !ok {
+				t.Errorf("%s: notifier '%s' is missing, notifiers: %v", step.name, name, Names())
+			}
+		}
+		for _, name := range step.absent {
+			if _, ok := Get(name); ok {
+				t.Errorf("%s: notifier '%s' is not removed", step.name, name)
+			}
+		}
+	}
+}
+
+func TestConfigureKeepsNotifiersOnError(t *testing.T) {
+	if err := Configure(map[string]map[string]interface{}{"kept": {"type": "log"}}); err != nil {
+		t.Fatal(err)
+	}
+	err := Configure(map[string]map[string]interface{}{"wrong": {"type": "nonexistent"}})
+	if err == nil {
+		t.Fatal("unknown type is accepted")
+	}
+	if _, ok := Get("kept"); !ok {
+		t.Error("configured notifier is removed on error")
+	}
+}
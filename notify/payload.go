@@ -0,0 +1,116 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Update payload formats
+const (
+	FormatLegacy = "legacy" // {"10.10.10.1":true}
+	FormatV1     = "v1"     // PayloadV1
+)
+
+// Formats - known update payload formats
+var Formats = []string{FormatLegacy, FormatV1}
+
+// IsFormat - check payload format name; empty means legacy
+func IsFormat(format string) bool {
+	if format == "" {
+		return true
+	}
+	for _, f := range Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+PayloadV1 - versioned update payload
+
+	{
+	  "version": 1,
+	  "sent": "2026-10-19T10:00:30Z",
+	  "events": [{
+	    "topic": "switches", "host": "10.10.10.1",
+	    "oldState": "up", "newState": "down", "alive": false,
+	    "changed": "2026-10-19T10:00:12Z",
+	    "previousStateDuration": 86400, "outageDuration": 0,
+	    "stats": {"successPercent": 0, "lossPercent": 100, "avgRttMs": 0, "avgRttNs": 0},
+	    "labels": {"site": "dc1"}
+	  }]
+	}
+*/
+type PayloadV1 struct {
+	Version int              `json:"version"`
+	Sent    time.Time        `json:"sent"`
+	Events  []PayloadEventV1 `json:"events"`
+}
+
+// PayloadEventV1 - single state change in PayloadV1. Durations are in seconds
+type PayloadEventV1 struct {
+	Topic                 string         `json:"topic"`
+	Host                  string         `json:"host"`
+	OldState              string         `json:"oldState"`
+	NewState              string         `json:"newState"`
+	Alive                 bool           `json:"alive"`
+	Changed               time.Time      `json:"changed"`
+	PreviousStateDuration float64        `json:"previousStateDuration"`
+	OutageDuration        float64        `json:"outageDuration"`
+	Stats                 PayloadStatsV1 `json:"stats"`
+	Labels                map[string]string `json:"labels,omitempty"`
+}
+
+// PayloadStatsV1 - probe statistics of the check which caused state change
+type PayloadStatsV1 struct {
+	SuccessPercent float64 `json:"successPercent"`
+	LossPercent    float64 `json:"lossPercent"`
+	AvgRttMs       float64 `json:"avgRttMs"`
+	AvgRttNs       int64   `json:"avgRttNs"`
+}
+
+// NewPayloadV1 - build v1 payload from events
+func NewPayloadV1(events []Event) PayloadV1 {
+	payload := PayloadV1{Version: 1, Sent: time.Now().UTC(), Events: make([]PayloadEventV1, 0, len(events))}
+	for _, e := range events {
+		pe := PayloadEventV1{
+			Topic:                 e.Topic,
+			Host:                  e.Host,
+			OldState:              e.Previous,
+			NewState:              e.State,
+			Alive:                 e.Alive,
+			Changed:               e.Time.UTC(),
+			PreviousStateDuration: e.Duration.Seconds(),
+			Stats: PayloadStatsV1{
+				SuccessPercent: e.Result.SuccessPercent,
+				LossPercent:    100 - e.Result.SuccessPercent,
+				AvgRttMs:       e.Result.AvgRttMs,
+				AvgRttNs:       e.Result.AvgRttNs,
+			},
+			Labels: e.Labels,
+		}
+		if e.Previous == StateDown && e.Alive {
+			pe.OutageDuration = pe.PreviousStateDuration
+		}
+		payload.Events = append(payload.Events, pe)
+	}
+	return payload
+}
+
+// MarshalPayload - encode events in given format
+func MarshalPayload(format string, events []Event) ([]byte, error) {
+	switch format {
+	case "", FormatLegacy:
+		values := make(map[string]bool)
+		for _, e := range events {
+			values[e.Host] = e.Alive
+		}
+		return json.Marshal(values)
+	case FormatV1:
+		return json.Marshal(NewPayloadV1(events))
+	}
+	return nil, fmt.Errorf("unknown payload format '%s'", format)
+}
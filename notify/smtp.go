@@ -0,0 +1,368 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	htmltemplate "html/template"
+	"io/ioutil"
+	"mime"
+	"net"
+	"net/smtp"
+	"pinger/logger"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+/*
+SMTP - e-mail notifier. In `digest` mode (default) one message per recipient list is sent every
+`updates-interval` with all changed hosts; in `immediate` mode every state change is mailed right away.
+
+	[notifiers.mail]
+	type = "smtp"
+	server = "mail.local:587"
+	username = "pinger"
+	password = "secret"
+	starttls = true          # or tls = true for implicit tls (port 465)
+	from = "pinger@local"
+	to = ["noc@local"]
+	mode = "digest"          # or "immediate"
+	subject = "..."          # text/template, optional
+	text-template = "..."    # text/template, optional (or text-template-file)
+	html-template = "..."    # html/template, optional (or html-template-file)
+	[notifiers.mail.topic-to]
+	cameras = ["video@local"]
+*/
+type SMTP struct {
+	name      string
+	Server    string
+	Username  string
+	Password  string
+	StartTLS  bool
+	TLS       bool
+	From      string
+	To        []string
+	TopicTo   map[string][]string
+	Immediate bool
+	Timeout   time.Duration
+
+	subject *template.Template
+	text    *template.Template
+	html    *htmltemplate.Template
+}
+
+// MailData - data passed to mail templates
+type MailData struct {
+	Notifier string
+	Time     time.Time
+	Topics   []string
+	Events   []Event
+	Up       int
+	Down     int
+}
+
+// DefaultMailSubject - default subject template
+const DefaultMailSubject = `[pinger] {{if eq (len .Events) 1}}{{with index .Events 0}}{{.Host}} ({{.Topic}}) is {{upper .State}}{{end}}{{else}}{{.Down}} down, {{.Up}} up{{end}}`
+
+// DefaultMailText - default plain text template
+const DefaultMailText = `Host state changes ({{.Time.Format "2006-01-02 15:04:05 MST"}}):
+{{range .Events}}
+{{.Time.Format "15:04:05"}}  {{printf "%-8s" .Topic}} {{printf "%-16s" .Host}} {{upper .State}}{{if .Alive}} rtt {{printf "%.2f" .Result.AvgRttMs}} ms{{end}}{{if .Duration}}{{if eq .Previous "down"}}, outage {{else}}, was {{.Previous}} {{end}}{{duration .Duration}}{{end}}{{end}}
+`
+
+// DefaultMailHTML - default html template
+const DefaultMailHTML = `<html><body>
+<p>Host state changes ({{.Time.Format "2006-01-02 15:04:05 MST"}}):</p>
+<table border="1" cellpadding="4" cellspacing="0">
+<tr><th>Time</th><th>Topic</th><th>Host</th><th>State</th><th>RTT, ms</th><th>Duration</th></tr>
+{{range .Events}}<tr>
+<td>{{.Time.Format "15:04:05"}}</td><td>{{.Topic}}</td><td>{{.Host}}</td>
+<td style="color:{{if eq .State "up"}}green{{else if eq .State "degraded"}}orange{{else}}red{{end}}">{{upper .State}}</td>
+<td>{{if .Alive}}{{printf "%.2f" .Result.AvgRttMs}}{{end}}</td>
+<td>{{if .Duration}}{{if eq .Previous "down"}}outage {{else}}was {{.Previous}} {{end}}{{duration .Duration}}{{end}}</td>
+</tr>
+{{end}}</table>
+</body></html>
+`
+
+var mailFuncs = map[string]interface{}{
+	"duration": formatDuration,
+	"upper":    strings.ToUpper,
+}
+
+func init() {
+	Register("smtp", newSMTP)
+}
+
+func newSMTP(name string, params map[string]interface{}) (Notifier, error) {
+	s := &SMTP{
+		name:     name,
+		Server:   ParamString(params, "server", ""),
+		Username: ParamString(params, "username", ""),
+		Password: ParamString(params, "password", ""),
+		StartTLS: ParamBool(params, "starttls", false),
+		TLS:      ParamBool(params, "tls", false),
+		From:     ParamString(params, "from", ""),
+		To:       ParamStrings(params, "to"),
+		TopicTo:  make(map[string][]string),
+		Timeout:  time.Duration(ParamInt(params, "timeout", 30)) * time.Second,
+	}
+	if s.Server == "" {
+		return nil, fmt.Errorf("missing 'server'")
+	}
+	if _, _, err := net.SplitHostPort(s.Server); err != nil {
+		return nil, fmt.Errorf("wrong 'server', should be host:port: %s", err.Error())
+	}
+	if s.From == "" {
+		return nil, fmt.Errorf("missing 'from'")
+	}
+	if topics, ok := params["topic-to"].(map[string]interface{}); ok {
+		for topic := range topics {
+			s.TopicTo[topic] = ParamStrings(topics, topic)
+		}
+	}
+	if len(s.To) == 0 && len(s.TopicTo) == 0 {
+		return nil, fmt.Errorf("missing 'to' or 'topic-to'")
+	}
+
+	switch mode := ParamString(params, "mode", "digest"); mode {
+	case "digest":
+	case "immediate":
+		s.Immediate = true
+	default:
+		return nil, fmt.Errorf("unknown mode '%s', should be 'digest' or 'immediate'", mode)
+	}
+
+	var err error
+	if s.subject, err = template.New("subject").Funcs(mailFuncs).Parse(ParamString(params, "subject", DefaultMailSubject)); err != nil {
+		return nil, fmt.Errorf("subject: %s", err.Error())
+	}
+	text, err := templateSource(params, "text-template", DefaultMailText)
+	if err != nil {
+		return nil, err
+	}
+	if s.text, err = template.New("text").Funcs(mailFuncs).Parse(text); err != nil {
+		return nil, fmt.Errorf("text-template: %s", err.Error())
+	}
+	html, err := templateSource(params, "html-template", DefaultMailHTML)
+	if err != nil {
+		return nil, err
+	}
+	if html != "" {
+		if s.html, err = htmltemplate.New("html").Funcs(mailFuncs).Parse(html); err != nil {
+			return nil, fmt.Errorf("html-template: %s", err.Error())
+		}
+	}
+
+	return s, nil
+}
+
+// templateSource - template from `<name>` parameter, `<name>-file` file or default
+func templateSource(params map[string]interface{}, name string, def string) (string, error) {
+	if file := ParamString(params, name+"-file", ""); file != "" {
+		contents, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("%s-file: %s", name, err.Error())
+		}
+		return string(contents), nil
+	}
+	return ParamString(params, name, def), nil
+}
+
+// Name - notifier name
+func (s *SMTP) Name() string {
+	return s.name
+}
+
+// IsImmediate - in immediate mode events are not buffered
+func (s *SMTP) IsImmediate() bool {
+	return s.Immediate
+}
+
+// Recipients - recipients for topic
+func (s *SMTP) Recipients(topic string) []string {
+	if to, ok := s.TopicTo[topic]; ok {
+		return to
+	}
+	return s.To
+}
+
+// Notify - send one message per recipient list (digest), or one per event (immediate)
+func (s *SMTP) Notify(events []Event) error {
+	sort.SliceStable(events, func(a, b int) bool { return events[a].Time.Before(events[b].Time) })
+
+	groups := make(map[string][]Event)
+	keys := make([]string, 0)
+	for _, e := range events {
+		key := strings.Join(s.Recipients(e.Topic), ",")
+		if key == "" {
+			continue
+		}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], e)
+	}
+
+	var lastErr error
+	failed := 0
+	for _, key := range keys {
+		batches := [][]Event{groups[key]}
+		if s.Immediate {
+			batches = make([][]Event, 0)
+			for _, e := range groups[key] {
+				batches = append(batches, []Event{e})
+			}
+		}
+		for _, batch := range batches {
+			if err := s.send(strings.Split(key, ","), batch); err != nil {
+				failed++
+				lastErr = fmt.Errorf("mail to %s: %s", key, err.Error())
+				logger.Err("[smtp:%s]: %s", s.name, lastErr.Error())
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d messages failed, last error: %s", failed, lastErr.Error())
+	}
+	return nil
+}
+
+// Render - render subject, text and html parts
+func (s *SMTP) Render(events []Event) (string, string, string, error) {
+	data := MailData{Notifier: s.name, Time: time.Now(), Events: events}
+	topics := make(map[string]bool)
+	for _, e := range events {
+		if e.Alive {
+			data.Up++
+		} else {
+			data.Down++
+		}
+		if !topics[e.Topic] {
+			topics[e.Topic] = true
+			data.Topics = append(data.Topics, e.Topic)
+		}
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := s.subject.Execute(&subject, data); err != nil {
+		return "", "", "", fmt.Errorf("subject: %s", err.Error())
+	}
+	if err := s.text.Execute(&text, data); err != nil {
+		return "", "", "", fmt.Errorf("text template: %s", err.Error())
+	}
+	if s.html != nil {
+		if err := s.html.Execute(&html, data); err != nil {
+			return "", "", "", fmt.Errorf("html template: %s", err.Error())
+		}
+	}
+	return strings.TrimSpace(subject.String()), text.String(), html.String(), nil
+}
+
+// Message - build MIME message
+func (s *SMTP) Message(to []string, events []Event) ([]byte, error) {
+	subject, text, html, err := s.Render(events)
+	if err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", s.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+
+	if html == "" {
+		fmt.Fprintf(&msg, "Content-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: base64\r\n\r\n")
+		writeBase64(&msg, text)
+		return msg.Bytes(), nil
+	}
+
+	boundary := randomBoundary()
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", boundary)
+	fmt.Fprintf(&msg, "--%s\r\nContent-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: base64\r\n\r\n", boundary)
+	writeBase64(&msg, text)
+	fmt.Fprintf(&msg, "--%s\r\nContent-Type: text/html; charset=utf-8\r\nContent-Transfer-Encoding: base64\r\n\r\n", boundary)
+	writeBase64(&msg, html)
+	fmt.Fprintf(&msg, "--%s--\r\n", boundary)
+	return msg.Bytes(), nil
+}
+
+func (s *SMTP) send(to []string, events []Event) error {
+	msg, err := s.Message(to, events)
+	if err != nil {
+		return err
+	}
+
+	host, _, _ := net.SplitHostPort(s.Server)
+	tlsConfig := &tls.Config{ServerName: host}
+
+	var conn net.Conn
+	dialer := &net.Dialer{Timeout: s.Timeout}
+	if s.TLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", s.Server, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", s.Server)
+	}
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(s.Timeout))
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if s.StartTLS && !s.TLS {
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("starttls: %s", err.Error())
+		}
+	}
+	if s.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.Username, s.Password, host)); err != nil {
+			return fmt.Errorf("auth: %s", err.Error())
+		}
+	}
+	if err := c.Mail(s.From); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func writeBase64(buf *bytes.Buffer, s string) {
+	encoded := base64.StdEncoding.EncodeToString([]byte(s))
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded + "\r\n")
+}
+
+func randomBoundary() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return fmt.Sprintf("pinger-%x", b)
+}
@@ -0,0 +1,193 @@
+package notify
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+/*
+SNMP - send state changes as SNMPv2c or SNMPv3 traps.
+
+	[notifiers.nms-trap]
+	type = "snmp"
+	address = "nms.local:162"
+	version = "2c"                     # or "3"
+	community = "public"               # v2c
+	base-oid = "1.3.6.1.4.1.32473.1"   # see OID layout below
+	# v3:
+	username = "pinger"
+	auth-protocol = "SHA"              # MD5, SHA, SHA256; empty - noAuth
+	auth-passphrase = "authsecret"
+	priv-protocol = "AES"              # DES, AES, AES256; empty - noPriv
+	priv-passphrase = "privsecret"
+	engine-id = "80001f888056562d"     # hex, authoritative engine id of this sender
+
+OID layout (relative to base-oid):
+
+	<base>.0.1  pingerHostDown      notification
+	<base>.0.2  pingerHostUp        notification
+	<base>.0.3  pingerHostDegraded  notification
+	<base>.1.1  pingerTopic         OCTET STRING   topic name
+	<base>.1.2  pingerHost          OCTET STRING   host address
+	<base>.1.3  pingerState         INTEGER        1 - up, 2 - down, 3 - degraded
+	<base>.1.4  pingerPrevState     INTEGER        previous state, same values
+	<base>.1.5  pingerLossPercent   Gauge32        packet loss of the check, %
+	<base>.1.6  pingerRttUsec       Gauge32        average rtt of the check, microseconds
+	<base>.1.7  pingerDuration      Gauge32        seconds spent in previous state, 0 - unknown
+
+Every trap carries sysUpTime.0, snmpTrapOID.0 (one of notifications) and all <base>.1.x objects.
+*/
+type SNMP struct {
+	name      string
+	Address   string
+	BaseOID   string
+	client    *gosnmp.GoSNMP
+	startedAt time.Time
+}
+
+// SNMP state values
+var snmpStates = map[string]int{StateUp: 1, StateDown: 2, StateDegraded: 3}
+
+// SNMP notification numbers under <base>.0
+var snmpNotifications = map[string]int{StateDown: 1, StateUp: 2, StateDegraded: 3}
+
+var snmpAuthProtocols = map[string]gosnmp.SnmpV3AuthProtocol{
+	"": gosnmp.NoAuth, "MD5": gosnmp.MD5, "SHA": gosnmp.SHA, "SHA256": gosnmp.SHA256,
+}
+
+var snmpPrivProtocols = map[string]gosnmp.SnmpV3PrivProtocol{
+	"": gosnmp.NoPriv, "DES": gosnmp.DES, "AES": gosnmp.AES, "AES256": gosnmp.AES256,
+}
+
+func init() {
+	Register("snmp", newSNMP)
+}
+
+func newSNMP(name string, params map[string]interface{}) (Notifier, error) {
+	s := &SNMP{
+		name:      name,
+		Address:   ParamString(params, "address", ""),
+		BaseOID:   strings.Trim(ParamString(params, "base-oid", "1.3.6.1.4.1.32473.1"), "."),
+		startedAt: time.Now(),
+	}
+	if s.Address == "" {
+		return nil, fmt.Errorf("missing 'address'")
+	}
+	host, portStr, err := net.SplitHostPort(s.Address)
+	if err != nil {
+		return nil, fmt.Errorf("wrong 'address': %s", err.Error())
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("wrong port in 'address': %s", err.Error())
+	}
+
+	s.client = &gosnmp.GoSNMP{
+		Target:    host,
+		Port:      uint16(port),
+		Transport: "udp",
+		Timeout:   time.Duration(ParamInt(params, "timeout", 5)) * time.Second,
+		Retries:   int(ParamInt(params, "retries", 1)),
+		MaxOids:   gosnmp.MaxOids,
+	}
+
+	switch version := ParamString(params, "version", "2c"); version {
+	case "2c":
+		s.client.Version = gosnmp.Version2c
+		s.client.Community = ParamString(params, "community", "public")
+	case "3":
+		auth, ok := snmpAuthProtocols[strings.ToUpper(ParamString(params, "auth-protocol", ""))]
+		if !ok {
+			return nil, fmt.Errorf("unknown auth-protocol '%s'", ParamString(params, "auth-protocol", ""))
+		}
+		priv, ok := snmpPrivProtocols[strings.ToUpper(ParamString(params, "priv-protocol", ""))]
+		if !ok {
+			return nil, fmt.Errorf("unknown priv-protocol '%s'", ParamString(params, "priv-protocol", ""))
+		}
+		flags := gosnmp.NoAuthNoPriv
+		if auth != gosnmp.NoAuth {
+			flags = gosnmp.AuthNoPriv
+			if priv != gosnmp.NoPriv {
+				flags = gosnmp.AuthPriv
+			}
+		} else if priv != gosnmp.NoPriv {
+			return nil, fmt.Errorf("priv-protocol requires auth-protocol")
+		}
+
+		engineID, err := hex.DecodeString(ParamString(params, "engine-id", ""))
+		if err != nil || len(engineID) < 5 {
+			return nil, fmt.Errorf("wrong 'engine-id', should be hex string of 5..32 bytes")
+		}
+		username := ParamString(params, "username", "")
+		if username == "" {
+			return nil, fmt.Errorf("missing 'username'")
+		}
+
+		s.client.Version = gosnmp.Version3
+		s.client.SecurityModel = gosnmp.UserSecurityModel
+		s.client.MsgFlags = flags
+		s.client.SecurityParameters = &gosnmp.UsmSecurityParameters{
+			UserName:                 username,
+			AuthenticationProtocol:   auth,
+			AuthenticationPassphrase: ParamString(params, "auth-passphrase", ""),
+			PrivacyProtocol:          priv,
+			PrivacyPassphrase:        ParamString(params, "priv-passphrase", ""),
+			AuthoritativeEngineID:    string(engineID),
+			AuthoritativeEngineBoots: 1,
+		}
+	default:
+		return nil, fmt.Errorf("unknown version '%s', should be '2c' or '3'", version)
+	}
+
+	return s, nil
+}
+
+// Name - notifier name
+func (s *SNMP) Name() string {
+	return s.name
+}
+
+// Notify - send trap for every event
+func (s *SNMP) Notify(events []Event) error {
+	if err := s.client.Connect(); err != nil {
+		return fmt.Errorf("connect: %s", err.Error())
+	}
+	defer s.client.Conn.Close()
+
+	if usm, ok := s.client.SecurityParameters.(*gosnmp.UsmSecurityParameters); ok {
+		usm.AuthoritativeEngineTime = uint32(time.Since(s.startedAt).Seconds())
+	}
+
+	for _, e := range events {
+		if _, err := s.client.SendTrap(gosnmp.SnmpTrap{Variables: s.Varbinds(e)}); err != nil {
+			return fmt.Errorf("trap for %s: %s", e.Host, err.Error())
+		}
+	}
+	return nil
+}
+
+/*
+Varbinds - trap variables for event, see OID layout in SNMP doc
+*/
+func (s *SNMP) Varbinds(e Event) []gosnmp.SnmpPDU {
+	object := func(n int) string {
+		return fmt.Sprintf(".%s.1.%d", s.BaseOID, n)
+	}
+	return []gosnmp.SnmpPDU{
+		{Name: ".1.3.6.1.2.1.1.3.0", Type: gosnmp.TimeTicks, Value: uint32(time.Since(s.startedAt) / (10 * time.Millisecond))},
+		{Name: ".1.3.6.1.6.3.1.1.4.1.0", Type: gosnmp.ObjectIdentifier, Value: fmt.Sprintf(".%s.0.%d", s.BaseOID, snmpNotifications[e.State])},
+		{Name: object(1), Type: gosnmp.OctetString, Value: e.Topic},
+		{Name: object(2), Type: gosnmp.OctetString, Value: e.Host},
+		{Name: object(3), Type: gosnmp.Integer, Value: snmpStates[e.State]},
+		{Name: object(4), Type: gosnmp.Integer, Value: snmpStates[e.Previous]},
+		{Name: object(5), Type: gosnmp.Gauge32, Value: uint(100 - e.Result.SuccessPercent)},
+		{Name: object(6), Type: gosnmp.Gauge32, Value: uint(e.Result.AvgRttNs / 1000)},
+		{Name: object(7), Type: gosnmp.Gauge32, Value: uint(e.Duration.Seconds())},
+	}
+}
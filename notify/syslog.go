@@ -0,0 +1,182 @@
+package notify
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+/*
+Syslog - send state changes as RFC 5424 messages over udp, tcp or tls (RFC 5425 octet-counting framing).
+
+	[notifiers.nms-syslog]
+	type = "syslog"
+	network = "udp"              # udp, tcp or tls
+	address = "nms.local:514"
+	facility = "daemon"
+	app-name = "pinger"
+	enterprise-id = 32473        # private enterprise number for structured data id
+	ca-file = "/etc/pinger/ca.pem" # tls only; system roots if empty
+
+Message example:
+
+	<27>1 2026-10-19T10:00:00Z pinger-host pinger 1234 HOSTDOWN [state@32473 topic="switches" host="10.10.10.1" state="down" previous="up" loss="100" rttMs="0.000" duration="3600"] 10.10.10.1 (switches) is down
+*/
+type Syslog struct {
+	name         string
+	Network      string
+	Address      string
+	Facility     int
+	AppName      string
+	Hostname     string
+	EnterpriseID int64
+	Timeout      time.Duration
+	tlsConfig    *tls.Config
+}
+
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19, "local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// Syslog severities for states
+const (
+	SeverityErr     = 3
+	SeverityWarning = 4
+	SeverityNotice  = 5
+)
+
+func init() {
+	Register("syslog", func(name string, params map[string]interface{}) (Notifier, error) {
+		s := &Syslog{
+			name:         name,
+			Network:      ParamString(params, "network", "udp"),
+			Address:      ParamString(params, "address", ""),
+			AppName:      ParamString(params, "app-name", "pinger"),
+			Hostname:     ParamString(params, "hostname", ""),
+			EnterpriseID: ParamInt(params, "enterprise-id", 32473),
+			Timeout:      time.Duration(ParamInt(params, "timeout", 5)) * time.Second,
+		}
+		if s.Address == "" {
+			return nil, fmt.Errorf("missing 'address'")
+		}
+		facility, ok := syslogFacilities[ParamString(params, "facility", "daemon")]
+		if !ok {
+			return nil, fmt.Errorf("unknown facility '%s'", ParamString(params, "facility", ""))
+		}
+		s.Facility = facility
+		if s.Hostname == "" {
+			s.Hostname, _ = os.Hostname()
+		}
+
+		switch s.Network {
+		case "udp", "tcp":
+		case "tls":
+			host, _, err := net.SplitHostPort(s.Address)
+			if err != nil {
+				return nil, fmt.Errorf("wrong 'address': %s", err.Error())
+			}
+			s.tlsConfig = &tls.Config{ServerName: host}
+			if caFile := ParamString(params, "ca-file", ""); caFile != "" {
+				pem, err := ioutil.ReadFile(caFile)
+				if err != nil {
+					return nil, fmt.Errorf("ca-file: %s", err.Error())
+				}
+				s.tlsConfig.RootCAs = x509.NewCertPool()
+				if !s.tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+					return nil, fmt.Errorf("ca-file: no certificates found")
+				}
+			}
+		default:
+			return nil, fmt.Errorf("unknown network '%s', should be udp, tcp or tls", s.Network)
+		}
+		return s, nil
+	})
+}
+
+// Name - notifier name
+func (s *Syslog) Name() string {
+	return s.name
+}
+
+// Notify - send one syslog message per event over single connection
+func (s *Syslog) Notify(events []Event) error {
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: s.Timeout}
+	if s.Network == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", s.Address, s.tlsConfig)
+	} else {
+		conn, err = dialer.Dial(s.Network, s.Address)
+	}
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(s.Timeout))
+
+	for _, e := range events {
+		msg := s.Format(e)
+		if s.Network != "udp" {
+			// octet counting framing, RFC 6587 / RFC 5425
+			msg = fmt.Sprintf("%d %s", len(msg), msg)
+		}
+		if _, err := conn.Write([]byte(msg)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+Format - RFC 5424 message for event
+*/
+func (s *Syslog) Format(e Event) string {
+	severity := SeverityNotice
+	switch e.State {
+	case StateDown:
+		severity = SeverityErr
+	case StateDegraded:
+		severity = SeverityWarning
+	}
+
+	sd := fmt.Sprintf(`[state@%d topic="%s" host="%s" state="%s" previous="%s" loss="%.0f" rttMs="%.3f" duration="%.0f"]`,
+		s.EnterpriseID, sdEscape(e.Topic), sdEscape(e.Host), e.State, e.Previous,
+		100-e.Result.SuccessPercent, e.Result.AvgRttMs, e.Duration.Seconds())
+
+	return fmt.Sprintf("<%d>1 %s %s %s %d %s %s %s (%s) is %s",
+		s.Facility*8+severity,
+		e.Time.UTC().Format(time.RFC3339Nano),
+		headerField(s.Hostname),
+		headerField(s.AppName),
+		os.Getpid(),
+		"HOST"+strings.ToUpper(e.State),
+		sd,
+		e.Host, e.Topic, e.State)
+}
+
+var sdReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+func sdEscape(s string) string {
+	return sdReplacer.Replace(s)
+}
+
+// headerField - header fields are printable ascii without spaces, `-` if empty
+func headerField(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return -1
+		}
+		return r
+	}, s)
+	if s == "" {
+		return "-"
+	}
+	return s
+}
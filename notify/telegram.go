@@ -0,0 +1,240 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"pinger/httpclient"
+	"pinger/logger"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*
+Telegram - send state changes via telegram bot. All events for one chat are batched into as few
+messages as possible, messages to the same chat are sent not faster than once per second.
+
+	[notifiers.tg]
+	type = "telegram"
+	token = "123456:ABC-DEF"
+	chat-ids = [-1001234567890]          # default chats
+	api-url = "https://api.telegram.org" # can be pointed to local stub
+	[notifiers.tg.topic-chats]
+	cameras = [-1009876543210, "@cams"]  # per-topic chats, override chat-ids
+*/
+type Telegram struct {
+	name       string
+	Token      string
+	APIURL     string
+	ChatIDs    []string
+	TopicChats map[string][]string
+	Timeout    time.Duration
+}
+
+// TelegramMaxMessage - telegram message length limit
+const TelegramMaxMessage = 4096
+
+// telegramChatInterval - minimal interval between messages to the same chat
+var telegramChatInterval = time.Second
+
+func init() {
+	Register("telegram", func(name string, params map[string]interface{}) (Notifier, error) {
+		t := &Telegram{
+			name:       name,
+			Token:      ParamString(params, "token", ""),
+			APIURL:     strings.TrimRight(ParamString(params, "api-url", "https://api.telegram.org"), "/"),
+			ChatIDs:    chatIDs(params["chat-ids"]),
+			TopicChats: make(map[string][]string),
+			Timeout:    time.Duration(ParamInt(params, "timeout", 15)) * time.Second,
+		}
+		if t.Token == "" {
+			return nil, fmt.Errorf("missing 'token'")
+		}
+		if topics, ok := params["topic-chats"].(map[string]interface{}); ok {
+			for topic, ids := range topics {
+				t.TopicChats[topic] = chatIDs(ids)
+			}
+		}
+		if len(t.ChatIDs) == 0 && len(t.TopicChats) == 0 {
+			return nil, fmt.Errorf("missing 'chat-ids' or 'topic-chats'")
+		}
+		return t, nil
+	})
+}
+
+// chatIDs - chat ids can be integers or @channel names
+func chatIDs(value interface{}) []string {
+	result := make([]string, 0)
+	list, ok := value.([]interface{})
+	if !ok {
+		if value != nil {
+			list = []interface{}{value}
+		}
+	}
+	for _, v := range list {
+		switch id := v.(type) {
+		case string:
+			result = append(result, id)
+		case int, int64:
+			result = append(result, fmt.Sprintf("%d", id))
+		case float64:
+			result = append(result, fmt.Sprintf("%.0f", id))
+		}
+	}
+	return result
+}
+
+// Name - notifier name
+func (t *Telegram) Name() string {
+	return t.name
+}
+
+// Chats - chats for topic
+func (t *Telegram) Chats(topic string) []string {
+	if chats, ok := t.TopicChats[topic]; ok {
+		return chats
+	}
+	return t.ChatIDs
+}
+
+// Notify - group events by chat and send them
+func (t *Telegram) Notify(events []Event) error {
+	sort.SliceStable(events, func(a, b int) bool { return events[a].Time.Before(events[b].Time) })
+
+	lines := make(map[string][]string)
+	chats := make([]string, 0)
+	for _, e := range events {
+		for _, chat := range t.Chats(e.Topic) {
+			if _, ok := lines[chat]; !ok {
+				chats = append(chats, chat)
+			}
+			lines[chat] = append(lines[chat], TelegramLine(e))
+		}
+	}
+
+	failed := 0
+	for _, chat := range chats {
+		for n, message := range splitMessage(lines[chat], TelegramMaxMessage) {
+			if n > 0 {
+				time.Sleep(telegramChatInterval)
+			}
+			if err := t.send(chat, message); err != nil {
+				logger.Err("[telegram:%s]: chat %s: %s", t.name, chat, err.Error())
+				failed++
+				break
+			}
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to send messages to %d of %d chats", failed, len(chats))
+	}
+	return nil
+}
+
+/*
+TelegramLine - format event as html message line
+*/
+func TelegramLine(e Event) string {
+	host := html.EscapeString(e.Host)
+	topic := html.EscapeString(e.Topic)
+	if !e.Alive {
+		line := fmt.Sprintf("🔴 <b>%s</b> (%s) is DOWN", host, topic)
+		if e.Duration > 0 && e.Previous != "" {
+			line += fmt.Sprintf(", was %s %s", e.Previous, formatDuration(e.Duration))
+		}
+		return line
+	}
+
+	if e.State == StateDegraded {
+		return fmt.Sprintf("🟡 <b>%s</b> (%s) is DEGRADED, loss %.0f%%, rtt %.2f ms", host, topic, 100-e.Result.SuccessPercent, e.Result.AvgRttMs)
+	}
+
+	line := fmt.Sprintf("🟢 <b>%s</b> (%s) is UP, rtt %.2f ms", host, topic, e.Result.AvgRttMs)
+	if e.Duration > 0 && e.Previous == StateDown {
+		line += fmt.Sprintf(", outage %s", formatDuration(e.Duration))
+	}
+	return line
+}
+
+// splitMessage - join lines into messages not longer than limit
+func splitMessage(lines []string, limit int) []string {
+	messages := make([]string, 0)
+	current := ""
+	for _, line := range lines {
+		if current != "" && len(current)+1+len(line) > limit {
+			messages = append(messages, current)
+			current = ""
+		}
+		if current != "" {
+			current += "\n"
+		}
+		current += line
+	}
+	if current != "" {
+		messages = append(messages, current)
+	}
+	return messages
+}
+
+type telegramResponse struct {
+	Ok          bool   `json:"ok"`
+	Description string `json:"description"`
+	Parameters  struct {
+		RetryAfter int `json:"retry_after"`
+	} `json:"parameters"`
+}
+
+// send - sendMessage api call; waits and retries once if telegram asks so (http 429)
+func (t *Telegram) send(chat string, text string) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"chat_id":                  chat,
+		"text":                     text,
+		"parse_mode":               "HTML",
+		"disable_web_page_preview": true,
+	})
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/bot%s/sendMessage", t.APIURL, t.Token)
+	for attempt := 0; ; attempt++ {
+		client := httpclient.NewTimeoutClient(t.Timeout, t.Timeout)
+		req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		response, err := client.Do(req)
+		if err != nil {
+			// do not leak token into log
+			return fmt.Errorf("request failed: %s", strings.Replace(err.Error(), t.Token, "***", -1))
+		}
+		var result telegramResponse
+		json.NewDecoder(response.Body).Decode(&result)
+		response.Body.Close()
+
+		if response.StatusCode == http.StatusTooManyRequests && attempt == 0 && result.Parameters.RetryAfter > 0 {
+			time.Sleep(time.Duration(result.Parameters.RetryAfter) * time.Second)
+			continue
+		}
+		if response.StatusCode != http.StatusOK || !result.Ok {
+			return fmt.Errorf("status %d: %s", response.StatusCode, result.Description)
+		}
+		return nil
+	}
+}
+
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	if days > 0 {
+		return fmt.Sprintf("%dd %s", days, d.String())
+	}
+	return d.String()
+}
@@ -0,0 +1,217 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"pinger/httpclient"
+	"pinger/logger"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+Webhook - POST json updates to URL: legacy `{"ip":alive}` map or versioned payload (see PayloadV1).
+Topic's UpdateURL is implicit webhook named by it's URL and options (see URLNotifierName).
+Url, method, headers and body are text/templates (see httpclient.RequestTemplate) over UpdateData;
+without body template payload in `format` is sent.
+
+	[notifiers.api]
+	type = "webhook"
+	url = "https://my-api-url/pingupdate"
+	timeout = 15
+	secret = "shared-secret"   # sign requests, see httpclient.Sign
+	format = "v1"              # "legacy" (default) or "v1"
+	method = "PUT"             # default POST
+	headers = { Authorization = "Bearer xxx" }
+	body = '{"changes":{{len .Events}}}'
+*/
+type Webhook struct {
+	name    string
+	URL     string
+	Timeout time.Duration
+	options WebhookOptions
+	mx      sync.Mutex
+}
+
+// WebhookOptions - options of webhook, which can be changed from topic parameters
+type WebhookOptions struct {
+	Secret  string
+	Format  string
+	Method  string
+	Headers map[string]string
+	Body    string
+}
+
+// UpdateData - data for webhook templates
+type UpdateData struct {
+	Notifier string
+	Time     time.Time
+	Events   []Event
+	Payload  string // events encoded in webhook format
+}
+
+// Request - parse request templates of options
+func (o WebhookOptions) Request(url string) (*httpclient.RequestTemplate, error) {
+	method := o.Method
+	if method == "" {
+		method = "POST"
+	}
+	return httpclient.ParseRequestTemplate(method, url, o.Headers, o.Body)
+}
+
+var (
+	defaultSecret   string // secret for webhooks without own secret (`pinger.update-secret`)
+	defaultSecretMx sync.Mutex
+)
+
+// SetDefaultSecret - set secret for webhooks without own secret
+func SetDefaultSecret(secret string) {
+	defaultSecretMx.Lock()
+	defer defaultSecretMx.Unlock()
+	defaultSecret = secret
+}
+
+func init() {
+	Register("webhook", func(name string, params map[string]interface{}) (Notifier, error) {
+		w := NewWebhook(name, ParamString(params, "url", ""))
+		if w.URL == "" {
+			return nil, fmt.Errorf("missing 'url'")
+		}
+		w.Timeout = time.Duration(ParamInt(params, "timeout", 15)) * time.Second
+		options := WebhookOptions{
+			Secret:  ParamString(params, "secret", ""),
+			Format:  ParamString(params, "format", FormatLegacy),
+			Method:  ParamString(params, "method", ""),
+			Headers: ParamStringMap(params, "headers"),
+			Body:    ParamString(params, "body", ""),
+		}
+		if !IsFormat(options.Format) {
+			return nil, fmt.Errorf("unknown format '%s', known formats: %v", options.Format, Formats)
+		}
+		if _, err := options.Request(w.URL); err != nil {
+			return nil, err
+		}
+		w.SetOptions(options)
+		return w, nil
+	})
+}
+
+// NewWebhook - returns webhook notifier with default timeout
+func NewWebhook(name string, url string) *Webhook {
+	return &Webhook{name: name, URL: url, Timeout: 15 * time.Second}
+}
+
+/*
+URLNotifier - returns implicit webhook notifier for update url and options, creating it if needed.
+Topics sharing same update url with different options (secret, format, request templates) get different
+webhooks, see URLNotifierName
+*/
+func URLNotifier(url string, options WebhookOptions) Notifier {
+	name := URLNotifierName(url, options)
+	n, _ := notifiers.LoadOrStore(name, NewWebhook(name, url))
+	if w, ok := n.(*Webhook); ok {
+		// webhook can be created from spool without options (see buffer.loadSpool)
+		w.SetOptions(options)
+	}
+	return n.(Notifier)
+}
+
+// optionsHashLen - hex digits of options hash in implicit webhook name
+const optionsHashLen = 8
+
+// URLNotifierName - name of implicit webhook: update url without options, `url#hash` of options otherwise
+func URLNotifierName(url string, options WebhookOptions) string {
+	if options.Secret == "" && options.Format == "" && options.Method == "" && len(options.Headers) == 0 && options.Body == "" {
+		return url
+	}
+	// map keys are sorted by json, so equal options have equal hash
+	bytes, _ := json.Marshal(options)
+	sum := sha256.Sum256(bytes)
+	return url + "#" + hex.EncodeToString(sum[:])[:optionsHashLen]
+}
+
+// urlOfName - update url of implicit webhook name (see URLNotifierName)
+func urlOfName(name string) string {
+	n := strings.LastIndex(name, "#")
+	if n < 0 || len(name)-n-1 != optionsHashLen {
+		return name
+	}
+	if _, err := hex.DecodeString(name[n+1:]); err != nil {
+		return name
+	}
+	return name[:n]
+}
+
+// SetOptions - set webhook options
+func (w *Webhook) SetOptions(options WebhookOptions) {
+	w.mx.Lock()
+	w.options = options
+	w.mx.Unlock()
+}
+
+// Options - webhook options; empty secret is replaced with default one (see SetDefaultSecret)
+func (w *Webhook) Options() WebhookOptions {
+	w.mx.Lock()
+	options := w.options
+	w.mx.Unlock()
+	if options.Secret == "" {
+		defaultSecretMx.Lock()
+		options.Secret = defaultSecret
+		defaultSecretMx.Unlock()
+	}
+	return options
+}
+
+// Name - notifier name
+func (w *Webhook) Name() string {
+	return w.name
+}
+
+// Notify - send json updates to url
+func (w *Webhook) Notify(events []Event) error {
+	options := w.Options()
+	jsonValues, err := MarshalPayload(options.Format, events)
+	if err != nil {
+		return fmt.Errorf("cannot marshal results to json: %s", err.Error())
+	}
+	logger.Debug("JSON UPDATES for '%s': %+v", w.URL, string(jsonValues))
+
+	request, err := options.Request(w.URL)
+	if err != nil {
+		return err
+	}
+	req, body, err := request.NewRequest(UpdateData{Notifier: w.name, Time: time.Now(), Events: events, Payload: string(jsonValues)})
+	if err != nil {
+		return fmt.Errorf("failed to make new http request: %s", err.Error())
+	}
+	if !request.HasBody() {
+		body = jsonValues
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		req.ContentLength = int64(len(body))
+		if options.Format == FormatV1 {
+			req.Header.Set("X-Pinger-Payload-Version", "1")
+		}
+	}
+	req.Close = true
+	if req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	httpclient.Sign(req, body, options.Secret)
+
+	client := httpclient.NewTimeoutClient(w.Timeout, w.Timeout)
+	response, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make update request: %s", err.Error())
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("update request on '%s' failed: status %d (%s)", w.URL, response.StatusCode, response.Status)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package notify
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestURLNotifierSecrets(t *testing.T) {
+	const url = "http://127.0.0.1/secrets"
+	tests := []struct {
+		name   string
+		first  WebhookOptions
+		second WebhookOptions
+		same   bool
+	}{
+		{"no options", WebhookOptions{}, WebhookOptions{}, true},
+		{"same secret", WebhookOptions{Secret: "a"}, WebhookOptions{Secret: "a"}, true},
+		{"different secrets", WebhookOptions{Secret: "a"}, WebhookOptions{Secret: "b"}, false},
+		{"secret and default", WebhookOptions{Secret: "a"}, WebhookOptions{}, false},
+		{"different headers", WebhookOptions{Headers: map[string]string{"X-Key": "a"}}, WebhookOptions{Headers: map[string]string{"X-Key": "b"}}, false},
+	}
+	for _, test := range tests {
+		first := URLNotifier(url, test.first).(*Webhook)
+		second := URLNotifier(url, test.second).(*Webhook)
+		if (first == second) != test.same {
+			t.Errorf("%s: same webhook is %v, expected %v (%s, %s)", test.name, first == second, test.same, first.Name(), second.Name())
+		}
+		if first.URL != url || second.URL != url {
+			t.Errorf("%s: webhook urls are %s, %s", test.name, first.URL, second.URL)
+		}
+		if first.Options().Secret != test.first.Secret {
+			t.Errorf("%s: first webhook secret is replaced with '%s'", test.name, first.Options().Secret)
+		}
+	}
+}
+
+func TestURLOfName(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+	}{
+		{"http://127.0.0.1/updates", "http://127.0.0.1/updates"},
+		{URLNotifierName("http://127.0.0.1/updates", WebhookOptions{Secret: "a"}), "http://127.0.0.1/updates"},
+		{"http://127.0.0.1/updates#anchor", "http://127.0.0.1/updates#anchor"},
+	}
+	for _, test := range tests {
+		if url := urlOfName(test.name); url != test.url {
+			t.Errorf("%s: url is '%s', expected '%s'", test.name, url, test.url)
+		}
+	}
+}
+
+func TestURLNotifierFormats(t *testing.T) {
+	versions := make(chan string, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		versions <- r.Header.Get("X-Pinger-Payload-Version")
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		format  string
+		version string
+	}{
+		{FormatLegacy, ""},
+		{FormatV1, "1"},
+	}
+	// both topics are registered before updates are sent, the second one should not change format of the first
+	webhooks := make([]Notifier, len(tests))
+	for n, test := range tests {
+		webhooks[n] = URLNotifier(server.URL, WebhookOptions{Format: test.format})
+	}
+	for n, test := range tests {
+		if err := webhooks[n].Notify([]Event{{Topic: "t", Host: "10.10.10.1", Alive: true}}); err != nil {
+			t.Fatalf("%s: %s", test.format, err.Error())
+		}
+		if version := <-versions; version != test.version {
+			t.Errorf("%s: payload version is '%s', expected '%s'", test.format, version, test.version)
+		}
+	}
+}
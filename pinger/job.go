@@ -65,6 +65,7 @@ func (j *PingJob) listenReplies(pingID int) {
 			//logger.Debug("%s (Run): got reply", j.Host)
 			// not our ping
 			if echoReply.ID != pingID {
+				icmpUnmatched.Inc(nil)
 				//logger.Debug("%s: Wrong ping ID: %d, waiting %d", j.Host, echoReply.ID, pingID)
 				continue
 			}
@@ -171,6 +172,8 @@ func (j *PingJob) sendEcho(seq int, id int) time.Time {
 	Pinger.ListenerLock.Lock()
 	if _, err := Pinger.Listener.WriteTo(writebuf, &net.IPAddr{IP: net.ParseIP(j.Host)}); err != nil {
 		logger.Err("Cannot send echo request: %s", err.Error())
+	} else {
+		icmpSent.Inc(nil)
 	}
 	defer Pinger.ListenerLock.Unlock()
 
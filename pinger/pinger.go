@@ -1,15 +1,18 @@
 package pinger
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"golang.org/x/net/icmp"
 	"golang.org/x/net/ipv4"
 	"net"
-	"net/http"
 	"pinger/httpclient"
 	"pinger/logger"
+	"pinger/metrics"
 	"strings"
 	"sync"
+	"time"
 )
 
 // PingDaemon is a global and unique struct for our pinger
@@ -17,11 +20,64 @@ type PingDaemon struct {
 	Listener     *icmp.PacketConn
 	ListenerLock sync.Mutex
 	Jobs         sync.Map
+
+	resultRequest *httpclient.RequestTemplate // result callback, see SetResultRequest
+	resultSecret  string
+	resultMx      sync.Mutex
+
+	running  sync.WaitGroup // started jobs, see Stop and Wait
+	stopping bool
+	stateMx  sync.Mutex
+}
+
+/*
+ResultData - data for result callback templates
+
+	http://api.local/pingresult?host={{query .Host}}&alive={{.Result.Alive}}&ms={{.Result.AvgRttMs}}&site={{query (index .Labels "site")}}
+*/
+type ResultData struct {
+	Host     string            // host as requested (ip or hostname)
+	IP       string            // pinged ip
+	Topic    string            // topic, if host is requested with topic
+	Labels   map[string]string // topic and host labels
+	Probes   int
+	Result   PingResult
+	Started  time.Time
+	Finished time.Time
 }
 
+// legacy result url placeholders
+var resultPlaceholders = strings.NewReplacer(
+	"{host}", "{{query .Host}}",
+	"{alive}", "{{.Result.Alive}}",
+	"{ns}", "{{.Result.AvgRttNs}}",
+	"{ms}", `{{printf "%f" .Result.AvgRttMs}}`,
+)
+
 // Pinger is PingDaemon instance
 var Pinger PingDaemon
 
+// Ping errors
+var (
+	ErrJobRunning = errors.New("ping job is already running")
+	ErrResolve    = errors.New("host is not ip and cannot be resolved")
+	ErrStopping   = errors.New("pinger is shutting down")
+)
+
+var (
+	icmpSent      = metrics.NewCounter("pinger_icmp_packets_sent_total", "ICMP echo requests sent")
+	icmpReceived  = metrics.NewCounter("pinger_icmp_packets_received_total", "ICMP packets received by listener")
+	icmpUnmatched = metrics.NewCounter("pinger_icmp_packets_unmatched_total", "Received ICMP packets not matching any running job")
+	_             = metrics.NewGaugeFunc("pinger_jobs_running", "Ping jobs running right now", func() float64 {
+		jobs := 0
+		Pinger.Jobs.Range(func(_, _ interface{}) bool {
+			jobs++
+			return true
+		})
+		return float64(jobs)
+	})
+)
+
 // Init - initializing pinger daemon; starting listener
 func (p *PingDaemon) Init() error {
 	logger.Debug("Starting pinger instance")
@@ -43,19 +99,57 @@ func (p *PingDaemon) Init() error {
 // Ping - pinging host right now without any goroutines, return result
 //func (p *PingDaemon) Ping(IP net.IP, probes int) (*PingResult, error) {
 func (p *PingDaemon) Ping(IP fmt.Stringer, probes int) (*PingResult, error) {
-	// check if there is no such running job
-	if _, running := p.Jobs.Load(IP.String()); running {
-		logger.Err("Ping job for '%s' is already running.", IP.String())
-		return nil, fmt.Errorf("Ping job for '%s' is already running", IP.String())
+	job, err := p.startJob(IP.String())
+	if err != nil {
+		return nil, err
 	}
-
-	job := NewJob(IP.String())
-	p.Jobs.Store(IP.String(), job)
+	defer p.running.Done()
 	result := job.Run(probes)
 
 	return result, nil
 }
 
+// startJob - register new job for ip, unless there is running one or pinger is stopping
+func (p *PingDaemon) startJob(ip string) (*PingJob, error) {
+	p.stateMx.Lock()
+	defer p.stateMx.Unlock()
+	if p.stopping {
+		return nil, ErrStopping
+	}
+	// check if there is no such running job
+	if _, running := p.Jobs.Load(ip); running {
+		logger.Err("Ping job for '%s' is already running.", ip)
+		return nil, fmt.Errorf("%w: '%s'", ErrJobRunning, ip)
+	}
+
+	job := NewJob(ip)
+	p.Jobs.Store(ip, job)
+	p.running.Add(1)
+	return job, nil
+}
+
+// Stop - refuse new jobs (ErrStopping) on shutdown; running jobs are not interrupted, see Wait
+func (p *PingDaemon) Stop() {
+	p.stateMx.Lock()
+	defer p.stateMx.Unlock()
+	p.stopping = true
+}
+
+// Wait - wait for running jobs (and their result callbacks) until ctx is done
+func (p *PingDaemon) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		p.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // PingNow - pings host and returns result without any goroutines
 func (p *PingDaemon) PingNow(host string, probes int) (*PingResult, error) {
 	// find valid ip
@@ -64,7 +158,7 @@ func (p *PingDaemon) PingNow(host string, probes int) (*PingResult, error) {
 		ips, err := net.LookupIP(host)
 		if err != nil || len(ips) == 0 {
 			logger.Err("'%s' is not ip, but also cannot resolve it with dns.", host)
-			return nil, fmt.Errorf("'%s' is not ip, but also cannot resolve it with dns", host)
+			return nil, fmt.Errorf("%w: '%s'", ErrResolve, host)
 		}
 
 		ip = ips[0]
@@ -72,8 +166,33 @@ func (p *PingDaemon) PingNow(host string, probes int) (*PingResult, error) {
 	return p.Ping(ip, probes)
 }
 
-// PingResultURL - pings host in goroutine and sends result to result URL
-func (p *PingDaemon) PingResultURL(host string, probes int, url string) {
+/*
+SetResultRequest - parse result callback templates (see httpclient.RequestTemplate).
+Old-style `{host}`, `{alive}`, `{ns}`, `{ms}` placeholders are still supported in urls without `{{`.
+On error previous request is kept
+*/
+func (p *PingDaemon) SetResultRequest(method string, url string, headers map[string]string, body string, secret string) error {
+	var request *httpclient.RequestTemplate
+	if url != "" {
+		if !strings.Contains(url, "{{") {
+			url = resultPlaceholders.Replace(url)
+		}
+		var err error
+		request, err = httpclient.ParseRequestTemplate(method, url, headers, body)
+		if err != nil {
+			return err
+		}
+	}
+	p.resultMx.Lock()
+	p.resultRequest = request
+	p.resultSecret = secret
+	p.resultMx.Unlock()
+	return nil
+}
+
+// PingResultURL - pings host in goroutine and sends result to result URL; request is signed if secret is set.
+// data should have Topic and Labels filled, if known
+func (p *PingDaemon) PingResultURL(host string, probes int, data ResultData) {
 	// find valid ip
 	ip := net.ParseIP(host)
 	if ip == nil {
@@ -85,43 +204,47 @@ func (p *PingDaemon) PingResultURL(host string, probes int, url string) {
 		ip = ips[0]
 	}
 
-	// check if there is no such running job
-	if _, running := p.Jobs.Load(ip.String()); running {
-		logger.Err("Ping job for '%s' is already running.", ip.String())
+	job, err := p.startJob(ip.String())
+	if err != nil {
+		logger.Debug("Cannot ping '%s': %s", host, err.Error())
 		return
 	}
-
-	job := NewJob(ip.String())
-	p.Jobs.Store(ip.String(), job)
+	// result callback is a part of the job: shutdown waits for it too
+	defer p.running.Done()
+	data.Started = time.Now()
 	result := job.Run(probes)
+	data.Finished = time.Now()
 
-	// form url
-	if "" == url {
+	p.resultMx.Lock()
+	request, secret := p.resultRequest, p.resultSecret
+	p.resultMx.Unlock()
+	if request == nil {
 		// todo: other notifies?
 		return
 	}
-	url = strings.Replace(url, `{host}`, host, -1)
-	if result.Alive {
-		url = strings.Replace(url, `{alive}`, "true", -1)
-	} else {
-		url = strings.Replace(url, `{alive}`, "false", -1)
-	}
-	url = strings.Replace(url, `{ns}`, fmt.Sprintf("%d", result.AvgRttNs), -1)
-	url = strings.Replace(url, `{ms}`, fmt.Sprintf("%f", result.AvgRttMs), -1)
-	logger.Debug("API CALL: %s", url)
+	data.Host = host
+	data.IP = ip.String()
+	data.Probes = probes
+	data.Result = *result
 
-	client := httpclient.NewTimeoutClient()
-	req, err := http.NewRequest("GET", url, nil)
+	req, body, err := request.NewRequest(data)
 	if err != nil {
 		logger.Err("Error creating request: %s", err.Error())
 		return
 	}
+	logger.Debug("API CALL: %s %s", req.Method, req.URL.String())
+	httpclient.Sign(req, body, secret)
 
-	_, err = client.Do(req)
+	client := httpclient.NewTimeoutClient()
+	response, err := client.Do(req)
 	if err != nil {
 		logger.Err("Error requesting api: %s", err.Error())
 		return
 	}
+	response.Body.Close()
+	if response.StatusCode >= 300 {
+		logger.Err("Error requesting api: status %d", response.StatusCode)
+	}
 }
 
 func (p *PingDaemon) listen() {
@@ -135,6 +258,7 @@ func (p *PingDaemon) listen() {
 			continue
 		}
 		//bytes := readBuf[:n]
+		icmpReceived.Inc(nil)
 		copied := make([]byte, len(readBuf[:n]))
 		copy(copied, readBuf[:n])
 		go func(b []byte) {
@@ -152,6 +276,7 @@ func (p *PingDaemon) listen() {
 				if parsed.Type != ipv4.ICMPTypeEchoReply {
 					// non-reply message
 					//logger.Debug("Non-Reply message from %s: %d; %+v", host, parsed.Type, parsed)
+					icmpUnmatched.Inc(nil)
 					logger.Debug("Non-Reply message from %s: %d; %+v\nbytes: %+v\nper byte: 0: %+v, 1: %+v, 2: %+v, 3: %+v", host, parsed.Type, parsed, b, b[0],b[1],b[2],b[3])
 					//continue
 					return
@@ -159,7 +284,9 @@ func (p *PingDaemon) listen() {
 				//logger.Debug("Reply message from %s: %d; %+v", host, parsed.Type, parsed)
 
 				job := j.(*PingJob)
-				if !job.Done {
+				if job.Done {
+					icmpUnmatched.Inc(nil)
+				} else {
 					// parse body
 					if parsed.Body != nil && parsed.Body.Len(parsed.Type.Protocol()) != 0 {
 						// we need mutex to avoid writing to closed channel
@@ -171,6 +298,8 @@ func (p *PingDaemon) listen() {
 						job.ChanMx.Unlock()
 					}
 				}
+			} else {
+				icmpUnmatched.Inc(nil)
 			}
 		}(copied)
 	}
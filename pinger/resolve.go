@@ -0,0 +1,126 @@
+package pinger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang.org/x/net/dns/dnsmessage"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+)
+
+// ResolvConf - file with name servers, which are asked for TTL of host names
+var ResolvConf = "/etc/resolv.conf"
+
+// ErrTruncated - dns answer doesn't fit in udp packet, system resolver is used
+var ErrTruncated = errors.New("dns answer is truncated")
+
+/*
+Resolve - ipv4 addresses of host name in order of dns answer and the smallest TTL of answer records (CNAMEs included).
+Name servers from ResolvConf are asked directly to get TTL. If they cannot answer (search domains, /etc/hosts,
+truncated answer), system resolver is used and TTL is 0 - unknown
+*/
+func Resolve(ctx context.Context, name string) ([]net.IP, time.Duration, error) {
+	for _, server := range nameservers() {
+		ips, ttl, err := queryA(ctx, server, name)
+		if err == nil {
+			return ips, ttl, nil
+		}
+		if ctx.Err() != nil {
+			return nil, 0, ctx.Err()
+		}
+	}
+
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip4", name)
+	if err != nil {
+		return nil, 0, err
+	}
+	return ips, 0, nil
+}
+
+// nameservers - "ip:53" of name servers from ResolvConf
+func nameservers() []string {
+	contents, err := ioutil.ReadFile(ResolvConf)
+	if err != nil {
+		return nil
+	}
+	servers := make([]string, 0)
+	for _, line := range strings.Split(string(contents), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "nameserver" && net.ParseIP(fields[1]) != nil {
+			servers = append(servers, net.JoinHostPort(fields[1], "53"))
+		}
+	}
+	return servers
+}
+
+// queryA - ask A records of name from server over udp
+func queryA(ctx context.Context, server string, name string) ([]net.IP, time.Duration, error) {
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	question, err := dnsmessage.NewName(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	id := uint16(rand.Intn(1 << 16))
+	query := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: question, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET}},
+	}
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", server)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	if _, err := conn.Write(packed); err != nil {
+		return nil, 0, err
+	}
+
+	buf := make([]byte, 512)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, 0, err
+		}
+		var answer dnsmessage.Message
+		if err := answer.Unpack(buf[:n]); err != nil || answer.ID != id || !answer.Response {
+			// not an answer to our query
+			continue
+		}
+		if answer.Truncated {
+			return nil, 0, ErrTruncated
+		}
+		if answer.RCode != dnsmessage.RCodeSuccess {
+			return nil, 0, fmt.Errorf("%s: %s", server, answer.RCode.String())
+		}
+
+		ips := make([]net.IP, 0)
+		var ttl uint32
+		for n, record := range answer.Answers {
+			if n == 0 || record.Header.TTL < ttl {
+				ttl = record.Header.TTL
+			}
+			if a, ok := record.Body.(*dnsmessage.AResource); ok {
+				ips = append(ips, net.IPv4(a.A[0], a.A[1], a.A[2], a.A[3]))
+			}
+		}
+		if len(ips) == 0 {
+			return nil, 0, fmt.Errorf("%s: no A records for '%s'", server, name)
+		}
+		return ips, time.Duration(ttl) * time.Second, nil
+	}
+}
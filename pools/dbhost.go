@@ -2,10 +2,19 @@ package pools
 
 import (
 	"net"
+	"pinger/history"
 	"pinger/logger"
+	"pinger/metrics"
 	"pinger/pinger"
 	"pinger/notify"
 	"sync"
+	"time"
+)
+
+var (
+	hostAlive = metrics.NewGauge("pinger_host_alive", "Host state in topic: 1 - alive, 0 - dead")
+	hostRtt   = metrics.NewGauge("pinger_host_rtt_seconds", "Average RTT of the last check")
+	hostLoss  = metrics.NewGauge("pinger_host_loss_ratio", "Packet loss of the last check, 0..1")
 )
 
 /*
@@ -13,24 +22,49 @@ DBHost - struct for per-topic in-memory instances for host.
 Can be multiple DBHosts for each pinged host (in different topics)
  */
 type DBHost struct {
-	IP        net.IP
+	IP        net.IP				// for dns name - the first resolved address, nil until name is resolved
+	Name      string				// dns name, if host is given by name
+	Resolve   string				// resolve mode of dns name, see ResolveModes
+	Addresses []net.IP				// resolved addresses of dns name
+	Topic     string
 	Probes    int
 	Timeout   int64
 	Interval  int64
 	UpdateURL string
+	UpdateSecret string
+	UpdateFormat string
+	UpdateMethod string
+	UpdateHeaders map[string]string
+	UpdateBody string
+	Notifiers []string
+	Labels    map[string]string		// topic labels merged with host's own
 	Mx        sync.Mutex
 	Alive     bool
+	Degraded  bool
+	Changed   time.Time
+	Checked   time.Time				// time of the last check, zero if not checked yet
+	LastResult pinger.PingResult
+	Recent    []RecentResult		// last RecentResults checks, oldest first
+	Static    bool					// declared in config file, see DBPool.SetStatic
+}
+
+// Key - host key in topic (see HostKey): dns name or ip
+func (h *DBHost) Key() string {
+	if h.Name != "" {
+		return h.Name
+	}
+	return h.IP.String()
 }
 
 // Lock - lock host mutex; write log
 func (h *DBHost) Lock(where string) {
-	logger.DebugLock("%s: DBHost:Lock() | %s", h.IP.String(), where)
+	logger.DebugLock("%s: DBHost:Lock() | %s", h.Key(), where)
 	h.Mx.Lock()
 }
 
 // Unlock - unlock host mutex; write debug log
 func (h *DBHost) Unlock(where string) {
-	logger.DebugLock("%s: DBHost:Unlock() | %s", h.IP.String(), where)
+	logger.DebugLock("%s: DBHost:Unlock() | %s", h.Key(), where)
 	h.Mx.Unlock()
 }
 
@@ -38,17 +72,122 @@ func (h *DBHost) Unlock(where string) {
 Updated - called from pinger when host state is determined: true of false
  */
 func (h *DBHost) Updated(result pinger.PingResult) {
-	// todo: send update via UpdateURL
-	// todo: send udpates to telegram bot (todo: make telegram api)
 	h.Lock("Update")
-	if result.Alive != h.Alive {
-		logger.Debug("[DBHost]: %s: state changed: %v", h.IP.String(), result.Alive)
+	h.Checked = time.Now()
+	h.LastResult = result
+	h.Recent = append(h.Recent, RecentResult{Time: h.Checked, Alive: result.Alive, HostStats: *newHostStats(result)})
+	if over := len(h.Recent) - RecentResults; over > 0 {
+		h.Recent = h.Recent[over:]
+	}
+	h.SetMetrics(result)
+	degraded := TopicPool.IsDegraded(result)
+	previous := notify.StateOf(h.Alive, h.Degraded)
+	state := notify.StateOf(result.Alive, degraded)
+	if state != previous {
+		logger.Debug("[DBHost]: %s: state changed: %s -> %s", h.Key(), previous, state)
+		now := time.Now()
+		if result.Alive != h.Alive {
+			history.Journal.Record(h.Topic, h.Key(), history.StateEvent(result.Alive), now)
+		}
 		h.Alive = result.Alive
+		h.Degraded = degraded
+
+		event := notify.Event{Topic: h.Topic, Host: h.Key(), State: state, Previous: previous, Alive: h.Alive, Result: result, Time: now, Labels: h.Labels}
+		if !h.Changed.IsZero() {
+			event.Duration = now.Sub(h.Changed)
+		}
+		h.Changed = now
 		if "" != h.UpdateURL {
-			notify.Buffer.BufferResult(h.UpdateURL, h.IP.String(), result)
+			notify.Buffer.BufferURL(h.UpdateURL, h.WebhookOptions(), event)
+		}
+		for _, name := range h.Notifiers {
+			notify.Buffer.BufferEvent(name, event)
 		}
-	} // else {
-	//	//logger.Debug("[DBHost]: %s: state not changed: %v", h.IP.String(), result.Alive)
-	//}
+		notify.Events.Publish(notify.KindState, event)
+	}
+	notify.Events.Publish(notify.KindCheck, notify.Event{Topic: h.Topic, Host: h.Key(), State: state, Previous: previous, Alive: result.Alive, Result: result, Time: h.Checked, Labels: h.Labels})
 	h.Unlock("Update")
 }
+
+// WebhookOptions - options for UpdateURL webhook
+func (h *DBHost) WebhookOptions() notify.WebhookOptions {
+	return notify.WebhookOptions{
+		Secret:  h.UpdateSecret,
+		Format:  h.UpdateFormat,
+		Method:  h.UpdateMethod,
+		Headers: h.UpdateHeaders,
+		Body:    h.UpdateBody,
+	}
+}
+
+// SetMetrics - export last check result as host gauges
+func (h *DBHost) SetMetrics(result pinger.PingResult) {
+	labels := h.MetricLabels()
+	alive := 0.0
+	if result.Alive {
+		alive = 1
+	}
+	hostAlive.Set(labels, alive)
+	hostRtt.Set(labels, float64(result.AvgRttNs)/1e9)
+	hostLoss.Set(labels, (100-result.SuccessPercent)/100)
+}
+
+// DeleteMetrics - remove host gauges (host is removed from topic)
+func (h *DBHost) DeleteMetrics() {
+	labels := h.MetricLabels()
+	hostAlive.Delete(labels)
+	hostRtt.Delete(labels)
+	hostLoss.Delete(labels)
+}
+
+// MetricLabels - labels for host gauges
+func (h *DBHost) MetricLabels() metrics.Labels {
+	return metrics.Labels{"topic": h.Topic, "host": h.Key()}
+}
+
+// document - host in saved/api json format; only parameters which differ from topic ones are included
+func (h *DBHost) document(topic *Topic) map[string]interface{} {
+	h.Lock("document")
+	defer h.Unlock("document")
+
+	doc := make(map[string]interface{})
+	doc["host"] = h.Key()
+	if h.Resolve != topic.Resolve {
+		doc["Resolve"] = h.Resolve
+	}
+	if h.Probes != topic.Probes {
+		doc["Probes"] = h.Probes
+	}
+	if h.Interval != topic.Interval {
+		doc["Interval"] = h.Interval
+	}
+	if h.UpdateURL != topic.UpdateURL {
+		doc["UpdateURL"] = h.UpdateURL
+	}
+	if h.UpdateSecret != topic.UpdateSecret {
+		doc["UpdateSecret"] = h.UpdateSecret
+	}
+	if h.UpdateFormat != topic.UpdateFormat {
+		doc["UpdateFormat"] = h.UpdateFormat
+	}
+	if h.UpdateMethod != topic.UpdateMethod {
+		doc["UpdateMethod"] = h.UpdateMethod
+	}
+	if !equalStringMaps(h.UpdateHeaders, topic.UpdateHeaders) {
+		doc["UpdateHeaders"] = h.UpdateHeaders
+	}
+	if h.UpdateBody != topic.UpdateBody {
+		doc["UpdateBody"] = h.UpdateBody
+	}
+	if !equalStringMaps(h.Labels, topic.Labels) {
+		doc["Labels"] = h.Labels
+	}
+	if !equalStrings(h.Notifiers, topic.Notifiers) {
+		doc["Notifiers"] = h.Notifiers
+	}
+	doc["alive"] = h.Alive
+	if !h.Changed.IsZero() {
+		doc["changed"] = h.Changed.Format(time.RFC3339)
+	}
+	return doc
+}
@@ -0,0 +1,189 @@
+package pools
+
+import (
+	"pinger/notify"
+	"pinger/pinger"
+	"time"
+)
+
+/*
+HostInfo - inventory record: host in topic with it's parameters, state and last check.
+Hosts added with /store-host (only in PingPool) have empty Topic
+*/
+type HostInfo struct {
+	Topic     string            `json:"topic"`
+	IP        string            `json:"ip"`                  // ip or dns name
+	Addresses []string          `json:"addresses,omitempty"` // resolved addresses of dns name
+	Resolve   string            `json:"resolve,omitempty"`
+	Probes    int               `json:"probes"`
+	Interval  int64             `json:"interval"`
+	UpdateURL string            `json:"updateURL,omitempty"`
+	Notifiers []string          `json:"notifiers,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	State     string            `json:"state"`
+	Alive     bool              `json:"alive"`
+	Changed   *time.Time        `json:"changed,omitempty"`
+	Checked   *time.Time        `json:"checked,omitempty"`
+	Stats     *HostStats        `json:"stats,omitempty"`
+	Static    bool              `json:"static,omitempty"` // declared in config, cannot be changed by API
+}
+
+// StateUnknown - state of PingPool host, which is not checked yet
+const StateUnknown = "unknown"
+
+// HostStats - statistics of the last check
+type HostStats struct {
+	SuccessPercent float64 `json:"successPercent"`
+	LossPercent    float64 `json:"lossPercent"`
+	AvgRttMs       float64 `json:"avgRttMs"`
+	AvgRttNs       int64   `json:"avgRttNs"`
+}
+
+// Info - inventory record of topic host
+func (h *DBHost) Info() HostInfo {
+	h.Lock("Info")
+	defer h.Unlock("Info")
+
+	info := HostInfo{
+		Topic:     h.Topic,
+		IP:        h.Key(),
+		Probes:    h.Probes,
+		Interval:  h.Interval,
+		UpdateURL: h.UpdateURL,
+		Notifiers: h.Notifiers,
+		Labels:    h.Labels,
+		State:     notify.StateOf(h.Alive, h.Degraded),
+		Alive:     h.Alive,
+		Static:    h.Static,
+	}
+	if h.Name != "" {
+		info.Addresses = addressStrings(h.Addresses)
+		info.Resolve = h.Resolve
+		if info.Resolve == "" {
+			info.Resolve = ResolveFirst
+		}
+	}
+	if !h.Changed.IsZero() {
+		changed := h.Changed
+		info.Changed = &changed
+	}
+	if !h.Checked.IsZero() {
+		checked := h.Checked
+		info.Checked = &checked
+		info.Stats = newHostStats(h.LastResult)
+	}
+	return info
+}
+
+// RecentResults - checks kept per topic host (for dashboard latency chart)
+const RecentResults = 120
+
+// RecentResult - one of the last checks of host
+type RecentResult struct {
+	Time  time.Time `json:"time"`
+	Alive bool      `json:"alive"`
+	HostStats
+}
+
+// RecentResults - copy of the last checks, oldest first
+func (h *DBHost) RecentResults() []RecentResult {
+	h.Lock("RecentResults")
+	defer h.Unlock("RecentResults")
+	return append([]RecentResult{}, h.Recent...)
+}
+
+func newHostStats(result pinger.PingResult) *HostStats {
+	return &HostStats{
+		SuccessPercent: result.SuccessPercent,
+		LossPercent:    100 - result.SuccessPercent,
+		AvgRttMs:       result.AvgRttMs,
+		AvgRttNs:       result.AvgRttNs,
+	}
+}
+
+/*
+Inventory - all hosts of all topics, and PingPool hosts which are not in any topic
+*/
+func Inventory() []HostInfo {
+	hosts := make([]HostInfo, 0)
+	inTopics := make(map[string]bool)
+	TopicPool.Topics.Range(func(_, t interface{}) bool {
+		t.(*Topic).Hosts.Range(func(key, h interface{}) bool {
+			inTopics[key.(string)] = true
+			hosts = append(hosts, h.(*DBHost).Info())
+			return true
+		})
+		return true
+	})
+
+	PingPool.Hosts.Range(func(key, h interface{}) bool {
+		if inTopics[key.(string)] {
+			return true
+		}
+		host := h.(*Host)
+		host.Lock("Inventory")
+		info := HostInfo{
+			IP:       host.Key(),
+			Probes:   host.Probes,
+			Interval: int64(host.Interval / time.Second),
+			State:    StateUnknown,
+		}
+		if !host.Checked.IsZero() {
+			checked := host.Checked
+			info.Checked = &checked
+			info.Alive = host.LastResult.Alive
+			info.State = notify.StateOf(info.Alive, TopicPool.IsDegraded(host.LastResult))
+			info.Stats = newHostStats(host.LastResult)
+		}
+		hosts = append(hosts, info)
+		host.Unlock("Inventory")
+		return true
+	})
+	return hosts
+}
+
+/*
+TopicInfo - topic parameters for API. Update secret, headers and body are not returned: they can contain credentials
+*/
+type TopicInfo struct {
+	Name         string            `json:"name"`
+	Probes       int               `json:"probes"`
+	Interval     int64             `json:"interval"`
+	UpdateURL    string            `json:"updateURL,omitempty"`
+	UpdateFormat string            `json:"updateFormat,omitempty"`
+	UpdateMethod string            `json:"updateMethod,omitempty"`
+	Notifiers    []string          `json:"notifiers,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	Resolve      string            `json:"resolve,omitempty"`
+	HostCount    int               `json:"hostCount"`
+	States       map[string]int    `json:"states"` // number of hosts by state
+	Static       bool              `json:"static,omitempty"`
+	Hosts        []HostInfo        `json:"hosts,omitempty"`
+}
+
+// Info - topic parameters, with hosts if withHosts is set
+func (t *Topic) Info(withHosts bool) TopicInfo {
+	info := TopicInfo{
+		Name:         t.Name,
+		Probes:       t.Probes,
+		Interval:     t.Interval,
+		UpdateURL:    t.UpdateURL,
+		UpdateFormat: t.UpdateFormat,
+		UpdateMethod: t.UpdateMethod,
+		Notifiers:    t.Notifiers,
+		Labels:       t.Labels,
+		Resolve:      t.Resolve,
+		States:       make(map[string]int),
+		Static:       t.Static,
+	}
+	t.Hosts.Range(func(_, h interface{}) bool {
+		host := h.(*DBHost).Info()
+		info.HostCount++
+		info.States[host.State]++
+		if withHosts {
+			info.Hosts = append(info.Hosts, host)
+		}
+		return true
+	})
+	return info
+}
@@ -4,7 +4,8 @@ import (
 	"fmt"
 	"strings"
 	"net"
-	"pinger/logger"
+	"pinger/notify"
+	"time"
 )
 
 /*
@@ -18,18 +19,51 @@ const (
 	StrBool    = "bool"			// bool const
 )
 
+/*
+FieldError - validation error of topics json. Field is path to wrong value, like `switches.Hosts[2].host`
+*/
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+func fieldError(field string, format string, args ...interface{}) error {
+	return &FieldError{Field: field, Message: fmt.Sprintf(format, args...)}
+}
+
 // todo: remove default url from config
 // todo: remove timeout from config
 
-// ParseTopics - parse json input (post or file contents) and return []Topic slice or error
+// ParseTopics - parse json input (post or file contents) and return []Topic slice or error. Parsed hosts are not started.
+// Notifiers should be registered (see notify.Get)
 func ParseTopics(topics map[string]interface{}, defProbes int, defInterval int64) ([]*Topic, error) {
+	return parseTopics(topics, defProbes, defInterval, registeredNotifier)
+}
+
+// registeredNotifier - notifier with given name is registered
+func registeredNotifier(name string) bool {
+	_, ok := notify.Get(name)
+	return ok
+}
+
+// anyNotifier - notifier names are not checked
+func anyNotifier(string) bool {
+	return true
+}
+
+// parseTopics - ParseTopics with check of notifier names
+func parseTopics(topics map[string]interface{}, defProbes int, defInterval int64, known func(string) bool) ([]*Topic, error) {
 
 	returnTopics := make([]*Topic, 0)
 
 	// range over json topics
 	for topicName, topicMap := range topics {
 		if gettype(topicMap) != StrMap {
-			return nil, fmt.Errorf("ParseTopics: %s should be map[string]interface, but %s given", topicName, gettype(topicMap))
+			return nil, fieldError(topicName, "should be map[string]interface, but %s given", gettype(topicMap))
 		}
 
 		topicMap := topicMap.(map[string]interface{})
@@ -51,20 +85,73 @@ func ParseTopics(topics map[string]interface{}, defProbes int, defInterval int64
 		if url, ok := topicMap["UpdateURL"]; ok && gettype(url) == StrString {
 			topic.UpdateURL = url.(string)
 		}
+		// parse update url secret
+		if secret, ok := topicMap["UpdateSecret"]; ok && gettype(secret) == StrString {
+			topic.UpdateSecret = secret.(string)
+		}
+		// parse update payload format
+		if format, ok := topicMap["UpdateFormat"]; ok && gettype(format) == StrString {
+			if !notify.IsFormat(format.(string)) {
+				return nil, fieldError(topicName+".UpdateFormat", "unknown format '%s', known formats: %v", format.(string), notify.Formats)
+			}
+			topic.UpdateFormat = format.(string)
+		}
+		// parse update request templates
+		if method, ok := topicMap["UpdateMethod"]; ok && gettype(method) == StrString {
+			topic.UpdateMethod = method.(string)
+		}
+		if headers, ok := topicMap["UpdateHeaders"]; ok {
+			values, err := parseStringMap(headers)
+			if err != nil {
+				return nil, fieldError(topicName+".UpdateHeaders", "%s", err.Error())
+			}
+			topic.UpdateHeaders = values
+		}
+		if body, ok := topicMap["UpdateBody"]; ok && gettype(body) == StrString {
+			topic.UpdateBody = body.(string)
+		}
+		if err := checkUpdateRequest(topic.UpdateURL, topic.webhookOptions()); err != nil {
+			return nil, fieldError(topicName+".UpdateURL", "%s", err.Error())
+		}
+		// parse labels
+		if labels, ok := topicMap["Labels"]; ok {
+			values, err := parseStringMap(labels)
+			if err != nil {
+				return nil, fieldError(topicName+".Labels", "%s", err.Error())
+			}
+			topic.Labels = values
+		}
+		// parse resolve mode of dns names
+		if resolve, ok := topicMap["Resolve"]; ok && gettype(resolve) == StrString {
+			if !isResolveMode(resolve.(string)) {
+				return nil, fieldError(topicName+".Resolve", "unknown resolve mode '%s', known modes: %v", resolve.(string), ResolveModes)
+			}
+			topic.Resolve = resolve.(string)
+		}
+		// parse notifiers
+		if notifiers, ok := topicMap["Notifiers"]; ok {
+			names, err := parseNotifiers(notifiers, known)
+			if err != nil {
+				return nil, fieldError(topicName+".Notifiers", "%s", err.Error())
+			}
+			topic.Notifiers = names
+		}
 
 		hosts, ok := topicMap["Hosts"]
 		if ok && gettype(hosts) == StrSlice {
 			// Parse hosts
-			hosts, err := ParseHosts(hosts.([]interface{}), topic.Probes, topic.Interval, topic.UpdateURL)
+			hosts, err := parseHosts(hosts.([]interface{}), &topic, known)
 			if err != nil {
-				logger.Err("Error parsing hosts in topic '%s': %s", topicName, err.Error())
-			} else {
-				for n := range hosts {
-					//topic.Hosts.Store(hosts[n].IP.String(), hosts[n])
-					topic.AddHost(hosts[n])
+				if fe, ok := err.(*FieldError); ok {
+					return nil, fieldError(topicName+"."+fe.Field, "%s", fe.Message)
 				}
-				returnTopics = append(returnTopics, &topic)
+				return nil, fieldError(topicName+".Hosts", "%s", err.Error())
+			}
+			for n := range hosts {
+				// only build topic: hosts are started, when topic is stored (see CompareTopic)
+				topic.storeHost(hosts[n])
 			}
+			returnTopics = append(returnTopics, &topic)
 		} else {
 			// Empty hosts slice
 			returnTopics = append(returnTopics, &topic)
@@ -74,27 +161,49 @@ func ParseTopics(topics map[string]interface{}, defProbes int, defInterval int64
 	return returnTopics, nil
 }
 
-// ParseHosts - parse hosts from json slice; return DBHost slice or error
-func ParseHosts(hosts []interface{}, probes int, interval int64, url string) ([]*DBHost, error) {
+// ParseHosts - parse hosts from json slice; hosts inherit topic parameters. Return DBHost slice or error
+func ParseHosts(hosts []interface{}, topic *Topic) ([]*DBHost, error) {
+	return parseHosts(hosts, topic, registeredNotifier)
+}
+
+func parseHosts(hosts []interface{}, topic *Topic, known func(string) bool) ([]*DBHost, error) {
 	newHosts := make([]*DBHost, 0)
 
 	for i, hostInt := range hosts {
 		if gettype(hostInt) != "map" {
-			return []*DBHost{}, fmt.Errorf("host %d is not a map", i)
+			return []*DBHost{}, fieldError(fmt.Sprintf("Hosts[%d]", i), "host should be map, but %s given", gettype(hostInt))
 		}
 
 		newHost := DBHost{
-			Probes:    probes,
-			Interval:  interval,
-			UpdateURL: url,
+			Probes:    topic.Probes,
+			Interval:  topic.Interval,
+			UpdateURL: topic.UpdateURL,
+			UpdateSecret: topic.UpdateSecret,
+			UpdateFormat: topic.UpdateFormat,
+			UpdateMethod: topic.UpdateMethod,
+			UpdateHeaders: topic.UpdateHeaders,
+			UpdateBody: topic.UpdateBody,
+			Notifiers: topic.Notifiers,
+			Labels: topic.Labels,
+			Resolve: topic.Resolve,
 		}
 		hostmap := hostInt.(map[string]interface{})
 
-		// parse ip
-		if ip, ok := hostmap["host"]; ok && gettype(ip) == StrString && net.ParseIP(ip.(string)) != nil {
-			newHost.IP = net.ParseIP(ip.(string))
+		// parse ip or dns name
+		host, _ := hostmap["host"].(string)
+		if ip := net.ParseIP(host); ip != nil {
+			newHost.IP = ip
+		} else if name, ok := HostKey(host); ok {
+			newHost.Name = name
 		} else {
-			return []*DBHost{}, fmt.Errorf("wrong 'host' parameter in host %d", i)
+			return []*DBHost{}, fieldError(fmt.Sprintf("Hosts[%d].host", i), "should be ip address or dns name")
+		}
+		// resolve mode of dns name
+		if resolveVal, ok := hostmap["Resolve"]; ok && gettype(resolveVal) == StrString {
+			if !isResolveMode(resolveVal.(string)) {
+				return []*DBHost{}, fieldError(fmt.Sprintf("Hosts[%d].Resolve", i), "unknown resolve mode '%s', known modes: %v", resolveVal.(string), ResolveModes)
+			}
+			newHost.Resolve = resolveVal.(string)
 		}
 
 		// parse `alive`
@@ -104,6 +213,13 @@ func ParseHosts(hosts []interface{}, probes int, interval int64, url string) ([]
 			newHost.Alive = false
 		}
 
+		// time of last state change
+		if changed, ok := hostmap["changed"]; ok && gettype(changed) == StrString {
+			if tm, err := time.Parse(time.RFC3339, changed.(string)); err == nil {
+				newHost.Changed = tm
+			}
+		}
+
 		// interval
 		if intVal, ok := hostmap["Interval"]; ok && gettype(intVal) == StrFloat64 {
 			newHost.Interval = int64(intVal.(float64))
@@ -116,6 +232,50 @@ func ParseHosts(hosts []interface{}, probes int, interval int64, url string) ([]
 		if urlVal, ok := hostmap["UpdateURL"]; ok && gettype(urlVal) == StrString {
 			newHost.UpdateURL = urlVal.(string)
 		}
+		// URL secret
+		if secretVal, ok := hostmap["UpdateSecret"]; ok && gettype(secretVal) == StrString {
+			newHost.UpdateSecret = secretVal.(string)
+		}
+		// URL payload format
+		if formatVal, ok := hostmap["UpdateFormat"]; ok && gettype(formatVal) == StrString {
+			if !notify.IsFormat(formatVal.(string)) {
+				return []*DBHost{}, fieldError(fmt.Sprintf("Hosts[%d].UpdateFormat", i), "unknown format '%s', known formats: %v", formatVal.(string), notify.Formats)
+			}
+			newHost.UpdateFormat = formatVal.(string)
+		}
+		// URL request templates
+		if methodVal, ok := hostmap["UpdateMethod"]; ok && gettype(methodVal) == StrString {
+			newHost.UpdateMethod = methodVal.(string)
+		}
+		if headersVal, ok := hostmap["UpdateHeaders"]; ok {
+			values, err := parseStringMap(headersVal)
+			if err != nil {
+				return []*DBHost{}, fieldError(fmt.Sprintf("Hosts[%d].UpdateHeaders", i), "%s", err.Error())
+			}
+			newHost.UpdateHeaders = values
+		}
+		if bodyVal, ok := hostmap["UpdateBody"]; ok && gettype(bodyVal) == StrString {
+			newHost.UpdateBody = bodyVal.(string)
+		}
+		if err := checkUpdateRequest(newHost.UpdateURL, newHost.WebhookOptions()); err != nil {
+			return []*DBHost{}, fieldError(fmt.Sprintf("Hosts[%d].UpdateURL", i), "%s", err.Error())
+		}
+		// Labels, host labels override topic ones
+		if labelsVal, ok := hostmap["Labels"]; ok {
+			values, err := parseStringMap(labelsVal)
+			if err != nil {
+				return []*DBHost{}, fieldError(fmt.Sprintf("Hosts[%d].Labels", i), "%s", err.Error())
+			}
+			newHost.Labels = mergeLabels(topic.Labels, values)
+		}
+		// Notifiers
+		if notifiers, ok := hostmap["Notifiers"]; ok {
+			names, err := parseNotifiers(notifiers, known)
+			if err != nil {
+				return []*DBHost{}, fieldError(fmt.Sprintf("Hosts[%d].Notifiers", i), "%s", err.Error())
+			}
+			newHost.Notifiers = names
+		}
 
 		newHosts = append(newHosts, &newHost)
 	}
@@ -123,6 +283,107 @@ func ParseHosts(hosts []interface{}, probes int, interval int64, url string) ([]
 	return newHosts, nil
 }
 
+// isResolveMode - mode is one of ResolveModes
+func isResolveMode(mode string) bool {
+	for _, known := range ResolveModes {
+		if mode == known {
+			return true
+		}
+	}
+	return false
+}
+
+// parseStrings - parse json slice of strings
+func parseStrings(value interface{}) ([]string, error) {
+	if gettype(value) != StrSlice {
+		return nil, fmt.Errorf("should be slice, but %s given", gettype(value))
+	}
+	result := make([]string, 0)
+	for n, v := range value.([]interface{}) {
+		if gettype(v) != StrString {
+			return nil, fmt.Errorf("element %d should be string, but %s given", n, gettype(v))
+		}
+		result = append(result, v.(string))
+	}
+	return result, nil
+}
+
+// parseNotifiers - list of notifier names, all of them should be known
+func parseNotifiers(value interface{}, known func(string) bool) ([]string, error) {
+	names, err := parseStrings(value)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if !known(name) {
+			return nil, fmt.Errorf("unknown notifier '%s'", name)
+		}
+	}
+	return names, nil
+}
+
+// parseStringMap - parse json object with string values
+func parseStringMap(value interface{}) (map[string]string, error) {
+	if gettype(value) != StrMap {
+		return nil, fmt.Errorf("should be map, but %s given", gettype(value))
+	}
+	result := make(map[string]string)
+	for k, v := range value.(map[string]interface{}) {
+		if gettype(v) != StrString {
+			return nil, fmt.Errorf("value of '%s' should be string, but %s given", k, gettype(v))
+		}
+		result[k] = v.(string)
+	}
+	return result, nil
+}
+
+// mergeLabels - copy of labels with overrides applied
+func mergeLabels(labels map[string]string, overrides map[string]string) map[string]string {
+	result := make(map[string]string)
+	for k, v := range labels {
+		result[k] = v
+	}
+	for k, v := range overrides {
+		result[k] = v
+	}
+	return result
+}
+
+// equalStringMaps - compare string maps
+func equalStringMaps(a map[string]string, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if bv, ok := b[k]; !ok || bv != v {
+			return false
+		}
+	}
+	return true
+}
+
+// checkUpdateRequest - check that update request templates can be parsed
+func checkUpdateRequest(url string, options notify.WebhookOptions) error {
+	if url == "" {
+		return nil
+	}
+	_, err := options.Request(url)
+	return err
+}
+
+// equalStrings - compare string slices
+func equalStrings(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for n := range a {
+		if a[n] != b[n] {
+			return false
+		}
+	}
+	return true
+}
+
 func gettype(variable interface{}) string {
 	switch v := variable.(type) {
 	default:
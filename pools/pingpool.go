@@ -1,9 +1,12 @@
 package pools
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net"
 	"pinger/logger"
+	"pinger/metrics"
 	"pinger/pinger"
 	"sync"
 	"time"
@@ -13,53 +16,92 @@ import (
 type Hostpool struct {
 	Hosts sync.Map
 	//Topics		*sync.Map
+	minResolve time.Duration // limits of dns re-resolution interval, TTL is used between them
+	maxResolve time.Duration
+	mx         sync.Mutex
 }
 
 // Host is strcut with all host parameters and channel
 type Host struct {
 	IP       net.IP
+	Name     string   // dns name; IP is nil and Addrs are pinged according to Resolve mode
+	Resolve  string
+	Addrs    []net.IP // addresses of Name, see resolve
 	Interval time.Duration
 	Probes   int
 	URL      string
-	Done     chan bool
+	Done     chan bool // closed by Stop
 	Ticker   *time.Ticker
 	Finished bool
+	Checked  time.Time
+	LastResult pinger.PingResult
 
+	resolveAt time.Time
+	stopOnce  sync.Once
+	stopped   chan bool // closed when Run returns, see Wait
 	Mx sync.Mutex
 }
 
 // Lock host mutex
 func (h *Host) Lock(args ...interface{}) {
-	logger.DebugLock("PingPool %s: lock %v", h.IP.String(), args)
+	logger.DebugLock("PingPool %s: lock %v", h.Key(), args)
 	//logger.DebugLock("PingPool %s: lock", h.IP.String(), args)
 	h.Mx.Lock()
 }
 
 // Unlock host mutex
 func (h *Host) Unlock(args ...interface{}) {
-	logger.DebugLock("PingPool %s: unlock %v", h.IP.String(), args)
+	logger.DebugLock("PingPool %s: unlock %v", h.Key(), args)
 	h.Mx.Unlock()
 }
 
 // PingPool is global Hostpool instance
-var PingPool Hostpool
+var PingPool = Hostpool{minResolve: DefaultMinResolve, maxResolve: DefaultMaxResolve}
+
+var _ = metrics.NewGaugeFunc("pinger_pingpool_hosts", "Hosts in PingPool", func() float64 {
+	hosts := 0
+	PingPool.Hosts.Range(func(_, _ interface{}) bool {
+		hosts++
+		return true
+	})
+	return float64(hosts)
+})
+
+// SetResolveLimits - change limits of dns re-resolution interval; used from next resolution of host names
+func (p *Hostpool) SetResolveLimits(min time.Duration, max time.Duration) {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	p.minResolve = min
+	p.maxResolve = max
+}
+
+// resolveLimits - limits of dns re-resolution interval, see SetResolveLimits
+func (p *Hostpool) resolveLimits() (time.Duration, time.Duration) {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	return p.minResolve, p.maxResolve
+}
 
 /*
-AddHost - adding host to pool with required parameters
+AddHost - adding host to pool with required parameters. ip can be dns name (see HostKey), it's resolved on first check
 */
-func (p *Hostpool) AddHost(ip string, probes int, interval int64, url string) error {
-	netip := net.ParseIP(ip)
-	if netip == nil {
-		return fmt.Errorf("Cannot parse ip '%s'", netip)
-	}
-
+func (p *Hostpool) AddHost(ip string, probes int, interval int64, url string, resolve string) error {
 	host := Host{
-		IP:       netip,
 		Probes:   probes,
 		Interval: (time.Duration(interval) * time.Second),
 		URL:      url,
+		Done:     make(chan bool), // before Run: host can be stopped before it's started
+		stopped:  make(chan bool),
 		Finished: false,
 	}
+	if netip := net.ParseIP(ip); netip != nil {
+		host.IP = netip
+	} else if name, ok := HostKey(ip); ok && name == ip {
+		host.Name = name
+		host.Resolve = resolve
+	} else {
+		return fmt.Errorf("Cannot parse ip '%s'", ip)
+	}
 
 	if old, found := p.Hosts.Load(ip); found {
 		// replace old host with new one
@@ -80,10 +122,12 @@ Run - Start monitoring process for current host
 */
 func (h *Host) Run() {
 	h.Lock("Run")
-	h.Done = make(chan bool)
-	h.Finished = false
 	h.Ticker = time.NewTicker(h.Interval)
 	h.Unlock("Run")
+	if h.Name != "" {
+		// addresses are known before the first check
+		h.resolve()
+	}
 
 	for {
 		select {
@@ -91,37 +135,56 @@ func (h *Host) Run() {
 		//	logger.Debug("-----default------")
 		//	time.Sleep(time.Second*1)
 		case <-h.Ticker.C:
-			// run ping
-			h.Lock("<- h.Ticker.C")
-			//logger.Debug("Pinging %s", h.IP.String())
-
-			if result, err := pinger.Pinger.Ping(h.IP, h.Probes); err != nil {
-				logger.Err("Failed to ping %s: %s", h.IP.String(), err.Error())
-			} else {
-				h.BroadcastResult(result)
-			}
-
-			h.Unlock("<- h.Ticker.C")
+			h.tick()
 		case <-h.Done:
 			// stop jobs
 			h.Lock("<- h.Done")
-			close(h.Done)
-			logger.Debug("Stopping timer for '%s'", h.IP.String())
+			logger.Debug("Stopping timer for '%s'", h.Key())
 			h.Finished = true
 			h.Ticker.Stop()
 			h.Unlock("<- h.Done")
+			close(h.stopped)
 			return
 		}
 	}
 }
 
+/*
+tick - check host and send result to topic hosts. Host is locked only during check: topic hosts are
+locked while result is applied, and DBPool.UpdateHost locks them before the Host
+*/
+func (h *Host) tick() {
+	// dns is asked without lock: it can take seconds
+	if h.resolveDue() {
+		h.resolve()
+	}
+	// run ping
+	h.Lock("tick")
+	//logger.Debug("Pinging %s", h.IP.String())
+
+	result, err := h.check()
+	if errors.Is(err, pinger.ErrStopping) {
+		logger.Debug("Skipping ping of %s: %s", h.Key(), err.Error())
+	} else if err != nil {
+		logger.Err("Failed to ping %s: %s", h.Key(), err.Error())
+	} else {
+		h.Checked = time.Now()
+		h.LastResult = *result
+	}
+	h.Unlock("tick")
+
+	if err == nil {
+		h.BroadcastResult(result)
+	}
+}
+
 /*
 Update - update pingpool host struct in memory
  */
-func (h *Host) Update(Interval int64, Probes int, URL string) {
+func (h *Host) Update(Interval int64, Probes int, URL string, Resolve string) {
 	h.Lock()
 	defer h.Unlock()
-	logger.Debug("Updating host %s", h.IP.String())
+	logger.Debug("Updating host %s", h.Key())
 	if h.Finished {
 		return
 	}
@@ -129,28 +192,76 @@ func (h *Host) Update(Interval int64, Probes int, URL string) {
 	if (time.Duration(Interval) * time.Second) < h.Interval {
 	// todo: check if tere is several DBHosts with this ip. If one - change interval anyway, if several - use smallest.
 		// Re-Add host, because we cannot update timer when it's in use
-		if err := PingPool.AddHost(h.IP.String(), h.Probes, Interval, h.URL); err != nil {
-			logger.Err("Host.Update: Cannot add host '%s' to PingPool: %s", h.IP.String(), err.Error())
+		if err := PingPool.AddHost(h.Key(), h.Probes, Interval, h.URL, Resolve); err != nil {
+			logger.Err("Host.Update: Cannot add host '%s' to PingPool: %s", h.Key(), err.Error())
 		}
 	} else {
 		h.Probes = Probes
 		h.URL = URL
+		h.Resolve = Resolve
 	}
 }
 
-// BroadcastResult - send result to all topic's host instances
+// BroadcastResult - send result (and addresses of dns name) to all topic's host instances; returns when all of them are updated.
+// Called without host lock, see tick
 func (h *Host) BroadcastResult(result *pinger.PingResult) {
+	var updated sync.WaitGroup
+	h.Lock("BroadcastResult")
+	addrs := h.Addrs
+	h.Unlock("BroadcastResult")
 	TopicPool.Topics.Range(func(_, topicInterface interface{}) bool {
-		if host, ok := topicInterface.(*Topic).Hosts.Load(h.IP.String()); ok {
-			go host.(*DBHost).Updated(*result)
+		if host, ok := topicInterface.(*Topic).Hosts.Load(h.Key()); ok {
+			updated.Add(1)
+			go func(host *DBHost) {
+				defer updated.Done()
+				if h.Name != "" {
+					host.Resolved(addrs)
+				}
+				host.Updated(*result)
+			}(host.(*DBHost))
 		}
 		return true
 	})
+	updated.Wait()
 }
 
-// Stop - stop monitoring for current host.
+// Stop - stop monitoring for current host; does not wait for running check (see Wait), can be called several times
 func (h *Host) Stop() {
-	if !h.Finished {
-		h.Done <- true
+	h.stopOnce.Do(func() {
+		close(h.Done)
+	})
+}
+
+// Wait - wait until monitoring is stopped: running check is finished and it's result is sent to topic hosts
+func (h *Host) Wait() {
+	<-h.stopped
+}
+
+/*
+StopAll - stop monitoring of all hosts on shutdown. Running checks are finished and their results
+are sent to topic hosts; returns ctx error if they are not finished before ctx is done
+*/
+func (p *Hostpool) StopAll(ctx context.Context) error {
+	var stopped sync.WaitGroup
+	p.Hosts.Range(func(_, v interface{}) bool {
+		stopped.Add(1)
+		go func(host *Host) {
+			defer stopped.Done()
+			host.Stop()
+			host.Wait()
+		}(v.(*Host))
+		return true
+	})
+
+	done := make(chan struct{})
+	go func() {
+		stopped.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
@@ -0,0 +1,94 @@
+package pools
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckWhileUpdate(t *testing.T) {
+	// name is not resolved and is not resolved during test: check returns dead result without ping
+	host := &Host{Name: "sw1.noc.test", Probes: 1, Interval: time.Hour, Done: make(chan bool), resolveAt: time.Now().Add(time.Hour)}
+	PingPool.Hosts.Store(host.Key(), host)
+	defer PingPool.Hosts.Delete(host.Key())
+
+	topic := &Topic{Name: "check-while-update", Probes: 1, Interval: 3600}
+	dbHost := &DBHost{Name: host.Key(), Topic: topic.Name, Probes: 1, Interval: 3600, Alive: true}
+	topic.Hosts.Store(dbHost.Key(), dbHost)
+	TopicPool.Topics.Store(topic.Name, topic)
+	defer TopicPool.Topics.Delete(topic.Name)
+
+	// topic host is changed by api while check is running: result waits for topic host
+	dbHost.Lock("test")
+	checked := make(chan bool)
+	go func() {
+		host.tick()
+		close(checked)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	// DBPool.UpdateHost updates PingPool host under topic host lock
+	updated := make(chan bool)
+	go func() {
+		host.Update(3600, 3, "", "")
+		close(updated)
+	}()
+	select {
+	case <-updated:
+	case <-time.After(5 * time.Second):
+		t.Fatal("host is not updated while check result is sent")
+	}
+	dbHost.Unlock("test")
+
+	select {
+	case <-checked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("check is not finished")
+	}
+	dbHost.Lock("test")
+	defer dbHost.Unlock("test")
+	if dbHost.Alive || len(dbHost.Recent) != 1 {
+		t.Errorf("result of check is not sent to topic host")
+	}
+	if host.Probes != 3 {
+		t.Errorf("host probes are %d after update, expected 3", host.Probes)
+	}
+}
+
+func TestStop(t *testing.T) {
+	tests := []struct {
+		name  string
+		stops int
+	}{
+		{"stop", 1},
+		{"stop twice", 2},
+		{"reload and shutdown", 3},
+	}
+	for _, test := range tests {
+		if err := PingPool.AddHost("10.0.0.9", 1, 3600, "", ""); err != nil {
+			t.Fatal(err)
+		}
+		value, _ := PingPool.Hosts.Load("10.0.0.9")
+		host := value.(*Host)
+		// host can be stopped before Run is started, and after it's returned
+		stopped := make(chan bool)
+		go func() {
+			for n := 0; n < test.stops; n++ {
+				host.Stop()
+			}
+			host.Wait()
+			host.Stop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: host is not stopped", test.name)
+		}
+		host.Lock("test")
+		if !host.Finished {
+			t.Errorf("%s: stopped host is not finished", test.name)
+		}
+		host.Unlock("test")
+		PingPool.Hosts.Delete("10.0.0.9")
+	}
+}
@@ -1,10 +1,16 @@
 package pools
 
 import (
+	"errors"
+	"strings"
 	"pinger/logger"
+	"pinger/metrics"
+	"pinger/notify"
+	"pinger/pinger"
 	"sync"
 	"encoding/json"
 	"io/ioutil"
+	"os"
 	"time"
 )
 
@@ -13,8 +19,11 @@ type DBPool struct {
 	Topics 			sync.Map
 	SavePath		string
 	SaveInterval	int64
+	degradedLoss	float64		// alive host with loss >= degradedLoss (percent) is degraded; 0 - disabled
+	degradedRtt		float64		// alive host with rtt >= degradedRtt (ms) is degraded; 0 - disabled
 
 	Mx     sync.Mutex
+	degradedMx	sync.Mutex
 }
 
 // Lock - lock pool mutex
@@ -32,6 +41,30 @@ func (p *DBPool) Unlock() {
 // TopicPool - single global instance of Topic pool
 var TopicPool = DBPool{}
 
+var saveDuration = metrics.NewHistogram("pinger_save_duration_seconds", "Duration of DBPool.Save()", nil)
+
+// SetDegraded - change degradation thresholds: loss in percent, rtt in ms; 0 - disabled
+func (p *DBPool) SetDegraded(loss float64, rtt float64) {
+	p.degradedMx.Lock()
+	defer p.degradedMx.Unlock()
+	p.degradedLoss = loss
+	p.degradedRtt = rtt
+}
+
+// IsDegraded - check ping result against degradation thresholds
+func (p *DBPool) IsDegraded(result pinger.PingResult) bool {
+	if !result.Alive {
+		return false
+	}
+	p.degradedMx.Lock()
+	loss, rtt := p.degradedLoss, p.degradedRtt
+	p.degradedMx.Unlock()
+	if loss > 0 && 100-result.SuccessPercent >= loss {
+		return true
+	}
+	return rtt > 0 && result.AvgRttMs >= rtt
+}
+
 /*
 StartSaver - if config savepath and saveInterval are set, start "saver" vorker for saving host states each N seconds
  */
@@ -71,11 +104,13 @@ func (p *DBPool) Init(savePath string, saveInterval int64, defaultProbes int, de
 			return
 		}
 
-		topics, err := ParseTopics(jsonParams, defaultProbes, defaultInterval)
+		// notifiers can be removed from config since hosts are saved: hosts are loaded, their updates are dropped with error
+		topics, err := parseTopics(jsonParams, defaultProbes, defaultInterval, anyNotifier)
 		if err != nil {
 			logger.Err("Cannot parse saved hosts: %s", err.Error())
 			return
 		}
+		loadStaticMarks(topics, jsonParams)
 
 		loadedTopics := 0
 		loadedHosts := 0
@@ -88,6 +123,10 @@ func (p *DBPool) Init(savePath string, saveInterval int64, defaultProbes int, de
 				return true
 			})
 			TopicPool.Topics.Store(topics[n].Name, topics[n])
+			topics[n].Hosts.Range(func (_, h interface{}) bool {
+				topics[n].startHost(h.(*DBHost))
+				return true
+			})
 		}
 		TopicPool.Unlock()
 
@@ -103,34 +142,24 @@ func (p *DBPool) Save() {
 	}
 	TopicPool.Lock()
 	defer TopicPool.Unlock()
+	started := time.Now()
 
 	topics := make(map[string]interface{})
 	TopicPool.Topics.Range(func (k, v interface{}) bool {
 		topic := v.(*Topic)
 
-		sTopic := make(map[string]interface{})
-		sTopic["Name"] = k.(string)
-		sTopic["Probes"] = topic.Probes
-		sTopic["Interval"] = topic.Interval
-		sTopic["UpdateURL"] = topic.UpdateURL
+		sTopic := topic.document()
+		// config marks are kept to reconcile config topics after restart; parser ignores them
+		if topic.Static {
+			sTopic["Static"] = true
+		}
 		hosts := make([]map[string]interface{}, 0)
-		v.(*Topic).Hosts.Range(func(hk, h interface{}) bool {
-			host := h.(*DBHost)
-			host.Lock("Save")
-			sHost := make(map[string]interface{})
-			sHost["host"] = host.IP.String()
-			if host.Probes != topic.Probes {
-				sHost["Probes"] = host.Probes
-			}
-			if host.Interval != topic.Interval {
-				sHost["Interval"] = host.Interval
-			}
-			if host.UpdateURL != topic.UpdateURL {
-				sHost["UpdateURL"] = host.UpdateURL
+		topic.Hosts.Range(func(hk, h interface{}) bool {
+			hDoc := h.(*DBHost).document(topic)
+			if h.(*DBHost).Static {
+				hDoc["static"] = true
 			}
-			sHost["alive"] = host.Alive
-			hosts = append(hosts, sHost)
-			host.Unlock("Save")
+			hosts = append(hosts, hDoc)
 			return true
 		})
 		sTopic["Hosts"] = hosts
@@ -144,12 +173,19 @@ func (p *DBPool) Save() {
 		return
 	}
 
-	err := ioutil.WriteFile(p.SavePath, bytes, 0644)
+	// hosts contain update secrets: file is written readable by owner only and replaced by rename,
+	// so permissions of file saved by old versions are changed too
+	tmp := p.SavePath + ".tmp"
+	err := ioutil.WriteFile(tmp, bytes, 0600)
+	if err == nil {
+		err = os.Rename(tmp, p.SavePath)
+	}
 	if err != nil {
 		logger.Err("Cannot save hosts: %s", err.Error())
 	} else {
 		logger.Debug("Hosts saved")
 	}
+	saveDuration.Observe(nil, time.Since(started).Seconds())
 }
 
 /*
@@ -158,9 +194,15 @@ Add new hosts and remove old ones
 Returns map[hostname]alive(bool)
 */
 func (p *DBPool) GetOrStore(topics []*Topic, removeOld bool) map[string]map[string]bool {
+	TopicPool.Lock()
+	defer TopicPool.Unlock()
+	return p.getOrStore(topics, removeOld)
+}
+
+// getOrStore - GetOrStore with pool lock held by caller
+func (p *DBPool) getOrStore(topics []*Topic, removeOld bool) map[string]map[string]bool {
 	returnTopics := make(map[string]map[string]bool)
 
-	TopicPool.Lock()
 	// loop over "new" topics
 	for _, newTopic := range topics {
 		// determine if newTopic already exists
@@ -171,8 +213,17 @@ func (p *DBPool) GetOrStore(topics []*Topic, removeOld bool) map[string]map[stri
 			topic := Topic{
 				Name:      newTopic.Name,
 				UpdateURL: newTopic.UpdateURL,
+				UpdateSecret: newTopic.UpdateSecret,
+				UpdateFormat: newTopic.UpdateFormat,
+				UpdateMethod: newTopic.UpdateMethod,
+				UpdateHeaders: newTopic.UpdateHeaders,
+				UpdateBody: newTopic.UpdateBody,
+				Labels:    newTopic.Labels,
 				Interval:  newTopic.Interval,
 				Probes:    newTopic.Probes,
+				Notifiers: newTopic.Notifiers,
+				Resolve:   newTopic.Resolve,
+				Static:    newTopic.Static,
 			}
 			TopicPool.Topics.Store(newTopic.Name, &topic)
 		}
@@ -182,12 +233,12 @@ func (p *DBPool) GetOrStore(topics []*Topic, removeOld bool) map[string]map[stri
 		returnTopics[newTopic.Name] = p.CompareTopic(newTopic, topic.(*Topic), removeOld)
 	}
 
-	TopicPool.Unlock()
 	return returnTopics
 }
 
 /*
-CompareTopic - compare topic contents; delete expired hosts; create new hosts
+CompareTopic - compare topic contents; delete expired hosts; create new hosts.
+Static (config) topic parameters and static hosts are changed only by static newTopic (see SetStatic)
 Return map[ip]bool(alive)
 */
 func (p *DBPool) CompareTopic(newTopic *Topic, oldTopic *Topic, removeOld bool) map[string]bool {
@@ -197,28 +248,50 @@ func (p *DBPool) CompareTopic(newTopic *Topic, oldTopic *Topic, removeOld bool)
 	oldTopic.Lock()
 	defer oldTopic.Unlock()
 
-	if oldTopic.UpdateURL != newTopic.UpdateURL {
-		oldTopic.UpdateURL = newTopic.UpdateURL
-	}
-	if oldTopic.Interval != newTopic.Interval {
-		oldTopic.Interval = newTopic.Interval
-	}
-	if oldTopic.Probes != newTopic.Probes {
-		oldTopic.Probes = newTopic.Probes
+	// API can only add own hosts to config topic
+	if !oldTopic.Static || newTopic.Static {
+		if oldTopic.UpdateURL != newTopic.UpdateURL {
+			oldTopic.UpdateURL = newTopic.UpdateURL
+		}
+		if oldTopic.UpdateSecret != newTopic.UpdateSecret {
+			oldTopic.UpdateSecret = newTopic.UpdateSecret
+		}
+		if oldTopic.UpdateFormat != newTopic.UpdateFormat {
+			oldTopic.UpdateFormat = newTopic.UpdateFormat
+		}
+		oldTopic.UpdateMethod = newTopic.UpdateMethod
+		oldTopic.UpdateHeaders = newTopic.UpdateHeaders
+		oldTopic.UpdateBody = newTopic.UpdateBody
+		if !equalStringMaps(oldTopic.Labels, newTopic.Labels) {
+			oldTopic.Labels = newTopic.Labels
+		}
+		if oldTopic.Interval != newTopic.Interval {
+			oldTopic.Interval = newTopic.Interval
+		}
+		if oldTopic.Probes != newTopic.Probes {
+			oldTopic.Probes = newTopic.Probes
+		}
+		if !equalStrings(oldTopic.Notifiers, newTopic.Notifiers) {
+			oldTopic.Notifiers = newTopic.Notifiers
+		}
+		oldTopic.Resolve = newTopic.Resolve
 	}
 
 	newTopic.Hosts.Range(func(key, newHost interface{}) bool {
 		oldHost, exist := oldTopic.Hosts.Load(key.(string))
-		if exist {
+		if exist && oldHost.(*DBHost).Static && !newTopic.Static {
+			// config host is not changed by API
+			topicHosts[oldHost.(*DBHost).Key()] = oldHost.(*DBHost).Alive
+		} else if exist {
 			// old host exist. update params (if needed);
 			// todo: store host results in some variable
-			topicHosts[oldHost.(*DBHost).IP.String()] = oldHost.(*DBHost).Alive
+			topicHosts[oldHost.(*DBHost).Key()] = oldHost.(*DBHost).Alive
 			p.UpdateHost(newHost.(*DBHost), oldHost.(*DBHost))
 		} else {
 			// There is no such host
 			// 1) add host to topic ; 2) add host to hostpool (if needed)
 			//p.AddHost(newHost.(*Host), oldTopic)
-			topicHosts[newHost.(*DBHost).IP.String()] = newHost.(*DBHost).Alive
+			topicHosts[newHost.(*DBHost).Key()] = newHost.(*DBHost).Alive
 			oldTopic.AddHost(newHost.(*DBHost))
 		}
 		return true
@@ -227,7 +300,7 @@ func (p *DBPool) CompareTopic(newTopic *Topic, oldTopic *Topic, removeOld bool)
 	// 2: loop over old topic and remove non-existing in newTopic hosts
 	if removeOld {
 		oldTopic.Hosts.Range(func(key, oldHost interface{}) bool {
-			if _, exist := newTopic.Hosts.Load(key.(string)); !exist {
+			if _, exist := newTopic.Hosts.Load(key.(string)); !exist && !oldHost.(*DBHost).Static {
 				// remove host from oldhosts; remove host from hostpool if no such host in other topics
 				oldTopic.RemoveHost(key.(string))
 			} else {
@@ -250,26 +323,275 @@ func (p *DBPool) UpdateHost(newHost *DBHost, oldHost *DBHost) {
 	oldHost.Lock("UpdateHost (oldHost)")
 
 	// todo: update interval only if 1) this host is in multiple topics AND new interval < old interval 2) this host is in only one topic
-	if newHost.Interval < oldHost.Interval || newHost.Probes != oldHost.Probes || newHost.UpdateURL != oldHost.UpdateURL || newHost.Alive != oldHost.Alive {
+	if newHost.Interval < oldHost.Interval || newHost.Probes != oldHost.Probes || newHost.UpdateURL != oldHost.UpdateURL || newHost.UpdateSecret != oldHost.UpdateSecret || newHost.UpdateFormat != oldHost.UpdateFormat ||
+		newHost.UpdateMethod != oldHost.UpdateMethod || newHost.UpdateBody != oldHost.UpdateBody || !equalStringMaps(newHost.UpdateHeaders, oldHost.UpdateHeaders) ||
+		!equalStringMaps(newHost.Labels, oldHost.Labels) || newHost.Alive != oldHost.Alive || !equalStrings(newHost.Notifiers, oldHost.Notifiers) ||
+		newHost.Resolve != oldHost.Resolve {
 		logger.Debug("updating oldHost")
 		oldHost.Interval = newHost.Interval
 		oldHost.Probes = newHost.Probes
 		oldHost.UpdateURL = newHost.UpdateURL
+		oldHost.UpdateSecret = newHost.UpdateSecret
+		oldHost.UpdateFormat = newHost.UpdateFormat
+		oldHost.UpdateMethod = newHost.UpdateMethod
+		oldHost.UpdateHeaders = newHost.UpdateHeaders
+		oldHost.UpdateBody = newHost.UpdateBody
+		oldHost.Labels = newHost.Labels
+		if oldHost.UpdateURL != "" {
+			notify.URLNotifier(oldHost.UpdateURL, oldHost.WebhookOptions())
+		}
+		oldHost.Notifiers = newHost.Notifiers
 		oldHost.Alive = newHost.Alive
+		oldHost.Resolve = newHost.Resolve
 
 		// find and update host in hostpool
-		hp, ok := PingPool.Hosts.Load(oldHost.IP.String())
+		hp, ok := PingPool.Hosts.Load(oldHost.Key())
 		if !ok {
 			// todo: something wrong, but anyway add host
-			if err := PingPool.AddHost(newHost.IP.String(), newHost.Probes, newHost.Interval, newHost.UpdateURL); err != nil {
-				logger.Err("DBPool.UpdateHost: Cannot add host '%s' to PingPool: %s", newHost.IP.String(), err.Error())
+			if err := PingPool.AddHost(newHost.Key(), newHost.Probes, newHost.Interval, newHost.UpdateURL, newHost.Resolve); err != nil {
+				logger.Err("DBPool.UpdateHost: Cannot add host '%s' to PingPool: %s", newHost.Key(), err.Error())
 			}
 		} else {
-			hp.(*Host).Update(oldHost.Interval, oldHost.Probes, oldHost.UpdateURL)
+			hp.(*Host).Update(oldHost.Interval, oldHost.Probes, oldHost.UpdateURL, oldHost.Resolve)
 		}
 	}
 
+	// API host becomes config host, when it's added to config
+	oldHost.Static = newHost.Static
+
 	oldHost.Unlock("UpdateHost (oldHost)")
 	newHost.Unlock("UpdateHost (newHost)")
 
 }
+
+// Errors of single topic and host operations
+var (
+	ErrTopicNotFound = errors.New("topic not found")
+	ErrHostNotFound  = errors.New("host not found in topic")
+	ErrStatic        = errors.New("declared in config file, cannot be changed by API")
+)
+
+/*
+StoreTopic - create or change single topic from json document (same format as topic in GetOrStore).
+With merge=false (PUT) topic parameters and hosts are replaced, hosts missing in document are removed.
+With merge=true (PATCH) given parameters are applied to existing topic, given hosts are added or changed,
+other hosts are kept and inherit new topic parameters.
+Host state (`alive`, `changed`) is kept if document does not set it. Config topic cannot be changed.
+Returns stored topic and true if topic is created.
+Pool is locked from load to store, so concurrent requests to the same topic don't lose each other's changes
+*/
+func (p *DBPool) StoreTopic(name string, params map[string]interface{}, merge bool, defProbes int, defInterval int64) (*Topic, bool, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	existing, exists := p.Topics.Load(name)
+	if merge && !exists {
+		return nil, false, ErrTopicNotFound
+	}
+	if exists && existing.(*Topic).Static {
+		return nil, false, ErrStatic
+	}
+
+	doc := params
+	if exists {
+		doc = existing.(*Topic).mergeDocument(params, merge)
+	}
+	topics, err := ParseTopics(map[string]interface{}{name: doc}, defProbes, defInterval)
+	if err != nil {
+		var fe *FieldError
+		if errors.As(err, &fe) {
+			fe.Field = strings.TrimPrefix(fe.Field, name+".")
+		}
+		return nil, false, err
+	}
+
+	p.getOrStore(topics, !merge)
+	topic, _ := p.Topics.Load(name)
+	return topic.(*Topic), !exists, nil
+}
+
+// mergeDocument - document for ParseTopics, which keeps state of existing hosts (and parameters with merge)
+func (t *Topic) mergeDocument(params map[string]interface{}, merge bool) map[string]interface{} {
+	t.Lock()
+	defer t.Unlock()
+
+	hosts := make(map[string]map[string]interface{})
+	t.Hosts.Range(func(key, h interface{}) bool {
+		hosts[key.(string)] = normalize(h.(*DBHost).document(t))
+		return true
+	})
+
+	doc := make(map[string]interface{})
+	if merge {
+		doc = normalize(t.document())
+	}
+	for k, v := range params {
+		if k != "Hosts" {
+			doc[k] = v
+		}
+	}
+
+	list := make([]interface{}, 0)
+	given := make(map[string]bool)
+	newHosts, _ := params["Hosts"].([]interface{})
+	for _, h := range newHosts {
+		hostMap, ok := h.(map[string]interface{})
+		ip, _ := hostMap["host"].(string)
+		if key, valid := HostKey(ip); valid {
+			ip = key
+		}
+		old, exists := hosts[ip]
+		if !ok || !exists {
+			// new or wrong host, ParseTopics will report errors
+			list = append(list, h)
+			continue
+		}
+		given[ip] = true
+		host := make(map[string]interface{})
+		if merge {
+			host = old
+		} else {
+			host["alive"], host["changed"] = old["alive"], old["changed"]
+		}
+		for k, v := range hostMap {
+			host[k] = v
+		}
+		list = append(list, host)
+	}
+	if merge {
+		for ip, host := range hosts {
+			if !given[ip] {
+				list = append(list, host)
+			}
+		}
+	}
+	doc["Hosts"] = list
+	return doc
+}
+
+/*
+RemoveTopic - remove topic and all of it's hosts (hosts are removed from PingPool if they are not in other topics).
+Config topic cannot be removed
+*/
+func (p *DBPool) RemoveTopic(name string) error {
+	p.Lock()
+	defer p.Unlock()
+	topic, ok := p.Topics.Load(name)
+	if !ok {
+		return ErrTopicNotFound
+	}
+	t := topic.(*Topic)
+	if t.Static {
+		return ErrStatic
+	}
+	t.Lock()
+	t.Hosts.Range(func(key, _ interface{}) bool {
+		t.RemoveHost(key.(string))
+		return true
+	})
+	t.Unlock()
+	p.Topics.Delete(name)
+	logger.Log("Topic '%s' removed", name)
+	return nil
+}
+
+/*
+StoreHost - add host to topic or change host parameters from json document (same format as host in topic Hosts).
+Missing parameters are inherited from topic (merge=false) or kept from existing host (merge=true).
+Host state is kept if document does not set it. Config host cannot be changed.
+Returns stored host and true if host is created
+*/
+func (p *DBPool) StoreHost(topicName string, ip string, params map[string]interface{}, merge bool) (*DBHost, bool, error) {
+	p.Lock()
+	defer p.Unlock()
+	topic, ok := p.Topics.Load(topicName)
+	if !ok {
+		return nil, false, ErrTopicNotFound
+	}
+	t := topic.(*Topic)
+	t.Lock()
+	defer t.Unlock()
+
+	doc := make(map[string]interface{})
+	old, exists := t.Hosts.Load(ip)
+	if exists && old.(*DBHost).Static {
+		return nil, false, ErrStatic
+	}
+	if exists {
+		oldDoc := normalize(old.(*DBHost).document(t))
+		if merge {
+			doc = oldDoc
+		} else {
+			doc["alive"], doc["changed"] = oldDoc["alive"], oldDoc["changed"]
+		}
+	} else if merge {
+		return nil, false, ErrHostNotFound
+	}
+	for k, v := range params {
+		doc[k] = v
+	}
+	doc["host"] = ip
+
+	hosts, err := ParseHosts([]interface{}{doc}, t)
+	if err != nil {
+		var fe *FieldError
+		if errors.As(err, &fe) {
+			fe.Field = strings.TrimPrefix(fe.Field, "Hosts[0].")
+		}
+		return nil, false, err
+	}
+
+	if exists {
+		p.UpdateHost(hosts[0], old.(*DBHost))
+		return old.(*DBHost), false, nil
+	}
+	t.AddHost(hosts[0])
+	return hosts[0], true, nil
+}
+
+// RemoveHostFromTopic - remove single host from topic; config host cannot be removed
+func (p *DBPool) RemoveHostFromTopic(topicName string, ip string) error {
+	p.Lock()
+	defer p.Unlock()
+	topic, ok := p.Topics.Load(topicName)
+	if !ok {
+		return ErrTopicNotFound
+	}
+	t := topic.(*Topic)
+	t.Lock()
+	defer t.Unlock()
+	host, ok := t.Hosts.Load(ip)
+	if !ok {
+		return ErrHostNotFound
+	}
+	if host.(*DBHost).Static {
+		return ErrStatic
+	}
+	t.RemoveHost(ip)
+	return nil
+}
+
+// normalize - convert document to the form of decoded json (numbers are float64 etc.), as parser expects
+func normalize(doc map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{})
+	if bytes, err := json.Marshal(doc); err == nil {
+		json.Unmarshal(bytes, &result)
+	}
+	return result
+}
+
+// loadStaticMarks - restore config marks of saved topics and hosts (see Save)
+func loadStaticMarks(topics []*Topic, docs map[string]interface{}) {
+	for _, topic := range topics {
+		doc, _ := docs[topic.Name].(map[string]interface{})
+		topic.Static, _ = doc["Static"].(bool)
+		hosts, _ := doc["Hosts"].([]interface{})
+		for _, h := range hosts {
+			hostDoc, _ := h.(map[string]interface{})
+			ip, _ := hostDoc["host"].(string)
+			if host, ok := topic.Hosts.Load(ip); ok {
+				host.(*DBHost).Static, _ = hostDoc["static"].(bool)
+			}
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package pools
+
+import (
+	"context"
+	"net"
+	"pinger/logger"
+	"pinger/metrics"
+	"pinger/notify"
+	"pinger/pinger"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Resolve modes of hosts given by dns name
+const (
+	ResolveFirst = "first" // ping the first address of dns answer
+	ResolveAny   = "any"   // addresses are pinged in order until one is alive; host is alive if any address is
+	ResolveAll   = "all"   // all addresses are pinged; host is alive if all of them are
+)
+
+// ResolveModes - known resolve modes, ResolveFirst is default
+var ResolveModes = []string{ResolveFirst, ResolveAny, ResolveAll}
+
+// Default limits of dns re-resolution interval, see Hostpool.SetResolveLimits
+const (
+	DefaultMinResolve = 30 * time.Second
+	DefaultMaxResolve = 300 * time.Second
+)
+
+var dnsResolves = metrics.NewCounter("pinger_dns_resolves_total", "Resolutions of host names by result: ok, changed or error")
+
+/*
+HostKey - key of host in topics and PingPool: ip as is, dns name is lowercased and trailing dot is removed.
+Returns false if host is neither ip nor dns name
+*/
+func HostKey(host string) (string, bool) {
+	if net.ParseIP(host) != nil {
+		return host, true
+	}
+	name := strings.TrimSuffix(strings.ToLower(host), ".")
+	if len(name) == 0 || len(name) > 253 {
+		return "", false
+	}
+	labels := strings.Split(name, ".")
+	for _, label := range labels {
+		if len(label) == 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return "", false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+				return "", false
+			}
+		}
+	}
+	// top level domain is not numeric, so wrong ips like 10.0.0.256 are not names
+	if strings.Trim(labels[len(labels)-1], "0123456789") == "" {
+		return "", false
+	}
+	return name, true
+}
+
+// Key - host key in topic and PingPool (see HostKey)
+func (h *Host) Key() string {
+	if h.Name != "" {
+		return h.Name
+	}
+	return h.IP.String()
+}
+
+// check - ping ip or addresses of host name according to resolve mode; called with host lock
+func (h *Host) check() (*pinger.PingResult, error) {
+	if h.Name == "" {
+		return pinger.Pinger.Ping(h.IP, h.Probes)
+	}
+	if len(h.Addrs) == 0 {
+		// name is not resolved yet: host is down
+		return &pinger.PingResult{}, nil
+	}
+
+	switch h.Resolve {
+	case ResolveAny:
+		var result *pinger.PingResult
+		var err error
+		for _, ip := range h.Addrs {
+			if result, err = pinger.Pinger.Ping(ip, h.Probes); err == nil && result.Alive {
+				break
+			}
+		}
+		return result, err
+	case ResolveAll:
+		results := make([]*pinger.PingResult, len(h.Addrs))
+		errs := make([]error, len(h.Addrs))
+		var pinged sync.WaitGroup
+		for n, ip := range h.Addrs {
+			pinged.Add(1)
+			go func(n int, ip net.IP) {
+				defer pinged.Done()
+				results[n], errs[n] = pinger.Pinger.Ping(ip, h.Probes)
+			}(n, ip)
+		}
+		pinged.Wait()
+		// the first dead address or the slowest one
+		var worst *pinger.PingResult
+		for n, result := range results {
+			if errs[n] != nil {
+				return nil, errs[n]
+			}
+			if worst == nil || worst.Alive && (!result.Alive || result.AvgRttNs > worst.AvgRttNs) {
+				worst = result
+			}
+		}
+		return worst, nil
+	}
+	return pinger.Pinger.Ping(h.Addrs[0], h.Probes)
+}
+
+// resolveDue - host is given by dns name and it's time to resolve it
+func (h *Host) resolveDue() bool {
+	h.Lock("resolveDue")
+	defer h.Unlock("resolveDue")
+	return h.Name != "" && !time.Now().Before(h.resolveAt)
+}
+
+/*
+resolve - get addresses of host name. Next resolution is after TTL, limited by PingPool resolve limits
+(maximum if TTL is unknown). Last addresses are kept when name cannot be resolved.
+Called without host lock: dns is asked unlocked, then addresses are changed under lock
+*/
+func (h *Host) resolve() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	addrs, ttl, err := pinger.Resolve(ctx, h.Name)
+	min, max := PingPool.resolveLimits()
+
+	h.Lock("resolve")
+	defer h.Unlock("resolve")
+	if err != nil {
+		dnsResolves.Inc(metrics.Labels{"result": "error"})
+		logger.Err("Cannot resolve '%s': %s", h.Name, err.Error())
+		h.resolveAt = time.Now().Add(min)
+		return
+	}
+
+	next := max
+	if ttl > 0 && ttl < next {
+		next = ttl
+	}
+	if next < min {
+		next = min
+	}
+	h.resolveAt = time.Now().Add(next)
+
+	if sameAddresses(h.Addrs, addrs) {
+		dnsResolves.Inc(metrics.Labels{"result": "ok"})
+		return
+	}
+	dnsResolves.Inc(metrics.Labels{"result": "changed"})
+	if len(h.Addrs) > 0 {
+		logger.Log("Host '%s' is resolved to %s, was %s", h.Name, joinAddresses(addrs), joinAddresses(h.Addrs))
+	} else {
+		logger.Debug("Host '%s' is resolved to %s, next resolution in %s", h.Name, joinAddresses(addrs), next)
+	}
+	h.Addrs = addrs
+}
+
+/*
+Resolved - set addresses of topic host given by dns name (see Host.resolve).
+Change of known addresses is published as notify.KindResolve event
+*/
+func (h *DBHost) Resolved(addrs []net.IP) {
+	h.Lock("Resolved")
+	defer h.Unlock("Resolved")
+	if len(addrs) == 0 || sameAddresses(h.Addresses, addrs) {
+		return
+	}
+	previous := h.Addresses
+	h.Addresses = addrs
+	h.IP = addrs[0]
+	if len(previous) == 0 {
+		return
+	}
+	notify.Events.Publish(notify.KindResolve, notify.Event{
+		Topic:             h.Topic,
+		Host:              h.Key(),
+		State:             notify.StateOf(h.Alive, h.Degraded),
+		Alive:             h.Alive,
+		Time:              time.Now(),
+		Labels:            h.Labels,
+		Addresses:         addressStrings(addrs),
+		PreviousAddresses: addressStrings(previous),
+	})
+}
+
+// sameAddresses - both lists have the same addresses, order is not important (dns round robin)
+func sameAddresses(a []net.IP, b []net.IP) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sa, sb := addressStrings(a), addressStrings(b)
+	sort.Strings(sa)
+	sort.Strings(sb)
+	return equalStrings(sa, sb)
+}
+
+func addressStrings(addrs []net.IP) []string {
+	result := make([]string, 0, len(addrs))
+	for _, ip := range addrs {
+		result = append(result, ip.String())
+	}
+	return result
+}
+
+func joinAddresses(addrs []net.IP) string {
+	return strings.Join(addressStrings(addrs), ",")
+}
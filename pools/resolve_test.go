@@ -0,0 +1,40 @@
+package pools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHostKey(t *testing.T) {
+	tests := []struct {
+		host string
+		key  string // empty if host is neither ip nor dns name
+	}{
+		{"10.10.10.1", "10.10.10.1"},
+		{"2001:db8::1", "2001:db8::1"},
+		{"sw1.noc.local", "sw1.noc.local"},
+		{"SW1.Noc.Local", "sw1.noc.local"},
+		{"sw1.noc.local.", "sw1.noc.local"},
+		{"localhost", "localhost"},
+		{"_srv.noc.local", "_srv.noc.local"},
+		{"core-1.noc.local", "core-1.noc.local"},
+		{"10.0.0.256", ""},
+		{"10.0.0", ""},
+		{"", ""},
+		{".", ""},
+		{"sw1..noc.local", ""},
+		{"-sw1.noc.local", ""},
+		{"sw1-.noc.local", ""},
+		{"sw 1.noc.local", ""},
+		{"sw1.noc.local/24", ""},
+		{strings.Repeat("a", 64) + ".local", ""},
+		{strings.Repeat("a", 63) + ".local", strings.Repeat("a", 63) + ".local"},
+		{strings.Repeat("a.", 127) + "local", ""},
+	}
+	for _, test := range tests {
+		key, ok := HostKey(test.host)
+		if ok != (test.key != "") || key != test.key {
+			t.Errorf("'%s': key is '%s' (%v), expected '%s'", test.host, key, ok, test.key)
+		}
+	}
+}
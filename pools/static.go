@@ -0,0 +1,105 @@
+package pools
+
+import (
+	"pinger/logger"
+)
+
+/*
+CheckStatic - validate topics declared in config file the same way SetStatic does, pool is not changed.
+Topics and hosts can use only notifiers with given names ([notifiers] of the same config).
+Returns *FieldError with path like `core.Hosts[1].Notifiers`
+*/
+func CheckStatic(docs map[string]interface{}, defProbes int, defInterval int64, notifiers []string) error {
+	known := make(map[string]bool)
+	for _, name := range notifiers {
+		known[name] = true
+	}
+	_, err := parseTopics(normalize(docs), defProbes, defInterval, func(name string) bool {
+		return known[name]
+	})
+	return err
+}
+
+/*
+SetStatic - reconcile topics declared in config file: name => topic document (same format as in /get-or-store).
+Called on start after saved hosts are loaded and on config reload.
+Config topics and their hosts are static: API cannot remove them or change their parameters, but can add own hosts
+to config topic. Static hosts removed from config are removed from topic; topic removed from config becomes usual
+API topic, or is removed if it has no API hosts. Host state is kept. Nothing is changed if documents are invalid
+*/
+func (p *DBPool) SetStatic(docs map[string]interface{}, defProbes int, defInterval int64) error {
+	docs = normalize(docs)
+	for name, doc := range docs {
+		// keep state of existing hosts; wrong documents are reported by ParseTopics
+		if params, ok := doc.(map[string]interface{}); ok {
+			if existing, ok := p.Topics.Load(name); ok {
+				docs[name] = existing.(*Topic).mergeDocument(params, false)
+			}
+		}
+	}
+	topics, err := ParseTopics(docs, defProbes, defInterval)
+	if err != nil {
+		return err
+	}
+
+	p.Lock()
+	defer p.Unlock()
+	declared := make(map[string]*Topic)
+	for _, topic := range topics {
+		declared[topic.Name] = topic
+		topic.Static = true
+		topic.Hosts.Range(func(_, h interface{}) bool {
+			h.(*DBHost).Static = true
+			return true
+		})
+
+		existing, ok := p.Topics.Load(topic.Name)
+		if !ok {
+			existing = &Topic{Name: topic.Name, Static: true}
+			p.Topics.Store(topic.Name, existing)
+		}
+		p.CompareTopic(topic, existing.(*Topic), false)
+		existing.(*Topic).Lock()
+		existing.(*Topic).Static = true
+		existing.(*Topic).Unlock()
+	}
+
+	// hosts and topics, which are not in config anymore
+	p.Topics.Range(func(k, v interface{}) bool {
+		name, topic := k.(string), v.(*Topic)
+		newTopic := declared[name]
+		topic.Lock()
+		defer topic.Unlock()
+		topic.Hosts.Range(func(key, h interface{}) bool {
+			if !h.(*DBHost).Static {
+				return true
+			}
+			if newTopic != nil {
+				if _, ok := newTopic.Hosts.Load(key); ok {
+					return true
+				}
+			}
+			topic.RemoveHost(key.(string))
+			return true
+		})
+		if newTopic != nil || !topic.Static {
+			return true
+		}
+
+		topic.Static = false
+		empty := true
+		topic.Hosts.Range(func(_, _ interface{}) bool {
+			empty = false
+			return false
+		})
+		if empty {
+			p.Topics.Delete(name)
+			logger.Log("Topic '%s' is removed from config", name)
+		} else {
+			logger.Log("Topic '%s' is removed from config, hosts added by API are kept", name)
+		}
+		return true
+	})
+	logger.Log("Loaded %d topics from config", len(topics))
+	return nil
+}
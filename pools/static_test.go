@@ -0,0 +1,159 @@
+package pools
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// hostsDoc - topic document with given hosts; hosts are checked in an hour, so they are not pinged by test
+func hostsDoc(params map[string]interface{}, hosts ...string) map[string]interface{} {
+	doc := map[string]interface{}{"Interval": 3600}
+	for k, v := range params {
+		doc[k] = v
+	}
+	list := make([]interface{}, 0)
+	for _, host := range hosts {
+		list = append(list, map[string]interface{}{"host": host})
+	}
+	doc["Hosts"] = list
+	return doc
+}
+
+// topicState - "static" or "api" mark of topic and it's hosts, like `static: 10.0.0.1(static) 10.0.0.3(api)`
+func topicState(name string) string {
+	value, ok := TopicPool.Topics.Load(name)
+	if !ok {
+		return "missing"
+	}
+	topic := value.(*Topic)
+	mark := func(static bool) string {
+		if static {
+			return "static"
+		}
+		return "api"
+	}
+	hosts := make([]string, 0)
+	topic.Hosts.Range(func(k, v interface{}) bool {
+		hosts = append(hosts, k.(string)+"("+mark(v.(*DBHost).Static)+")")
+		return true
+	})
+	sort.Strings(hosts)
+	return strings.TrimSpace(mark(topic.Static) + ": " + strings.Join(hosts, " "))
+}
+
+func TestSetStatic(t *testing.T) {
+	defer TopicPool.SetStatic(nil, 3, 3600)
+
+	steps := []struct {
+		name   string
+		docs   map[string]interface{}
+		api    func() error // API request after config is applied; expected error is checked with errors.Is
+		apiErr error
+		topics map[string]string
+	}{
+		{
+			name:   "declared",
+			docs:   map[string]interface{}{"core": hostsDoc(nil, "10.0.0.1", "10.0.0.2")},
+			topics: map[string]string{"core": "static: 10.0.0.1(static) 10.0.0.2(static)"},
+		},
+		{
+			name: "api host in config topic",
+			docs: map[string]interface{}{"core": hostsDoc(nil, "10.0.0.1", "10.0.0.2")},
+			api: func() error {
+				_, _, err := TopicPool.StoreHost("core", "10.0.0.3", map[string]interface{}{}, false)
+				return err
+			},
+			topics: map[string]string{"core": "static: 10.0.0.1(static) 10.0.0.2(static) 10.0.0.3(api)"},
+		},
+		{
+			name: "api cannot change config host",
+			docs: map[string]interface{}{"core": hostsDoc(nil, "10.0.0.1", "10.0.0.2")},
+			api: func() error {
+				_, _, err := TopicPool.StoreHost("core", "10.0.0.1", map[string]interface{}{"Probes": 5.0}, true)
+				return err
+			},
+			apiErr: ErrStatic,
+			topics: map[string]string{"core": "static: 10.0.0.1(static) 10.0.0.2(static) 10.0.0.3(api)"},
+		},
+		{
+			name: "api cannot change config topic",
+			docs: map[string]interface{}{"core": hostsDoc(nil, "10.0.0.1", "10.0.0.2")},
+			api: func() error {
+				_, _, err := TopicPool.StoreTopic("core", map[string]interface{}{"Probes": 5.0}, true, 3, 3600)
+				return err
+			},
+			apiErr: ErrStatic,
+			topics: map[string]string{"core": "static: 10.0.0.1(static) 10.0.0.2(static) 10.0.0.3(api)"},
+		},
+		{
+			name:   "host removed from config",
+			docs:   map[string]interface{}{"core": hostsDoc(map[string]interface{}{"Probes": 5}, "10.0.0.1")},
+			topics: map[string]string{"core": "static: 10.0.0.1(static) 10.0.0.3(api)"},
+		},
+		{
+			name: "new config topic",
+			docs: map[string]interface{}{
+				"core": hostsDoc(nil, "10.0.0.1"),
+				"edge": hostsDoc(nil, "10.0.1.1"),
+			},
+			topics: map[string]string{
+				"core": "static: 10.0.0.1(static) 10.0.0.3(api)",
+				"edge": "static: 10.0.1.1(static)",
+			},
+		},
+		{
+			name: "topics removed from config",
+			docs: map[string]interface{}{},
+			topics: map[string]string{
+				"core": "api: 10.0.0.3(api)",
+				"edge": "missing",
+			},
+		},
+		{
+			name: "api topic declared in config",
+			docs: map[string]interface{}{"core": hostsDoc(nil, "10.0.0.3", "10.0.0.4")},
+			topics: map[string]string{
+				"core": "static: 10.0.0.3(static) 10.0.0.4(static)",
+			},
+		},
+	}
+	for _, step := range steps {
+		if err := TopicPool.SetStatic(step.docs, 3, 3600); err != nil {
+			t.Fatalf("%s: %s", step.name, err.Error())
+		}
+		if step.api != nil {
+			if err := step.api(); !errors.Is(err, step.apiErr) {
+				t.Errorf("%s: api error is %v, expected %v", step.name, err, step.apiErr)
+			}
+		}
+		for name, expected := range step.topics {
+			if state := topicState(name); state != expected {
+				t.Errorf("%s: topic '%s' is '%s', expected '%s'", step.name, name, state, expected)
+			}
+		}
+	}
+
+	// host parameters are changed, state is kept
+	value, _ := TopicPool.Topics.Load("core")
+	host, _ := value.(*Topic).Hosts.Load("10.0.0.3")
+	host.(*DBHost).Alive = true
+	if err := TopicPool.SetStatic(map[string]interface{}{"core": hostsDoc(map[string]interface{}{"Probes": 7}, "10.0.0.3")}, 3, 3600); err != nil {
+		t.Fatal(err)
+	}
+	host, _ = value.(*Topic).Hosts.Load("10.0.0.3")
+	if h := host.(*DBHost); !h.Alive || h.Probes != 7 {
+		t.Errorf("host after reload: alive %v, probes %d; expected alive with 7 probes", h.Alive, h.Probes)
+	}
+
+	// invalid documents don't change topics
+	err := TopicPool.SetStatic(map[string]interface{}{"core": hostsDoc(map[string]interface{}{"UpdateFormat": "v2"}, "10.0.0.5")}, 3, 3600)
+	var fe *FieldError
+	if !errors.As(err, &fe) || fe.Field != "core.UpdateFormat" {
+		t.Errorf("error of invalid document is %v", err)
+	}
+	if state := topicState("core"); state != "static: 10.0.0.3(static)" {
+		t.Errorf("topic is changed by invalid document: '%s'", state)
+	}
+}
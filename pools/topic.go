@@ -1,8 +1,12 @@
 package pools
 
 import (
+	"pinger/history"
 	"pinger/logger"
+	"pinger/notify"
+	"sort"
 	"sync"
+	"time"
 )
 
 /*
@@ -13,6 +17,15 @@ type Topic struct {
 	Probes    int
 	Interval  int64
 	UpdateURL string
+	UpdateSecret string
+	UpdateFormat string
+	UpdateMethod string
+	UpdateHeaders map[string]string
+	UpdateBody string
+	Notifiers []string
+	Labels    map[string]string
+	Resolve   string		// resolve mode of hosts given by dns name
+	Static    bool		// declared in config file, see DBPool.SetStatic
 	Mx        sync.Mutex
 	Hosts     sync.Map
 }
@@ -29,24 +42,53 @@ func (t *Topic) Unlock() {
 	t.Mx.Unlock()
 }
 
+// webhookOptions - options for topic's UpdateURL webhook
+func (t *Topic) webhookOptions() notify.WebhookOptions {
+	return notify.WebhookOptions{
+		Secret:  t.UpdateSecret,
+		Format:  t.UpdateFormat,
+		Method:  t.UpdateMethod,
+		Headers: t.UpdateHeaders,
+		Body:    t.UpdateBody,
+	}
+}
+
 /*
 AddHost - adding host to topic
 todo: check if host is alive in hostpool?
 */
 func (t *Topic) AddHost(host *DBHost) {
-	logger.Debug("Adding host %s to topic %s", host.IP.String(), t.Name)
+	logger.Debug("Adding host %s to topic %s", host.Key(), t.Name)
 
-	t.Hosts.Store(host.IP.String(), host)
+	t.storeHost(host)
+	t.startHost(host)
+}
+
+// storeHost - put host into topic without side effects; parsed topics are only built with it (see ParseTopics)
+func (t *Topic) storeHost(host *DBHost) {
+	host.Topic = t.Name
+	if host.Changed.IsZero() {
+		host.Changed = time.Now()
+	}
+	t.Hosts.Store(host.Key(), host)
+}
+
+// startHost - register update url webhook of topic host, record it's state in journal and add it to PingPool
+func (t *Topic) startHost(host *DBHost) {
+	if host.UpdateURL != "" {
+		notify.URLNotifier(host.UpdateURL, host.WebhookOptions())
+	}
+	history.Journal.Record(t.Name, host.Key(), history.StateEvent(host.Alive), time.Now())
 	// add host to hostpool if it doesnt exist there
-	if hp, ok := PingPool.Hosts.Load(host.IP.String()); !ok {
-		if err := PingPool.AddHost(host.IP.String(), host.Probes, host.Interval, host.UpdateURL); err != nil {
-			logger.Err("Topic.AddHost: Cannot add host '%s' to PingPool: %s", host.IP.String(), err.Error())
+	if hp, ok := PingPool.Hosts.Load(host.Key()); !ok {
+		if err := PingPool.AddHost(host.Key(), host.Probes, host.Interval, host.UpdateURL, host.Resolve); err != nil {
+			logger.Err("Topic.AddHost: Cannot add host '%s' to PingPool: %s", host.Key(), err.Error())
 		}
 		//time.Sleep(10 * time.Millisecond)
 	} else {
 		HP := hp.(*Host)
-		if int64(HP.Interval) > host.Interval || HP.Probes != host.Probes || HP.URL != host.UpdateURL {
-			HP.Update(host.Interval, host.Probes, host.UpdateURL)
+		if int64(HP.Interval) > host.Interval || HP.Probes != host.Probes || HP.URL != host.UpdateURL || HP.Resolve != host.Resolve {
+			HP.Update(host.Interval, host.Probes, host.UpdateURL, host.Resolve)
 		}
 	}
 }
@@ -58,7 +100,11 @@ Check for existance of this host in other topics. If not exist - remove topic ho
 func (t *Topic) RemoveHost(key string) {
 	logger.Debug("Removing host %s from topic %s", key, t.Name)
 
+	if host, ok := t.Hosts.Load(key); ok {
+		host.(*DBHost).DeleteMetrics()
+	}
 	t.Hosts.Delete(key)
+	history.Journal.Record(t.Name, key, history.EventRemoved, time.Now())
 	// check this host in other topics
 	found := false
 	TopicPool.Topics.Range(func(topicName, topic interface{}) bool {
@@ -83,3 +129,55 @@ func (t *Topic) RemoveHost(key string) {
 		}
 	}
 }
+
+// document - topic parameters in saved/api json format, without hosts
+func (t *Topic) document() map[string]interface{} {
+	doc := make(map[string]interface{})
+	doc["Name"] = t.Name
+	doc["Probes"] = t.Probes
+	doc["Interval"] = t.Interval
+	doc["UpdateURL"] = t.UpdateURL
+	if t.UpdateSecret != "" {
+		doc["UpdateSecret"] = t.UpdateSecret
+	}
+	if t.UpdateFormat != "" {
+		doc["UpdateFormat"] = t.UpdateFormat
+	}
+	if t.UpdateMethod != "" {
+		doc["UpdateMethod"] = t.UpdateMethod
+	}
+	if len(t.UpdateHeaders) > 0 {
+		doc["UpdateHeaders"] = t.UpdateHeaders
+	}
+	if t.UpdateBody != "" {
+		doc["UpdateBody"] = t.UpdateBody
+	}
+	if len(t.Labels) > 0 {
+		doc["Labels"] = t.Labels
+	}
+	if len(t.Notifiers) > 0 {
+		doc["Notifiers"] = t.Notifiers
+	}
+	if t.Resolve != "" {
+		doc["Resolve"] = t.Resolve
+	}
+	return doc
+}
+
+/*
+Document - topic with hosts in json format of StoreTopic, including update secrets and host state,
+so it can be stored back without changes (ctl export / import)
+*/
+func (t *Topic) Document() map[string]interface{} {
+	t.Lock()
+	defer t.Unlock()
+	doc := t.document()
+	hosts := make([]map[string]interface{}, 0)
+	t.Hosts.Range(func(_, h interface{}) bool {
+		hosts = append(hosts, h.(*DBHost).document(t))
+		return true
+	})
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i]["host"].(string) < hosts[j]["host"].(string) })
+	doc["Hosts"] = hosts
+	return doc
+}
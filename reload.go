@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"pinger/auth"
+	"pinger/ccfg"
+	"pinger/history"
+	"pinger/logger"
+	"pinger/notify"
+	"pinger/pinger"
+	"pinger/pools"
+	"pinger/web"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var cfgMx sync.Mutex
+
+// config - current config; replaced on reload
+func config() *ccfg.Cfg {
+	cfgMx.Lock()
+	defer cfgMx.Unlock()
+	return cfg
+}
+
+/*
+ReloadResult - result of config reload: changed settings, which are applied,
+changed settings, which need restart (listeners, storage paths), and settings failed to apply (previous values are kept)
+*/
+type ReloadResult struct {
+	OK              bool              `json:"ok"`
+	Applied         []string          `json:"applied"`
+	RestartRequired []string          `json:"restartRequired"`
+	Failed          map[string]string `json:"failed,omitempty"`
+}
+
+// reloader - re-reads config file on SIGHUP or POST /admin/reload and applies live settings
+type reloader struct {
+	path    string
+	started *ccfg.Cfg // config daemon is started with: restart settings are compared with it
+	web     *web.Params
+	tls     *auth.TLSReloader // nil without ssl
+	mx      sync.Mutex
+}
+
+/*
+liveSetting - how to apply group of live settings; apply is called once if any of keys is changed.
+Always groups are applied on every reload: log file is reopened (logrotate), token file is re-read
+*/
+type liveSetting struct {
+	keys   []string
+	always bool
+	apply  func(r *reloader, c *ccfg.Cfg) error
+}
+
+var liveSettings = []liveSetting{
+	{[]string{"log.path", "log.debug"}, true, func(r *reloader, c *ccfg.Cfg) error {
+		if err := logger.OpenPath(c.LogPath); err != nil {
+			return err
+		}
+		logger.SetDebug(c.LogDebug)
+		return nil
+	}},
+	{[]string{"listen.cert", "listen.key", "listen.client-ca", "listen.client-auth"}, false, func(r *reloader, c *ccfg.Cfg) error {
+		if r.tls == nil {
+			// ssl is disabled, files are not used
+			return nil
+		}
+		return r.tls.Update(c.SslCert, c.SslKey, c.SslClientCA, c.SslClientAuth)
+	}},
+	{[]string{"pinger.result-url", "pinger.result-method", "pinger.result-headers", "pinger.result-body", "pinger.result-secret"}, false, func(r *reloader, c *ccfg.Cfg) error {
+		r.web.SetDefaultURL(c.ResultURL)
+		return pinger.Pinger.SetResultRequest(c.ResultMethod, c.ResultURL, c.ResultHeaders, c.ResultBody, c.ResultSecret)
+	}},
+	{[]string{"pinger.update-secret", "pinger.updates-interval", "pinger.notify-retry-max", "pinger.notify-spool-path"}, false, func(r *reloader, c *ccfg.Cfg) error {
+		notify.SetDefaultSecret(c.UpdateSecret)
+		notify.Buffer.SetOptions(c.UpdatesInterval, c.NotifyRetryMax, c.NotifySpoolPath)
+		return nil
+	}},
+	{[]string{"notifiers"}, false, func(r *reloader, c *ccfg.Cfg) error {
+		return notify.Configure(c.Notifiers)
+	}},
+	{[]string{"topics"}, false, func(r *reloader, c *ccfg.Cfg) error {
+		return pools.TopicPool.SetStatic(c.Topics, c.DefaultProbes, c.DefaultInterval)
+	}},
+	{[]string{"pinger.default-probes", "pinger.default-interval"}, false, func(r *reloader, c *ccfg.Cfg) error {
+		r.web.SetDefaults(c.DefaultProbes, c.DefaultInterval)
+		return nil
+	}},
+	{[]string{"pinger.degraded-loss", "pinger.degraded-rtt"}, false, func(r *reloader, c *ccfg.Cfg) error {
+		pools.TopicPool.SetDegraded(c.DegradedLoss, c.DegradedRtt)
+		return nil
+	}},
+	{[]string{"pinger.dns-min-interval", "pinger.dns-max-interval"}, false, func(r *reloader, c *ccfg.Cfg) error {
+		pools.PingPool.SetResolveLimits(time.Duration(c.DNSMinInterval)*time.Second, time.Duration(c.DNSMaxInterval)*time.Second)
+		return nil
+	}},
+	{[]string{"pinger.event-log-size"}, false, func(r *reloader, c *ccfg.Cfg) error {
+		notify.Events.SetLogSize(c.EventLogSize)
+		return nil
+	}},
+	{[]string{"pinger.shutdown-timeout"}, false, func(r *reloader, c *ccfg.Cfg) error {
+		// read from current config on shutdown
+		return nil
+	}},
+	{[]string{"sla.retention-days"}, false, func(r *reloader, c *ccfg.Cfg) error {
+		history.Journal.SetRetention(c.HistoryRetention)
+		return nil
+	}},
+	{[]string{"sla.maintenance"}, false, func(r *reloader, c *ccfg.Cfg) error {
+		r.web.SetMaintenance(c.Maintenance)
+		return nil
+	}},
+	{[]string{"listen.allowed-origins"}, false, func(r *reloader, c *ccfg.Cfg) error {
+		r.web.SetAllowedOrigins(c.AllowedOrigins)
+		return nil
+	}},
+	{[]string{"auth.tokens", "auth.certs", "auth.token-file"}, true, func(r *reloader, c *ccfg.Cfg) error {
+		return auth.Init(c.Tokens, c.Certs, c.TokenFile)
+	}},
+}
+
+/*
+Reload - read config file and apply changed live settings. Returns error if config cannot be read,
+nothing is changed in this case. If some settings are failed, current config is kept: next reload applies all changes again
+*/
+func (r *reloader) Reload() (*ReloadResult, error) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	next, err := ccfg.Load(r.path)
+	if err != nil {
+		return nil, err
+	}
+	live, _ := ccfg.Diff(config(), next)
+	_, restart := ccfg.Diff(r.started, next)
+	changed := make(map[string]bool)
+	for _, key := range live {
+		changed[key] = true
+	}
+
+	result := &ReloadResult{OK: true, Applied: []string{}, RestartRequired: []string{}, Failed: make(map[string]string)}
+	result.RestartRequired = append(result.RestartRequired, restart...)
+	for _, s := range liveSettings {
+		keys := make([]string, 0)
+		for _, key := range s.keys {
+			if changed[key] {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) == 0 && !s.always {
+			continue
+		}
+		if err := s.apply(r, next); err != nil {
+			result.OK = false
+			if len(keys) == 0 {
+				keys = s.keys
+			}
+			for _, key := range keys {
+				result.Failed[key] = err.Error()
+			}
+			continue
+		}
+		result.Applied = append(result.Applied, keys...)
+	}
+
+	if result.OK {
+		cfgMx.Lock()
+		cfg = next
+		cfgMx.Unlock()
+	}
+	return result, nil
+}
+
+// HandleSignals - reload config on SIGHUP. Never returns
+func (r *reloader) HandleSignals() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	for range signals {
+		logger.Log("[reload]: SIGHUP received, reloading '%s'", r.path)
+		result, err := r.Reload()
+		if err != nil {
+			logger.Err("[reload]: config is not changed: %s", err.Error())
+			continue
+		}
+		logResult(result)
+	}
+}
+
+/*
+Handler - POST /admin/reload: reload config, response is ReloadResult.
+Config read errors are returned as 422, nothing is changed
+*/
+func (r *reloader) Handler(w http.ResponseWriter, req *http.Request) {
+	result, err := r.Reload()
+	if err != nil {
+		logger.Err("[reload]: config is not changed: %s", err.Error())
+		web.ReturnFieldError(w, req, "config", err.Error())
+		return
+	}
+	logResult(result)
+
+	bytes, e := json.Marshal(result)
+	if e != nil {
+		web.ReturnError(w, req, fmt.Sprintf("Cannot marshal result: %s", e.Error()), http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprintf(w, "%s", string(bytes))
+}
+
+func logResult(result *ReloadResult) {
+	logger.Log("[reload]: applied: %v", result.Applied)
+	if len(result.RestartRequired) > 0 {
+		logger.Log("[reload]: changed, but restart is required: %v", result.RestartRequired)
+	}
+	for key, message := range result.Failed {
+		logger.Err("[reload]: %s is not applied: %s", key, message)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"pinger/logger"
+	"pinger/notify"
+	"pinger/pinger"
+	"pinger/pools"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+/*
+waitShutdown - block until SIGTERM or SIGINT, then stop gracefully: refuse new ping jobs, close event streams
+and drain http and grpc servers, wait for running ping jobs, flush notifications and save hosts.
+Waiting is limited by pinger.shutdown-timeout; flush and save are done anyway. Second signal exits at once
+*/
+func waitShutdown(server *http.Server, grpcServer *grpc.Server) {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	received := <-signals
+
+	timeout := time.Duration(config().ShutdownTimeout) * time.Second
+	logger.Log("[shutdown]: %s received, stopping (timeout %s)", received.String(), timeout.String())
+	go func() {
+		<-signals
+		logger.Err("[shutdown]: second signal received, exiting without final save")
+		os.Exit(1)
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// new checks are refused at once, running ones are finished below
+	pinger.Pinger.Stop()
+	// streams are endless, close them so servers can be drained
+	notify.Events.Close()
+	grpcStopped := make(chan struct{})
+	go func() {
+		stopGRPC(ctx, grpcServer)
+		close(grpcStopped)
+	}()
+	if err := server.Shutdown(ctx); err != nil {
+		logger.Err("[shutdown]: http requests are not finished: %s", err.Error())
+		server.Close()
+	}
+	<-grpcStopped
+
+	if err := pools.PingPool.StopAll(ctx); err != nil {
+		logger.Err("[shutdown]: checks are not finished: %s", err.Error())
+	}
+	if err := pinger.Pinger.Wait(ctx); err != nil {
+		logger.Err("[shutdown]: ping jobs are not finished: %s", err.Error())
+	}
+
+	notify.Buffer.Stop()
+	pools.TopicPool.Save()
+	logger.Log("[shutdown]: stopped")
+}
+
+// stopGRPC - finish running grpc calls; calls are cancelled when ctx is done
+func stopGRPC(ctx context.Context, grpcServer *grpc.Server) {
+	if grpcServer == nil {
+		return
+	}
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		logger.Err("[shutdown]: grpc calls are not finished: %s", ctx.Err().Error())
+		grpcServer.Stop()
+	}
+}
@@ -0,0 +1,33 @@
+package web
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed dashboard
+var dashboardFiles embed.FS
+
+/*
+Dashboard - GET /dashboard/ (route is /dashboard prefix): embedded web UI (topics overview, hosts tables, host latency chart, ping buttons).
+Static files are served without authentication: data is loaded by browser from API with token entered by user
+*/
+func Dashboard() http.Handler {
+	files, err := fs.Sub(dashboardFiles, "dashboard")
+	if err != nil {
+		panic(err)
+	}
+	fileServer := http.StripPrefix("/dashboard/", http.FileServer(http.FS(files)))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// router uses StrictSlash, so /dashboard route would redirect back to itself
+		if r.URL.Path == "/dashboard" {
+			http.Redirect(w, r, "/dashboard/", http.StatusMovedPermanently)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Content-Security-Policy", "default-src 'self'")
+		fileServer.ServeHTTP(w, r)
+	})
+}
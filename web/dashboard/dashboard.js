@@ -0,0 +1,414 @@
+'use strict';
+
+/*
+pinger dashboard: data is loaded from API (/v1/topics, /ping-now) with token of user,
+live updates come from /events stream. Views:
+
+	#/                    topics with host counts by state
+	#/topic/{name}        hosts of topic with last check
+	#/host/{name}/{ip}    host details and latency chart of recent checks
+*/
+
+const tokenKey = 'pinger-token';
+const maxResults = 120; // the same as pools.RecentResults
+
+let token = localStorage.getItem(tokenKey) || '';
+let stream = null;
+let reloadTimer = null;
+
+// el - create element: el('td', {class: 'num', text: '1'}, child...)
+function el(tag, attrs, ...children) {
+	const node = document.createElement(tag);
+	for (const [key, value] of Object.entries(attrs || {})) {
+		if (key === 'text') {
+			node.textContent = value;
+		} else if (key === 'onclick') {
+			node.addEventListener('click', value);
+		} else {
+			node.setAttribute(key, value);
+		}
+	}
+	for (const child of children) {
+		node.append(child);
+	}
+	return node;
+}
+
+function svg(tag, attrs) {
+	const node = document.createElementNS('http://www.w3.org/2000/svg', tag);
+	for (const [key, value] of Object.entries(attrs || {})) {
+		node.setAttribute(key, value);
+	}
+	return node;
+}
+
+class APIError extends Error {
+	constructor(status, body) {
+		super(body && body.message ? body.message : 'request failed with status ' + status);
+		this.status = status;
+	}
+}
+
+async function api(path) {
+	const response = await fetch(path, {headers: token ? {'X-API-Key': token} : {}});
+	let body = null;
+	try {
+		body = await response.json();
+	} catch (e) {
+		// empty or not json body
+	}
+	if (response.status === 401) {
+		showLogin(token ? 'Token is not valid' : '');
+	}
+	if (!response.ok || (body && body.ok === false)) {
+		throw new APIError(response.status, body);
+	}
+	return body;
+}
+
+function showLogin(message) {
+	closeStream();
+	document.getElementById('view').replaceChildren();
+	document.getElementById('login').hidden = false;
+	document.getElementById('login-error').textContent = message || '';
+	document.getElementById('token').focus();
+}
+
+document.getElementById('login').addEventListener('submit', (e) => {
+	e.preventDefault();
+	token = document.getElementById('token').value.trim();
+	localStorage.setItem(tokenKey, token);
+	document.getElementById('login').hidden = true;
+	render();
+});
+
+document.getElementById('logout').addEventListener('click', () => {
+	token = '';
+	localStorage.removeItem(tokenKey);
+	showLogin('');
+});
+
+// subscribe - open /events stream; onEvent gets parsed event, gap event reloads the view
+function subscribe(params, onEvent) {
+	closeStream();
+	const query = new URLSearchParams(params);
+	if (token) {
+		query.set('access_token', token);
+	}
+	const live = document.getElementById('live');
+	stream = new EventSource('/events?' + query.toString());
+	stream.onopen = () => {
+		live.textContent = 'live';
+		live.classList.add('on');
+	};
+	stream.onerror = () => {
+		// EventSource reconnects itself with Last-Event-ID
+		live.textContent = stream.readyState === EventSource.CLOSED ? 'offline' : 'reconnecting';
+		live.classList.remove('on');
+	};
+	const handle = (message) => onEvent(JSON.parse(message.data));
+	stream.addEventListener('state', handle);
+	stream.addEventListener('check', handle);
+	stream.addEventListener('gap', () => scheduleReload());
+}
+
+function closeStream() {
+	if (stream) {
+		stream.close();
+		stream = null;
+	}
+	const live = document.getElementById('live');
+	live.textContent = 'offline';
+	live.classList.remove('on');
+}
+
+// scheduleReload - render view again, not more often than once a second
+function scheduleReload() {
+	if (!reloadTimer) {
+		reloadTimer = setTimeout(() => {
+			reloadTimer = null;
+			render();
+		}, 1000);
+	}
+}
+
+function stateBadge(state) {
+	return el('span', {class: 'state ' + (state || 'unknown'), text: state || 'unknown'});
+}
+
+function formatMs(ms) {
+	return ms === undefined || ms === null ? '-' : ms.toFixed(ms < 10 ? 2 : 1) + ' ms';
+}
+
+function formatLoss(percent) {
+	return percent === undefined || percent === null ? '-' : Math.round(percent) + '%';
+}
+
+function formatAgo(time) {
+	if (!time) {
+		return '-';
+	}
+	const seconds = Math.max(0, Math.round((Date.now() - new Date(time).getTime()) / 1000));
+	if (seconds < 60) {
+		return seconds + 's ago';
+	} else if (seconds < 3600) {
+		return Math.floor(seconds / 60) + 'm ago';
+	} else if (seconds < 86400) {
+		return Math.floor(seconds / 3600) + 'h ago';
+	}
+	return Math.floor(seconds / 86400) + 'd ago';
+}
+
+function topicLink(name) {
+	return el('a', {href: '#/topic/' + encodeURIComponent(name), text: name});
+}
+
+function hostLink(name, ip) {
+	return el('a', {href: '#/host/' + encodeURIComponent(name) + '/' + encodeURIComponent(ip), text: ip});
+}
+
+// pingButton - on-demand ping with /ping-now, result is shown next to button
+function pingButton(name, ip) {
+	const result = el('span', {class: 'result'});
+	const button = el('button', {type: 'button', text: 'Ping'});
+	button.addEventListener('click', async () => {
+		button.disabled = true;
+		result.className = 'result';
+		result.textContent = 'pinging...';
+		try {
+			const r = await api('/ping-now?' + new URLSearchParams({host: ip, topic: name}).toString());
+			result.textContent = (r.Alive ? 'alive' : 'dead') + ', ' + formatMs(r.AvgRttMs) + ', loss ' + formatLoss(100 - r.SuccessPercent);
+		} catch (e) {
+			result.className = 'result error';
+			result.textContent = e.status === 403 ? 'token has no ping scope' : e.message;
+		}
+		button.disabled = false;
+	});
+	return el('span', {}, button, result);
+}
+
+function setCrumbs(...items) {
+	const crumbs = document.getElementById('crumbs');
+	crumbs.replaceChildren();
+	for (const item of items) {
+		crumbs.append(' / ', item);
+	}
+}
+
+// overview - topics with host counts by state
+async function overview(view) {
+	setCrumbs();
+	const data = await api('/v1/topics');
+	const rows = data.topics.map((topic) => {
+		const states = topic.states || {};
+		const bar = el('div', {class: 'bar'});
+		for (const state of ['up', 'degraded', 'down']) {
+			if (states[state] && topic.hostCount) {
+				const part = el('span', {class: state, title: states[state] + ' ' + state});
+				part.style.width = (100 * states[state] / topic.hostCount) + '%';
+				bar.append(part);
+			}
+		}
+		return el('tr', {},
+			el('td', {}, topicLink(topic.name)),
+			el('td', {class: 'num', text: topic.hostCount}),
+			el('td', {class: 'num', text: states.up || 0}),
+			el('td', {class: 'num', text: states.degraded || 0}),
+			el('td', {class: 'num', text: states.down || 0}),
+			el('td', {}, bar),
+			el('td', {class: 'num', text: topic.interval + 's'}),
+		);
+	});
+	view.replaceChildren(
+		el('h1', {text: 'Topics'}),
+		rows.length === 0 ? el('p', {class: 'muted', text: 'No topics'}) : el('table', {},
+			el('thead', {}, el('tr', {},
+				el('th', {text: 'Topic'}),
+				el('th', {class: 'num', text: 'Hosts'}),
+				el('th', {class: 'num', text: 'Up'}),
+				el('th', {class: 'num', text: 'Degraded'}),
+				el('th', {class: 'num', text: 'Down'}),
+				el('th', {text: ''}),
+				el('th', {class: 'num', text: 'Interval'}),
+			)),
+			el('tbody', {}, ...rows),
+		),
+	);
+	subscribe({}, () => scheduleReload());
+}
+
+// topicView - hosts of topic; rows are updated by check events
+async function topicView(view, name) {
+	setCrumbs(topicLink(name));
+	const data = await api('/v1/topics/' + encodeURIComponent(name));
+	const rows = new Map();
+	for (const host of data.topic.hosts || []) {
+		const cells = {
+			state: el('td', {}, stateBadge(host.state)),
+			rtt: el('td', {class: 'num', text: formatMs(host.stats && host.stats.avgRttMs)}),
+			loss: el('td', {class: 'num', text: formatLoss(host.stats && host.stats.lossPercent)}),
+			checked: el('td', {text: formatAgo(host.checked)}),
+			changed: el('td', {text: formatAgo(host.changed)}),
+		};
+		const row = el('tr', {},
+			el('td', {}, hostLink(name, host.ip)),
+			cells.state, cells.rtt, cells.loss, cells.checked, cells.changed,
+			el('td', {}, pingButton(name, host.ip)),
+		);
+		rows.set(host.ip, {row, cells, checked: host.checked, changed: host.changed});
+	}
+	view.replaceChildren(
+		el('h1', {text: name}),
+		el('p', {class: 'muted', text: data.topic.hostCount + ' hosts, interval ' + data.topic.interval + 's, ' + data.topic.probes + ' probes'}),
+		el('table', {},
+			el('thead', {}, el('tr', {},
+				el('th', {text: 'Host'}),
+				el('th', {text: 'State'}),
+				el('th', {class: 'num', text: 'RTT'}),
+				el('th', {class: 'num', text: 'Loss'}),
+				el('th', {text: 'Checked'}),
+				el('th', {text: 'Changed'}),
+				el('th', {text: ''}),
+			)),
+			el('tbody', {}, ...Array.from(rows.values(), (r) => r.row)),
+		),
+	);
+
+	subscribe({topic: name, checks: 'true'}, (e) => {
+		const r = rows.get(e.host);
+		if (!r) {
+			// host is added to topic
+			scheduleReload();
+			return;
+		}
+		r.cells.state.replaceChildren(stateBadge(e.state));
+		r.cells.rtt.textContent = formatMs(e.result.AvgRttMs);
+		r.cells.loss.textContent = formatLoss(100 - e.result.SuccessPercent);
+		r.checked = e.time;
+		if (e.kind === 'state') {
+			r.changed = e.time;
+			r.row.classList.add('flash');
+			setTimeout(() => r.row.classList.remove('flash'), 2000);
+		}
+		r.cells.checked.textContent = formatAgo(r.checked);
+		r.cells.changed.textContent = formatAgo(r.changed);
+	});
+}
+
+// chart - svg latency chart of results; dead checks are red dots on x axis
+function chart(results) {
+	const width = 1000, height = 240, left = 50, right = 10, top = 10, bottom = 24;
+	const node = svg('svg', {class: 'chart', viewBox: `0 0 ${width} ${height}`, preserveAspectRatio: 'none'});
+	if (results.length === 0) {
+		const text = svg('text', {x: width / 2, y: height / 2, 'text-anchor': 'middle'});
+		text.textContent = 'no checks yet';
+		node.append(text);
+		return node;
+	}
+
+	const times = results.map((r) => new Date(r.time).getTime());
+	const first = times[0], last = Math.max(times[times.length - 1], first + 1);
+	const maxRtt = Math.max(1, ...results.filter((r) => r.alive).map((r) => r.avgRttMs)) * 1.1;
+	const x = (t) => left + (t - first) / (last - first) * (width - left - right);
+	const y = (ms) => top + (1 - ms / maxRtt) * (height - top - bottom);
+
+	for (const ms of [0, maxRtt / 2, maxRtt]) {
+		node.append(svg('line', {class: 'axis', x1: left, x2: width - right, y1: y(ms), y2: y(ms)}));
+		const label = svg('text', {x: left - 6, y: y(ms) + 4, 'text-anchor': 'end'});
+		label.textContent = ms.toFixed(ms < 10 ? 1 : 0);
+		node.append(label);
+	}
+	for (const [t, anchor] of [[first, 'start'], [last, 'end']]) {
+		const label = svg('text', {x: x(t), y: height - 6, 'text-anchor': anchor});
+		label.textContent = new Date(t).toLocaleTimeString();
+		node.append(label);
+	}
+
+	// line is broken on dead checks
+	let points = [];
+	const flush = () => {
+		if (points.length > 0) {
+			node.append(svg('polyline', {class: 'line', points: points.join(' ')}));
+		}
+		points = [];
+	};
+	results.forEach((r, i) => {
+		if (r.alive) {
+			points.push(x(times[i]).toFixed(1) + ',' + y(r.avgRttMs).toFixed(1));
+		} else {
+			flush();
+			node.append(svg('circle', {class: 'down', cx: x(times[i]), cy: y(0), r: 3}));
+		}
+	});
+	flush();
+	return node;
+}
+
+// hostView - host details and latency chart; new checks are appended to chart
+async function hostView(view, name, ip) {
+	setCrumbs(topicLink(name), hostLink(name, ip));
+	const path = '/v1/topics/' + encodeURIComponent(name) + '/hosts/' + encodeURIComponent(ip);
+	const [data, recent] = await Promise.all([api(path), api(path + '/results')]);
+	const host = data.host;
+	const results = recent.results || [];
+
+	const details = el('dl', {class: 'details'});
+	const chartBox = el('div');
+	const update = () => {
+		const lastResult = results[results.length - 1];
+		details.replaceChildren(
+			el('dt', {text: 'State'}), el('dd', {}, stateBadge(host.state)),
+			el('dt', {text: 'RTT'}), el('dd', {text: formatMs(lastResult && lastResult.avgRttMs)}),
+			el('dt', {text: 'Loss'}), el('dd', {text: formatLoss(lastResult && lastResult.lossPercent)}),
+			el('dt', {text: 'Checked'}), el('dd', {text: formatAgo(host.checked)}),
+			el('dt', {text: 'Changed'}), el('dd', {text: formatAgo(host.changed)}),
+			el('dt', {text: 'Interval'}), el('dd', {text: host.interval + 's, ' + host.probes + ' probes'}),
+			el('dt', {text: 'Labels'}), el('dd', {text: Object.entries(host.labels || {}).map(([k, v]) => k + '=' + v).join(', ') || '-'}),
+		);
+		chartBox.replaceChildren(chart(results));
+	};
+	update();
+	view.replaceChildren(
+		el('h1', {}, document.createTextNode(ip + ' '), pingButton(name, ip)),
+		details,
+		el('h2', {text: 'Latency, ms (last ' + maxResults + ' checks)'}),
+		chartBox,
+	);
+
+	subscribe({topic: name, host: ip, checks: 'true'}, (e) => {
+		if (e.kind === 'check') {
+			results.push({time: e.time, alive: e.result.Alive, avgRttMs: e.result.AvgRttMs, lossPercent: 100 - e.result.SuccessPercent});
+			if (results.length > maxResults) {
+				results.shift();
+			}
+			host.checked = e.time;
+		} else {
+			host.changed = e.time;
+		}
+		host.state = e.state;
+		update();
+	});
+}
+
+async function render() {
+	const view = document.getElementById('view');
+	document.getElementById('logout').hidden = !token;
+	const parts = location.hash.replace(/^#\/?/, '').split('/').map(decodeURIComponent);
+	try {
+		if (parts[0] === 'topic' && parts.length === 2) {
+			await topicView(view, parts[1]);
+		} else if (parts[0] === 'host' && parts.length === 3) {
+			await hostView(view, parts[1], parts[2]);
+		} else {
+			await overview(view);
+		}
+	} catch (e) {
+		if (e.status !== 401) {
+			closeStream();
+			view.replaceChildren(el('p', {class: 'error', text: e.message}));
+		}
+	}
+}
+
+window.addEventListener('hashchange', render);
+render();
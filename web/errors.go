@@ -0,0 +1,118 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"pinger/auth"
+	"pinger/logger"
+	"pinger/pools"
+)
+
+/*
+Error - json body of every failed API request:
+
+	{"ok":false, "code":"validation_failed", "message":"wrong 'host' parameter", "field":"switches.Hosts[2].host"}
+
+Code depends only on http status (see Codes); field is set for validation errors.
+*/
+type Error struct {
+	OK      bool   `json:"ok"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Field   string `json:"field,omitempty"`
+}
+
+// Error codes
+const (
+	CodeBadRequest       = "bad_request"
+	CodeValidation       = "validation_failed"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeConflict         = "conflict"
+	CodeUnavailable      = "unavailable"
+	CodeInternal         = "internal"
+)
+
+// Codes - error code for http status
+var Codes = map[int]string{
+	http.StatusBadRequest:          CodeBadRequest,
+	http.StatusUnprocessableEntity: CodeValidation,
+	http.StatusUnauthorized:        CodeUnauthorized,
+	http.StatusForbidden:           CodeForbidden,
+	http.StatusNotFound:            CodeNotFound,
+	http.StatusMethodNotAllowed:    CodeMethodNotAllowed,
+	http.StatusConflict:            CodeConflict,
+	http.StatusServiceUnavailable:  CodeUnavailable,
+	http.StatusInternalServerError: CodeInternal,
+}
+
+/*
+ReturnError writes json error with http status and logs it into error log
+*/
+func ReturnError(w http.ResponseWriter, r *http.Request, err string, status int) {
+	writeError(w, r, status, Error{Message: err})
+}
+
+/*
+ReturnFieldError writes validation error (422) for request parameter or json field
+*/
+func ReturnFieldError(w http.ResponseWriter, r *http.Request, field string, err string) {
+	writeError(w, r, http.StatusUnprocessableEntity, Error{Message: err, Field: field})
+}
+
+/*
+ReturnValidationError writes validation error (422); field is taken from pools.FieldError
+*/
+func ReturnValidationError(w http.ResponseWriter, r *http.Request, err error) {
+	var fieldErr *pools.FieldError
+	if errors.As(err, &fieldErr) {
+		ReturnFieldError(w, r, fieldErr.Field, fieldErr.Message)
+		return
+	}
+	ReturnFieldError(w, r, "", err.Error())
+}
+
+/*
+Deny - write 401 or 403 response. Message is the same for missing and foreign topics,
+so clients cannot find out which topics exist
+*/
+func Deny(w http.ResponseWriter, r *http.Request, status int) {
+	e := Error{Message: "Forbidden"}
+	if status == http.StatusUnauthorized {
+		e.Message = "Unauthorized"
+		w.Header().Set("WWW-Authenticate", `Bearer realm="pinger"`)
+	}
+	auth.Rejected(Codes[status])
+	writeError(w, r, status, e)
+}
+
+// NotFound - handler for unknown routes
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	ReturnError(w, r, "Not found", http.StatusNotFound)
+}
+
+// MethodNotAllowed - handler for known routes with wrong method
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	ReturnError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, status int, e Error) {
+	e.OK = false
+	e.Code = Codes[status]
+	if e.Code == "" {
+		e.Code = CodeInternal
+	}
+	body, _ := json.Marshal(e)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+	if e.Field != "" {
+		logger.Err("[web]: Error %d: %s: %s (%s, %s)", status, e.Field, e.Message, r.URL.Path, auth.FromRequest(r).Name)
+	} else {
+		logger.Err("[web]: Error %d: %s (%s, %s)", status, e.Message, r.URL.Path, auth.FromRequest(r).Name)
+	}
+}
@@ -0,0 +1,243 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"pinger/auth"
+	"pinger/logger"
+	"pinger/notify"
+	"pinger/pools"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// Stream parameters
+const (
+	StreamBuffer    = 1000             // events kept for slow client before it is disconnected
+	StreamHeartbeat = 15 * time.Second // sse comment / websocket ping interval, keeps proxies from closing idle stream
+)
+
+/*
+eventStream - parsed stream parameters (same for /events and /events/ws):
+
+	topic   - topic name, comma separated or repeated
+	host    - host ip, comma separated or repeated
+	checks  - true: send every check result (kind "check"), not only state changes
+	since   - resume after this event ID (for sse `Last-Event-ID` header is used too)
+*/
+type eventStream struct {
+	topics map[string]bool
+	hosts  map[string]bool
+	checks bool
+	since  uint64
+}
+
+func newEventStream(r *http.Request) (*eventStream, error) {
+	query := r.URL.Query()
+	s := &eventStream{topics: listParam(query["topic"]), hosts: listParam(query["host"])}
+
+	principal := auth.FromRequest(r)
+	for topic := range s.topics {
+		if !principal.Can(auth.ScopeRead, topic) {
+			return nil, ErrForbidden
+		}
+	}
+	if v := query.Get("checks"); v != "" {
+		checks, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, &pools.FieldError{Field: "checks", Message: "should be bool"}
+		}
+		s.checks = checks
+	}
+	since := query.Get("since")
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		since = id
+	}
+	if since != "" {
+		id, err := strconv.ParseUint(since, 10, 64)
+		if err != nil {
+			return nil, &pools.FieldError{Field: "since", Message: "should be event id"}
+		}
+		s.since = id
+	}
+	return s, nil
+}
+
+// subscribe - subscription with stream filters and principal's topics
+func (s *eventStream) subscribe(principal *auth.Principal) (*notify.Subscription, []notify.StreamEvent, bool) {
+	return notify.Events.Subscribe(StreamBuffer, func(e notify.StreamEvent) bool {
+		return (s.checks || e.Kind != notify.KindCheck) &&
+			principal.Can(auth.ScopeRead, e.Topic) &&
+			(len(s.topics) == 0 || s.topics[e.Topic]) &&
+			(len(s.hosts) == 0 || s.hosts[e.Host])
+	}, s.since)
+}
+
+// gapEvent - sent first if requested events are not in log; client should reload full state (e.g. /dump-hosts)
+func gapEvent(since uint64) map[string]interface{} {
+	return map[string]interface{}{"kind": notify.KindGap, "since": since}
+}
+
+/*
+Events - GET /events: Server-Sent Events stream of host state changes (event name is kind, data is json event).
+Stream is closed if client is too slow; EventSource reconnects with Last-Event-ID and gets missed events from log
+*/
+func (ws *Params) Events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		ReturnError(w, r, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	stream, err := newEventStream(r)
+	if errors.Is(err, ErrForbidden) {
+		Deny(w, r, http.StatusForbidden)
+		return
+	} else if err != nil {
+		ReturnValidationError(w, r, err)
+		return
+	}
+
+	principal := auth.FromRequest(r)
+	subscription, replay, gap := stream.subscribe(principal)
+	defer notify.Events.Unsubscribe(subscription)
+	logger.Debug("[events]: %s connected to sse stream", principal.Name)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if gap {
+		writeSSE(w, 0, notify.KindGap, gapEvent(stream.since))
+	}
+	for _, e := range replay {
+		writeSSE(w, e.ID, e.Kind, e)
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(StreamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case e, ok := <-subscription.C:
+			if !ok {
+				return
+			}
+			writeSSE(w, e.ID, e.Kind, e)
+		}
+		flusher.Flush()
+	}
+}
+
+func writeSSE(w http.ResponseWriter, id uint64, kind string, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		logger.Err("[events]: cannot marshal event: %s", err.Error())
+		return
+	}
+	if id > 0 {
+		fmt.Fprintf(w, "id: %d\n", id)
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", kind, data)
+}
+
+// originAllowed - origin is the same host as request (dashboard) or one of allowed origins (listen.allowed-origins)
+func (ws *Params) originAllowed(origin string, host string) bool {
+	for _, allowed := range ws.AllowedOrigins() {
+		if allowed == "*" || strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, host)
+}
+
+// pingFrame - websocket ping (browsers answer with pong)
+var pingFrame = websocket.Codec{Marshal: func(interface{}) ([]byte, byte, error) {
+	return nil, websocket.PingFrame, nil
+}}
+
+/*
+EventsWebSocket - GET /events/ws: the same stream over websocket, every message is json event
+(`kind` is "state", "check" or "gap"). Resume with `since` parameter
+*/
+func (ws *Params) EventsWebSocket(w http.ResponseWriter, r *http.Request) {
+	stream, err := newEventStream(r)
+	if errors.Is(err, ErrForbidden) {
+		Deny(w, r, http.StatusForbidden)
+		return
+	} else if err != nil {
+		ReturnValidationError(w, r, err)
+		return
+	}
+	principal := auth.FromRequest(r)
+
+	server := websocket.Server{
+		// browsers send Origin, other clients may not; token can be in query, so other sites are not allowed
+		Handshake: func(config *websocket.Config, r *http.Request) error {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				return nil
+			}
+			if !ws.originAllowed(origin, r.Host) {
+				logger.Log("[events]: websocket from origin '%s' is rejected", origin)
+				return fmt.Errorf("origin '%s' is not allowed", origin)
+			}
+			var e error
+			config.Origin, e = url.Parse(origin)
+			return e
+		},
+		Handler: func(conn *websocket.Conn) {
+			defer conn.Close()
+			subscription, replay, gap := stream.subscribe(principal)
+			defer notify.Events.Unsubscribe(subscription)
+			logger.Debug("[events]: %s connected to websocket stream", principal.Name)
+
+			// client messages are ignored, read only to notice disconnect
+			closed := make(chan bool)
+			go func() {
+				var message string
+				for websocket.Message.Receive(conn, &message) == nil {
+				}
+				close(closed)
+			}()
+
+			if gap && websocket.JSON.Send(conn, gapEvent(stream.since)) != nil {
+				return
+			}
+			for _, e := range replay {
+				if websocket.JSON.Send(conn, e) != nil {
+					return
+				}
+			}
+
+			heartbeat := time.NewTicker(StreamHeartbeat)
+			defer heartbeat.Stop()
+			for {
+				select {
+				case <-closed:
+					return
+				case <-heartbeat.C:
+					if pingFrame.Send(conn, nil) != nil {
+						return
+					}
+				case e, ok := <-subscription.C:
+					if !ok || websocket.JSON.Send(conn, e) != nil {
+						return
+					}
+				}
+			}
+		},
+	}
+	server.ServeHTTP(w, r)
+}
@@ -0,0 +1,272 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"pinger/auth"
+	"pinger/notify"
+	"pinger/pools"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Inventory page size
+const (
+	DefaultPageLimit = 100
+	MaxPageLimit     = 1000
+)
+
+// InventoryPage - /dump-hosts response
+type InventoryPage struct {
+	OK     bool             `json:"ok"`
+	Total  int              `json:"total"`
+	Offset int              `json:"offset"`
+	Limit  int              `json:"limit"`
+	Hosts  []pools.HostInfo `json:"hosts"`
+}
+
+// inventory sort keys
+var hostSorters = map[string]func(a, b *pools.HostInfo) int{
+	"ip":    func(a, b *pools.HostInfo) int { return compareIP(a.IP, b.IP) },
+	"topic": func(a, b *pools.HostInfo) int { return strings.Compare(a.Topic, b.Topic) },
+	"state": func(a, b *pools.HostInfo) int { return strings.Compare(a.State, b.State) },
+	"changed": func(a, b *pools.HostInfo) int {
+		return compareTime(a.Changed, b.Changed)
+	},
+	"checked": func(a, b *pools.HostInfo) int {
+		return compareTime(a.Checked, b.Checked)
+	},
+	"rtt": func(a, b *pools.HostInfo) int {
+		return compareFloat(statValue(a, func(s *pools.HostStats) float64 { return s.AvgRttMs }), statValue(b, func(s *pools.HostStats) float64 { return s.AvgRttMs }))
+	},
+	"loss": func(a, b *pools.HostInfo) int {
+		return compareFloat(statValue(a, func(s *pools.HostStats) float64 { return s.LossPercent }), statValue(b, func(s *pools.HostStats) float64 { return s.LossPercent }))
+	},
+}
+
+/*
+DumpHosts - inventory of all monitored hosts (see pools.HostInfo), only topics readable by token are returned.
+Parameters (all optional):
+
+	topic   - topic name, comma separated or repeated
+	state   - up, down, degraded, unknown; comma separated or repeated
+	label   - `key:value` or `key` (host has label), repeated labels must all match
+	ip      - ip prefix (`10.10.`) or network (`10.10.0.0/16`)
+	sort    - ip (default), topic, state, changed, checked, rtt, loss; `-` prefix for descending order
+	limit   - page size, default 100, max 1000
+	offset  - number of hosts to skip
+*/
+func (ws *Params) DumpHosts(w http.ResponseWriter, r *http.Request) {
+	page, err := QueryInventory(auth.FromRequest(r), r.URL.Query())
+	if err != nil {
+		ReturnValidationError(w, r, err)
+		return
+	}
+
+	bytes, e := json.Marshal(page)
+	if e != nil {
+		ReturnError(w, r, fmt.Sprintf("Cannot marshal result: %s", e.Error()), http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprintf(w, "%s", string(bytes))
+}
+
+/*
+QueryInventory - page of hosts visible for principal, query parameters are the same as in DumpHosts.
+Errors are *pools.FieldError
+*/
+func QueryInventory(principal *auth.Principal, query url.Values) (*InventoryPage, error) {
+	filter, err := newHostFilter(query)
+	if err != nil {
+		return nil, err
+	}
+
+	sortKey := query.Get("sort")
+	if sortKey == "" {
+		sortKey = "ip"
+	}
+	desc := strings.HasPrefix(sortKey, "-")
+	sorter, ok := hostSorters[strings.TrimPrefix(sortKey, "-")]
+	if !ok {
+		return nil, &pools.FieldError{Field: "sort", Message: fmt.Sprintf("unknown sort key '%s'", sortKey)}
+	}
+
+	limit, err := intParam(query.Get("limit"), DefaultPageLimit)
+	if err != nil || limit < 1 || limit > MaxPageLimit {
+		return nil, &pools.FieldError{Field: "limit", Message: fmt.Sprintf("should be integer 1..%d", MaxPageLimit)}
+	}
+	offset, err := intParam(query.Get("offset"), 0)
+	if err != nil || offset < 0 {
+		return nil, &pools.FieldError{Field: "offset", Message: "should be non-negative integer"}
+	}
+
+	hosts := make([]pools.HostInfo, 0)
+	for _, h := range pools.Inventory() {
+		// hosts without topic are visible only for tokens with access to all topics
+		if h.Topic == "" && !principal.CanAll(auth.ScopeRead) || h.Topic != "" && !principal.Can(auth.ScopeRead, h.Topic) {
+			continue
+		}
+		if filter.match(&h) {
+			hosts = append(hosts, h)
+		}
+	}
+
+	sort.SliceStable(hosts, func(i, j int) bool {
+		a, b := &hosts[i], &hosts[j]
+		if c := sorter(a, b); c != 0 {
+			return (c < 0) != desc
+		}
+		if c := strings.Compare(a.Topic, b.Topic); c != 0 {
+			return c < 0
+		}
+		return compareIP(a.IP, b.IP) < 0
+	})
+
+	page := InventoryPage{OK: true, Total: len(hosts), Offset: offset, Limit: limit, Hosts: []pools.HostInfo{}}
+	if offset < len(hosts) {
+		end := offset + limit
+		if end > len(hosts) {
+			end = len(hosts)
+		}
+		page.Hosts = hosts[offset:end]
+	}
+	return &page, nil
+}
+
+// hostFilter - DumpHosts filters
+type hostFilter struct {
+	topics   map[string]bool
+	states   map[string]bool
+	labels   map[string]string // key => value, "" - any value
+	ipPrefix string
+	network  *net.IPNet
+}
+
+func newHostFilter(query url.Values) (*hostFilter, error) {
+	f := &hostFilter{
+		topics: listParam(query["topic"]),
+		states: listParam(query["state"]),
+		labels: make(map[string]string),
+	}
+	for state := range f.states {
+		if state != notify.StateUp && state != notify.StateDown && state != notify.StateDegraded && state != pools.StateUnknown {
+			return nil, &pools.FieldError{Field: "state", Message: fmt.Sprintf("unknown state '%s'", state)}
+		}
+	}
+	for _, label := range query["label"] {
+		parts := strings.SplitN(label, ":", 2)
+		if parts[0] == "" {
+			return nil, &pools.FieldError{Field: "label", Message: "should be 'key:value' or 'key'"}
+		}
+		if len(parts) == 2 {
+			f.labels[parts[0]] = parts[1]
+		} else {
+			f.labels[parts[0]] = ""
+		}
+	}
+	if ip := strings.TrimSpace(strings.Join(query["ip"], "")); ip != "" {
+		if strings.Contains(ip, "/") {
+			_, network, err := net.ParseCIDR(ip)
+			if err != nil {
+				return nil, &pools.FieldError{Field: "ip", Message: "wrong network"}
+			}
+			f.network = network
+		} else {
+			f.ipPrefix = ip
+		}
+	}
+	return f, nil
+}
+
+func (f *hostFilter) match(h *pools.HostInfo) bool {
+	if len(f.topics) > 0 && !f.topics[h.Topic] {
+		return false
+	}
+	if len(f.states) > 0 && !f.states[h.State] {
+		return false
+	}
+	for key, value := range f.labels {
+		if v, ok := h.Labels[key]; !ok || (value != "" && v != value) {
+			return false
+		}
+	}
+	if f.ipPrefix == "" && f.network == nil {
+		return true
+	}
+	// hosts given by dns name are matched by resolved addresses
+	for _, ip := range append([]string{h.IP}, h.Addresses...) {
+		if (f.ipPrefix == "" || strings.HasPrefix(ip, f.ipPrefix)) && (f.network == nil || f.network.Contains(net.ParseIP(ip))) {
+			return true
+		}
+	}
+	return false
+}
+
+// listParam - set of comma separated or repeated parameter values
+func listParam(values []string) map[string]bool {
+	result := make(map[string]bool)
+	for _, v := range values {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				result[item] = true
+			}
+		}
+	}
+	return result
+}
+
+func intParam(value string, def int) (int, error) {
+	if value == "" {
+		return def, nil
+	}
+	return strconv.Atoi(value)
+}
+
+func compareIP(a, b string) int {
+	ipA, ipB := net.ParseIP(a), net.ParseIP(b)
+	if ipA == nil || ipB == nil {
+		return strings.Compare(a, b)
+	}
+	return bytes.Compare(ipA.To16(), ipB.To16())
+}
+
+// compareTime - zero (nil) times go first
+func compareTime(a, b *time.Time) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	case a.Before(*b):
+		return -1
+	case a.After(*b):
+		return 1
+	}
+	return 0
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// statValue - value of last check stats, -1 if host is not checked yet
+func statValue(h *pools.HostInfo, value func(*pools.HostStats) float64) float64 {
+	if h.Stats == nil {
+		return -1
+	}
+	return value(h.Stats)
+}
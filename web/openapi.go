@@ -0,0 +1,387 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"pinger/pools"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+/*
+OpenAPI - OpenAPI 3 document. Only the parts used by Spec are defined
+*/
+type OpenAPI struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Paths      map[string]PathItem   `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []map[string][]string `json:"security,omitempty"`
+}
+
+// Info - document info
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem - operations of path by lowercase method
+type PathItem map[string]*Operation
+
+// Operation - single API operation
+type Operation struct {
+	Summary     string              `json:"summary"`
+	Description string              `json:"description,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+// Parameter - query or path parameter
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody - request body by content type
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// MediaType - body schema
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Response - response description and body
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// Components - reusable schemas and security schemes
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme - token authentication scheme
+type SecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme,omitempty"`
+	In     string `json:"in,omitempty"`
+	Name   string `json:"name,omitempty"`
+}
+
+/*
+Schema - subset of OpenAPI schema object, which is checked by Validate.
+AdditionalProperties is false (unknown fields are errors), *Schema or nil (any fields)
+*/
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+}
+
+// OpenAPIHandler - GET /openapi.json
+func OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, Spec)
+}
+
+/*
+ValidateRequest - check parameters and json body of request against Spec operation of matched route.
+Writes 400 (body is not json), 405 (method is not described in Spec) or 422 (*pools.FieldError)
+and returns false if request is invalid
+*/
+func ValidateRequest(w http.ResponseWriter, r *http.Request) bool {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return true
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return true
+	}
+	path, ok := Spec.Paths[template]
+	if !ok {
+		return true
+	}
+	op := path[strings.ToLower(r.Method)]
+	if op == nil {
+		MethodNotAllowed(w, r)
+		return false
+	}
+
+	if err := Spec.validateParams(op, r.URL.Query(), mux.Vars(r)); err != nil {
+		ReturnValidationError(w, r, err)
+		return false
+	}
+
+	if op.RequestBody == nil {
+		return true
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		ReturnError(w, r, fmt.Sprintf("Error getting input: %s", err.Error()), http.StatusBadRequest)
+		return false
+	}
+	r.Body.Close()
+	// handlers read body again
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		if op.RequestBody.Required {
+			ReturnError(w, r, "Missing json body", http.StatusBadRequest)
+			return false
+		}
+		return true
+	}
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		ReturnError(w, r, fmt.Sprintf("Cannot parse json body: %s", err.Error()), http.StatusBadRequest)
+		return false
+	}
+	if err := Spec.Validate(op.RequestBody.Content["application/json"].Schema, value, ""); err != nil {
+		ReturnValidationError(w, r, err)
+		return false
+	}
+	return true
+}
+
+// validateParams - check required parameters and types of given ones. Query parameter names are case insensitive
+func (s *OpenAPI) validateParams(op *Operation, query url.Values, vars map[string]string) error {
+	lowerQuery := make(map[string]string)
+	for name, values := range query {
+		if v := strings.Join(values, ""); v != "" {
+			lowerQuery[strings.ToLower(name)] = v
+		}
+	}
+
+	for _, p := range op.Parameters {
+		var value string
+		var ok bool
+		if p.In == "path" {
+			value, ok = vars[p.Name]
+		} else {
+			value, ok = lowerQuery[strings.ToLower(p.Name)]
+		}
+		if !ok {
+			if p.Required {
+				return &pools.FieldError{Field: p.Name, Message: "missing parameter"}
+			}
+			continue
+		}
+		if err := s.validateString(p.Schema, value, p.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateString - check parameter value (always string in query) against schema type
+func (s *OpenAPI) validateString(schema *Schema, value string, field string) error {
+	schema = s.resolve(schema)
+	switch schema.Type {
+	case "integer":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return &pools.FieldError{Field: field, Message: "should be integer"}
+		}
+		return checkRange(schema, float64(n), field)
+	case "number":
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return &pools.FieldError{Field: field, Message: "should be number"}
+		}
+		return checkRange(schema, n, field)
+	case "boolean":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return &pools.FieldError{Field: field, Message: "should be bool"}
+		}
+		return nil
+	}
+	return s.Validate(schema, value, field)
+}
+
+/*
+Validate - check decoded json value against schema. Errors are *pools.FieldError with json path
+of wrong value, like `switches.Hosts[2].Probes`
+*/
+func (s *OpenAPI) Validate(schema *Schema, value interface{}, field string) error {
+	schema = s.resolve(schema)
+	if schema == nil {
+		return nil
+	}
+
+	switch schema.Type {
+	case "object":
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return typeError(field, "object", value)
+		}
+		for _, name := range schema.Required {
+			if _, ok := m[name]; !ok {
+				return &pools.FieldError{Field: join(field, name), Message: "missing field"}
+			}
+		}
+		// sorted keys: the same body always gives the same error
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if property, ok := schema.Properties[k]; ok {
+				if err := s.Validate(property, m[k], join(field, k)); err != nil {
+					return err
+				}
+				continue
+			}
+			switch additional := schema.AdditionalProperties.(type) {
+			case *Schema:
+				if err := s.Validate(additional, m[k], join(field, k)); err != nil {
+					return err
+				}
+			case bool:
+				if !additional {
+					return unknownField(schema, field, k)
+				}
+			}
+		}
+	case "array":
+		list, ok := value.([]interface{})
+		if !ok {
+			return typeError(field, "array", value)
+		}
+		for n, item := range list {
+			if err := s.Validate(schema.Items, item, fmt.Sprintf("%s[%d]", field, n)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return typeError(field, "string", value)
+		}
+		if len(schema.Enum) > 0 && !contains(schema.Enum, str) {
+			return &pools.FieldError{Field: field, Message: fmt.Sprintf("should be one of %v, but '%s' given", schema.Enum, str)}
+		}
+		switch schema.Format {
+		case "ip":
+			if net.ParseIP(str) == nil {
+				return &pools.FieldError{Field: field, Message: "should be ip address"}
+			}
+		case "host":
+			if _, ok := pools.HostKey(str); !ok {
+				return &pools.FieldError{Field: field, Message: "should be ip address or dns name"}
+			}
+		case "date-time":
+			if _, err := time.Parse(time.RFC3339, str); err != nil {
+				return &pools.FieldError{Field: field, Message: "should be RFC3339 time"}
+			}
+		}
+	case "integer", "number":
+		n, ok := value.(float64)
+		if !ok {
+			return typeError(field, schema.Type, value)
+		}
+		if schema.Type == "integer" && n != math.Trunc(n) {
+			return &pools.FieldError{Field: field, Message: fmt.Sprintf("should be integer, but %v given", n)}
+		}
+		return checkRange(schema, n, field)
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return typeError(field, "boolean", value)
+		}
+	}
+	return nil
+}
+
+// resolve - schema by $ref from components
+func (s *OpenAPI) resolve(schema *Schema) *Schema {
+	for schema != nil && schema.Ref != "" {
+		schema = s.Components.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+	}
+	return schema
+}
+
+func checkRange(schema *Schema, n float64, field string) error {
+	if schema.Minimum != nil && n < *schema.Minimum {
+		return &pools.FieldError{Field: field, Message: fmt.Sprintf("should be >= %v", *schema.Minimum)}
+	}
+	if schema.Maximum != nil && n > *schema.Maximum {
+		return &pools.FieldError{Field: field, Message: fmt.Sprintf("should be <= %v", *schema.Maximum)}
+	}
+	return nil
+}
+
+// unknownField - error for field, which is not in schema; suggests field with other case (`UpdateUrl` => `UpdateURL`)
+func unknownField(schema *Schema, field string, name string) error {
+	for known := range schema.Properties {
+		if strings.EqualFold(known, name) {
+			return &pools.FieldError{Field: join(field, name), Message: fmt.Sprintf("unknown field, did you mean '%s'?", known)}
+		}
+	}
+	return &pools.FieldError{Field: join(field, name), Message: "unknown field"}
+}
+
+func typeError(field string, expected string, value interface{}) error {
+	return &pools.FieldError{Field: field, Message: fmt.Sprintf("should be %s, but %s given", expected, jsonType(value))}
+}
+
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func join(field string, name string) string {
+	if field == "" {
+		return name
+	}
+	return field + "." + name
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"pinger/pools"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		field   string // empty if body is valid
+		message string
+	}{
+		{"empty", `{}`, "", ""},
+		{"topic", `{"switches":{"Probes":3,"Interval":60,"Labels":{"dc":"a"},"Hosts":[{"host":"10.10.10.1","alive":true}]}}`, "", ""},
+		{"dns name", `{"switches":{"Hosts":[{"host":"sw1.noc.local"}]}}`, "", ""},
+		{"not object", `[]`, "", "should be object, but array given"},
+		{"topic is not object", `{"switches":1}`, "switches", "should be object, but number given"},
+		{"unknown field", `{"switches":{"Foo":1}}`, "switches.Foo", "unknown field"},
+		{"field case", `{"switches":{"UpdateUrl":"http://x"}}`, "switches.UpdateUrl", "unknown field, did you mean 'UpdateURL'?"},
+		{"wrong type", `{"switches":{"Probes":"3"}}`, "switches.Probes", "should be integer, but string given"},
+		{"not integer", `{"switches":{"Interval":1.5}}`, "switches.Interval", "should be integer, but 1.5 given"},
+		{"minimum", `{"switches":{"Probes":0}}`, "switches.Probes", "should be >= 1"},
+		{"enum", `{"switches":{"UpdateFormat":"v2"}}`, "switches.UpdateFormat", "should be one of [legacy v1], but 'v2' given"},
+		{"missing host", `{"switches":{"Hosts":[{"alive":true}]}}`, "switches.Hosts[0].host", "missing field"},
+		{"wrong host", `{"switches":{"Hosts":[{"host":"10.10.10.1"},{"host":"-"}]}}`, "switches.Hosts[1].host", "should be ip address or dns name"},
+		{"wrong time", `{"switches":{"Hosts":[{"host":"10.10.10.1","changed":"yesterday"}]}}`, "switches.Hosts[0].changed", "should be RFC3339 time"},
+		{"wrong label", `{"switches":{"Labels":{"dc":1}}}`, "switches.Labels.dc", "should be string, but number given"},
+	}
+	for _, test := range tests {
+		var value interface{}
+		if err := json.Unmarshal([]byte(test.body), &value); err != nil {
+			t.Fatalf("%s: %s", test.name, err.Error())
+		}
+		err := Spec.Validate(ref("Topics"), value, "")
+		if test.message == "" {
+			if err != nil {
+				t.Errorf("%s: valid body is rejected: %s", test.name, err.Error())
+			}
+			continue
+		}
+		var fe *pools.FieldError
+		if !errors.As(err, &fe) {
+			t.Errorf("%s: error is %v, expected field error", test.name, err)
+			continue
+		}
+		if fe.Field != test.field || fe.Message != test.message {
+			t.Errorf("%s: error is '%s', expected '%s: %s'", test.name, fe.Error(), test.field, test.message)
+		}
+	}
+}
+
+func TestValidateRequest(t *testing.T) {
+	router := mux.NewRouter()
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if ValidateRequest(w, r) {
+			w.WriteHeader(http.StatusOK)
+		}
+	}
+	// without methods, as legacy routes were registered: ValidateRequest checks them
+	router.HandleFunc("/ping-now", handler)
+	router.HandleFunc("/store", handler)
+	router.HandleFunc("/v1/topics/{name}", handler)
+	router.HandleFunc("/v1/topics/{name}/hosts/{ip}", handler)
+	router.HandleFunc("/undocumented", handler)
+
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		body   string
+		status int
+		field  string
+	}{
+		{"query", "GET", "/ping-now?host=10.10.10.1&probes=3", "", http.StatusOK, ""},
+		{"query name case", "GET", "/ping-now?Host=10.10.10.1", "", http.StatusOK, ""},
+		{"missing parameter", "GET", "/ping-now", "", http.StatusUnprocessableEntity, "host"},
+		{"wrong parameter", "GET", "/ping-now?host=10.10.10.1&probes=many", "", http.StatusUnprocessableEntity, "probes"},
+		{"parameter range", "GET", "/ping-now?host=10.10.10.1&probes=0", "", http.StatusUnprocessableEntity, "probes"},
+		{"undescribed method", "POST", "/ping-now?host=10.10.10.1", "", http.StatusMethodNotAllowed, ""},
+		{"body", "POST", "/store", `{"switches":{}}`, http.StatusOK, ""},
+		{"wrong body", "POST", "/store", `{"switches":{"Probes":0}}`, http.StatusUnprocessableEntity, "switches.Probes"},
+		{"missing body", "POST", "/store", "", http.StatusBadRequest, ""},
+		{"not json", "POST", "/store", "topics", http.StatusBadRequest, ""},
+		{"get instead of post", "GET", "/store", "", http.StatusMethodNotAllowed, ""},
+		{"path parameter", "PUT", "/v1/topics/switches", `{"Probes":3}`, http.StatusOK, ""},
+		{"wrong path parameter", "GET", "/v1/topics/switches/hosts/-", "", http.StatusUnprocessableEntity, "ip"},
+		{"undocumented route", "POST", "/undocumented", "", http.StatusOK, ""},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, test.url, strings.NewReader(test.body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != test.status {
+			t.Errorf("%s: status is %d, expected %d (%s)", test.name, w.Code, test.status, w.Body.String())
+			continue
+		}
+		if test.field == "" {
+			continue
+		}
+		var e Error
+		if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil || e.Field != test.field {
+			t.Errorf("%s: error field is '%s', expected '%s' (%s)", test.name, e.Field, test.field, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"pinger/auth"
+	"pinger/history"
+	"pinger/pools"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// DefaultReportPeriod - report period if `from` is not given
+const DefaultReportPeriod = 30 * 24 * time.Hour
+
+/*
+SLA - availability report for topic or single host in json
+Parameters: topic (required), host, from, to (RFC3339 or unix timestamp), exclude-maintenance (bool)
+*/
+func (ws *Params) SLA(w http.ResponseWriter, r *http.Request) {
+	if !ws.slaAllowed(r) {
+		Deny(w, r, http.StatusForbidden)
+		return
+	}
+	report, err := ws.slaReport(r)
+	if err != nil {
+		ReturnValidationError(w, r, err)
+		return
+	}
+
+	bytes, e := json.Marshal(report)
+	if e != nil {
+		ReturnError(w, r, fmt.Sprintf("Cannot marshal result: %s", e.Error()), http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprintf(w, "%s", string(bytes))
+}
+
+/*
+SLACSV - same as SLA, but in csv format
+*/
+func (ws *Params) SLACSV(w http.ResponseWriter, r *http.Request) {
+	if !ws.slaAllowed(r) {
+		Deny(w, r, http.StatusForbidden)
+		return
+	}
+	report, err := ws.slaReport(r)
+	if err != nil {
+		ReturnValidationError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="sla-%s.csv"`, report.Topic))
+	report.WriteCSV(w)
+}
+
+// slaAllowed - check read access to requested topic (missing topic is reported by slaReport)
+func (ws *Params) slaAllowed(r *http.Request) bool {
+	topic := r.URL.Query().Get("topic")
+	return topic == "" || auth.FromRequest(r).Can(auth.ScopeRead, topic)
+}
+
+// slaReport - build report from request parameters; errors are *pools.FieldError
+func (ws *Params) slaReport(r *http.Request) (*history.Report, error) {
+	query := r.URL.Query()
+	topic := query.Get("topic")
+	if topic == "" {
+		return nil, &pools.FieldError{Field: "topic", Message: "missing parameter"}
+	}
+
+	now := time.Now()
+	to := now
+	if s := query.Get("to"); s != "" {
+		t, err := parseTime(s)
+		if err != nil {
+			return nil, &pools.FieldError{Field: "to", Message: "should be RFC3339 time or unix timestamp"}
+		}
+		to = t
+	}
+	if to.After(now) {
+		to = now
+	}
+	from := to.Add(-DefaultReportPeriod)
+	if s := query.Get("from"); s != "" {
+		t, err := parseTime(s)
+		if err != nil {
+			return nil, &pools.FieldError{Field: "from", Message: "should be RFC3339 time or unix timestamp"}
+		}
+		from = t
+	}
+	if !to.After(from) {
+		return nil, &pools.FieldError{Field: "from", Message: "must be before 'to'"}
+	}
+
+	var maintenance []history.Maintenance
+	if s := query.Get("exclude-maintenance"); s != "" {
+		exclude, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, &pools.FieldError{Field: "exclude-maintenance", Message: "should be bool"}
+		}
+		if exclude {
+			maintenance = ws.Maintenance()
+		}
+	}
+
+	if host := query.Get("host"); host != "" {
+		return history.Journal.HostReport(topic, host, from, to, maintenance), nil
+	}
+	return history.Journal.TopicReport(topic, from, to, maintenance), nil
+}
+
+// HistoryResponse - /v1/topics/{name}/history response
+type HistoryResponse struct {
+	OK          bool                 `json:"ok"`
+	Transitions []history.Transition `json:"transitions"`
+}
+
+/*
+TopicHistory - GET /v1/topics/{name}/history: state transitions of topic hosts from journal, ordered by time.
+Parameters (all optional): host, from, to (RFC3339 or unix timestamp)
+*/
+func (ws *Params) TopicHistory(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	if !topicAllowed(w, r, name) {
+		return
+	}
+	query := r.URL.Query()
+	var from, to time.Time
+	for field, value := range map[string]*time.Time{"from": &from, "to": &to} {
+		if s := query.Get(field); s != "" {
+			t, err := parseTime(s)
+			if err != nil {
+				ReturnFieldError(w, r, field, "should be RFC3339 time or unix timestamp")
+				return
+			}
+			*value = t
+		}
+	}
+
+	hosts := history.Journal.Hosts(name)
+	if host := query.Get("host"); host != "" {
+		hosts = []string{host}
+	}
+	result := HistoryResponse{OK: true, Transitions: []history.Transition{}}
+	for _, host := range hosts {
+		for _, t := range history.Journal.Transitions(name, host) {
+			if (from.IsZero() || !t.Time.Before(from)) && (to.IsZero() || t.Time.Before(to)) {
+				result.Transitions = append(result.Transitions, t)
+			}
+		}
+	}
+	sort.SliceStable(result.Transitions, func(i, j int) bool { return result.Transitions[i].Time.Before(result.Transitions[j].Time) })
+	writeJSON(w, r, http.StatusOK, result)
+}
+
+// parseTime - parse RFC3339 time or unix timestamp
+func parseTime(s string) (time.Time, error) {
+	if ts, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(ts, 0), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
@@ -0,0 +1,587 @@
+package web
+
+import (
+	"pinger/history"
+	"pinger/notify"
+	"pinger/pools"
+	"sort"
+)
+
+// Spec - OpenAPI document of all routes, served at /openapi.json and used by ValidateRequest
+var Spec = &OpenAPI{
+	OpenAPI: "3.0.3",
+	Info: Info{
+		Title:       "pinger",
+		Version:     "1",
+		Description: "Host monitoring daemon: topics of hosts are pinged, state changes are sent to update urls and notifiers",
+	},
+	Security: []map[string][]string{{}, {"bearer": {}}, {"apiKey": {}}},
+	Components: Components{
+		SecuritySchemes: map[string]SecurityScheme{
+			"bearer": {Type: "http", Scheme: "bearer"},
+			"apiKey": {Type: "apiKey", In: "header", Name: "X-API-Key"},
+		},
+		Schemas: map[string]*Schema{
+			"Topics": {
+				Type:                 "object",
+				Description:          "topicName => topic",
+				AdditionalProperties: ref("Topic"),
+			},
+			"Topic": {
+				Type:                 "object",
+				Properties:           topicProperties(),
+				AdditionalProperties: false,
+			},
+			"Host": {
+				Type:                 "object",
+				Properties:           hostProperties(true),
+				Required:             []string{"host"},
+				AdditionalProperties: false,
+			},
+			"HostParams": {
+				Type:                 "object",
+				Description:          "host parameters, ip is taken from path",
+				Properties:           hostProperties(false),
+				AdditionalProperties: false,
+			},
+			"StoreResult": {
+				Type:                 "object",
+				Description:          "topicName => ip => alive",
+				AdditionalProperties: &Schema{Type: "object", AdditionalProperties: &Schema{Type: "boolean"}},
+			},
+			"OK": {
+				Type:       "object",
+				Properties: map[string]*Schema{"ok": {Type: "boolean"}},
+			},
+			"Error": {
+				Type: "object",
+				Properties: map[string]*Schema{
+					"ok":      {Type: "boolean"},
+					"code":    {Type: "string", Enum: errorCodes()},
+					"message": {Type: "string"},
+					"field":   {Type: "string", Description: "path of wrong parameter or json field, like `switches.Hosts[2].host`"},
+				},
+			},
+			"PingResult": {
+				Type: "object",
+				Properties: map[string]*Schema{
+					"Alive":          {Type: "boolean"},
+					"SuccessPercent": {Type: "number"},
+					"AvgRttNs":       {Type: "integer"},
+					"AvgRttMs":       {Type: "number"},
+				},
+			},
+			"HostStats": {
+				Type: "object",
+				Properties: map[string]*Schema{
+					"successPercent": {Type: "number"},
+					"lossPercent":    {Type: "number"},
+					"avgRttMs":       {Type: "number"},
+					"avgRttNs":       {Type: "integer"},
+				},
+			},
+			"HostInfo": {
+				Type: "object",
+				Properties: map[string]*Schema{
+					"topic":     {Type: "string", Description: "empty for hosts added with /store-host"},
+					"ip":        {Type: "string", Description: "ip address or dns name"},
+					"addresses": stringArray(),
+					"resolve":   {Type: "string", Enum: pools.ResolveModes},
+					"probes":    {Type: "integer"},
+					"interval":  {Type: "integer"},
+					"updateURL": {Type: "string"},
+					"notifiers": stringArray(),
+					"labels":    stringMap(),
+					"state":     {Type: "string", Enum: []string{notify.StateUp, notify.StateDown, notify.StateDegraded, pools.StateUnknown}},
+					"alive":     {Type: "boolean"},
+					"changed":   {Type: "string", Format: "date-time"},
+					"checked":   {Type: "string", Format: "date-time"},
+					"stats":     ref("HostStats"),
+					"static":    {Type: "boolean", Description: "declared in config file, cannot be changed by API"},
+				},
+			},
+			"InventoryPage": {
+				Type: "object",
+				Properties: map[string]*Schema{
+					"ok":     {Type: "boolean"},
+					"total":  {Type: "integer"},
+					"offset": {Type: "integer"},
+					"limit":  {Type: "integer"},
+					"hosts":  {Type: "array", Items: ref("HostInfo")},
+				},
+			},
+			"TopicInfo": {
+				Type: "object",
+				Properties: map[string]*Schema{
+					"name":         {Type: "string"},
+					"probes":       {Type: "integer"},
+					"interval":     {Type: "integer"},
+					"updateURL":    {Type: "string"},
+					"updateFormat": {Type: "string"},
+					"updateMethod": {Type: "string"},
+					"notifiers":    stringArray(),
+					"labels":       stringMap(),
+					"resolve":      {Type: "string", Enum: pools.ResolveModes},
+					"hostCount":    {Type: "integer"},
+					"states":       {Type: "object", Description: "number of hosts by state", AdditionalProperties: &Schema{Type: "integer"}},
+					"static":       {Type: "boolean", Description: "declared in config file, cannot be changed or removed by API"},
+					"hosts":        {Type: "array", Items: ref("HostInfo")},
+				},
+			},
+			"TopicsResponse": {
+				Type: "object",
+				Properties: map[string]*Schema{
+					"ok":     {Type: "boolean"},
+					"topics": {Type: "array", Items: ref("TopicInfo")},
+				},
+			},
+			"TopicResponse": {
+				Type:       "object",
+				Properties: map[string]*Schema{"ok": {Type: "boolean"}, "topic": ref("TopicInfo")},
+			},
+			"DocumentResponse": {
+				Type:       "object",
+				Properties: map[string]*Schema{"ok": {Type: "boolean"}, "document": ref("Topic")},
+			},
+			"HostResponse": {
+				Type:       "object",
+				Properties: map[string]*Schema{"ok": {Type: "boolean"}, "host": ref("HostInfo")},
+			},
+			"ResultsResponse": {
+				Type: "object",
+				Properties: map[string]*Schema{
+					"ok": {Type: "boolean"},
+					"results": {Type: "array", Items: &Schema{
+						Type: "object",
+						Properties: map[string]*Schema{
+							"time":           {Type: "string", Format: "date-time"},
+							"alive":          {Type: "boolean"},
+							"successPercent": {Type: "number"},
+							"lossPercent":    {Type: "number"},
+							"avgRttMs":       {Type: "number"},
+							"avgRttNs":       {Type: "integer"},
+						},
+					}},
+				},
+			},
+			"HistoryResponse": {
+				Type: "object",
+				Properties: map[string]*Schema{
+					"ok": {Type: "boolean"},
+					"transitions": {Type: "array", Items: &Schema{
+						Type: "object",
+						Properties: map[string]*Schema{
+							"topic": {Type: "string"},
+							"host":  {Type: "string"},
+							"event": {Type: "string", Enum: []string{history.EventUp, history.EventDown, history.EventRemoved}},
+							"time":  {Type: "string", Format: "date-time"},
+						},
+					}},
+				},
+			},
+			"ReloadResult": {
+				Type: "object",
+				Properties: map[string]*Schema{
+					"ok":              {Type: "boolean"},
+					"applied":         {Type: "array", Items: &Schema{Type: "string"}},
+					"restartRequired": {Type: "array", Items: &Schema{Type: "string"}},
+					"failed":          {Type: "object", Description: "error message by config key, previous value is kept", AdditionalProperties: &Schema{Type: "string"}},
+				},
+			},
+			"StreamEvent": {
+				Type: "object",
+				Properties: map[string]*Schema{
+					"id":                {Type: "integer"},
+					"kind":              {Type: "string", Enum: []string{notify.KindState, notify.KindCheck, notify.KindResolve, notify.KindGap}},
+					"since":             {Type: "integer", Description: "gap: requested event id"},
+					"topic":             {Type: "string"},
+					"host":              {Type: "string"},
+					"state":             {Type: "string"},
+					"previous":          {Type: "string"},
+					"alive":             {Type: "boolean"},
+					"result":            ref("PingResult"),
+					"time":              {Type: "string", Format: "date-time"},
+					"duration":          {Type: "integer", Description: "nanoseconds in previous state"},
+					"labels":            stringMap(),
+					"addresses":         {Type: "array", Items: &Schema{Type: "string"}, Description: "resolve: new addresses of dns name"},
+					"previousAddresses": {Type: "array", Items: &Schema{Type: "string"}, Description: "resolve: previous addresses"},
+				},
+			},
+			"Report": {
+				Type:        "object",
+				Description: "durations are in seconds",
+				Properties: map[string]*Schema{
+					"topic":         {Type: "string"},
+					"host":          {Type: "string"},
+					"from":          {Type: "string", Format: "date-time"},
+					"to":            {Type: "string", Format: "date-time"},
+					"monitored":     {Type: "number"},
+					"downtime":      {Type: "number"},
+					"maintenance":   {Type: "number"},
+					"uptime":        {Type: "number", Description: "percent"},
+					"outages":       {Type: "integer"},
+					"mttr":          {Type: "number"},
+					"longestOutage": {Type: "number"},
+					"hosts":         {Type: "array", Items: ref("Report")},
+				},
+			},
+		},
+	},
+	Paths: map[string]PathItem{
+		"/ping-now": {
+			"get": {
+				Summary:    "Ping host and return result",
+				Parameters: []Parameter{hostParam("ip address or hostname", false), probesParam(), topicParam(false)},
+				Responses:  responses(jsonResponse("ping result", "PingResult"), "403", "404", "409", "422", "503"),
+			},
+		},
+		"/ping-api": {
+			"get": {
+				Summary:     "Ping host in background",
+				Description: "result is sent to pinger.result-url",
+				Parameters:  []Parameter{hostParam("ip address or hostname", false), probesParam(), topicParam(false)},
+				Responses:   responses(jsonResponse("job is started", "OK"), "403", "422", "503"),
+			},
+		},
+		"/store-host": {
+			"get": {
+				Summary: "Add host to PingPool (without topic)",
+				Parameters: []Parameter{
+					hostParam("ip address", true),
+					{Name: "interval", In: "query", Required: true, Schema: &Schema{Type: "integer", Minimum: number(30)}},
+					probesParam(),
+				},
+				Responses: responses(jsonResponse("host is added", "OK"), "403", "422"),
+			},
+		},
+		"/remove-host": {
+			"get": {
+				Summary:    "Stop pinging of PingPool host",
+				Parameters: []Parameter{hostParam("ip address", true)},
+				Responses:  responses(jsonResponse("host is stopped", "OK"), "403", "404", "422"),
+			},
+		},
+		"/dump-hosts": {
+			"get": {
+				Summary: "Inventory of monitored hosts",
+				Parameters: []Parameter{
+					{Name: "topic", In: "query", Description: "comma separated or repeated", Schema: &Schema{Type: "string"}},
+					{Name: "state", In: "query", Description: "up, down, degraded, unknown; comma separated or repeated", Schema: &Schema{Type: "string"}},
+					{Name: "label", In: "query", Description: "`key:value` or `key`", Schema: &Schema{Type: "string"}},
+					{Name: "ip", In: "query", Description: "ip prefix or network", Schema: &Schema{Type: "string"}},
+					{Name: "sort", In: "query", Description: "ip, topic, state, changed, checked, rtt, loss; `-` prefix for descending order", Schema: &Schema{Type: "string"}},
+					{Name: "limit", In: "query", Schema: &Schema{Type: "integer", Minimum: number(1), Maximum: number(MaxPageLimit)}},
+					{Name: "offset", In: "query", Schema: &Schema{Type: "integer", Minimum: number(0)}},
+				},
+				Responses: responses(jsonResponse("hosts page", "InventoryPage"), "403", "422"),
+			},
+		},
+		"/get-or-store": {
+			"post": {
+				Summary:     "Store topics, remove hosts missing in request",
+				RequestBody: jsonBody("Topics"),
+				Responses:   responses(jsonResponse("topic hosts state", "StoreResult"), "400", "403", "422"),
+			},
+		},
+		"/store": {
+			"post": {
+				Summary:     "Store topics, keep hosts missing in request",
+				RequestBody: jsonBody("Topics"),
+				Responses:   responses(jsonResponse("topic hosts state", "StoreResult"), "400", "403", "422"),
+			},
+		},
+		"/metrics": {
+			"get": {
+				Summary: "Metrics in prometheus text format",
+				Responses: responses(Response{
+					Description: "metrics",
+					Content:     map[string]MediaType{"text/plain": {Schema: &Schema{Type: "string"}}},
+				}, "403"),
+			},
+		},
+		"/sla": {
+			"get": {
+				Summary:    "Availability report of topic or host",
+				Parameters: slaParams(),
+				Responses:  responses(jsonResponse("report", "Report"), "403", "422"),
+			},
+		},
+		"/sla.csv": {
+			"get": {
+				Summary:    "Availability report in csv",
+				Parameters: slaParams(),
+				Responses: responses(Response{
+					Description: "report",
+					Content:     map[string]MediaType{"text/csv": {Schema: &Schema{Type: "string"}}},
+				}, "403", "422"),
+			},
+		},
+		"/v1/topics": {
+			"get": {
+				Summary:   "Topics readable by token",
+				Responses: responses(jsonResponse("topics without hosts", "TopicsResponse")),
+			},
+		},
+		"/v1/topics/{name}": {
+			"get": {
+				Summary:    "Topic with hosts",
+				Parameters: []Parameter{nameParam()},
+				Responses:  responses(jsonResponse("topic", "TopicResponse"), "403", "404"),
+			},
+			"put": {
+				Summary:     "Create or replace topic, hosts missing in body are removed",
+				Parameters:  []Parameter{nameParam()},
+				RequestBody: jsonBody("Topic"),
+				Responses:   created(responses(jsonResponse("topic is changed (201 - created)", "TopicResponse"), "400", "403", "409", "422")),
+			},
+			"patch": {
+				Summary:     "Change given topic parameters and hosts",
+				Parameters:  []Parameter{nameParam()},
+				RequestBody: jsonBody("Topic"),
+				Responses:   responses(jsonResponse("topic is changed", "TopicResponse"), "400", "403", "404", "409", "422"),
+			},
+			"delete": {
+				Summary:    "Remove topic with all hosts",
+				Parameters: []Parameter{nameParam()},
+				Responses:  responses(jsonResponse("topic is removed", "OK"), "403", "404", "409"),
+			},
+		},
+		"/v1/topics/{name}/hosts/{ip}": {
+			"get": {
+				Summary:    "Host of topic",
+				Parameters: []Parameter{nameParam(), ipParam()},
+				Responses:  responses(jsonResponse("host", "HostResponse"), "403", "404", "422"),
+			},
+			"put": {
+				Summary:     "Add host or replace it's parameters",
+				Parameters:  []Parameter{nameParam(), ipParam()},
+				RequestBody: jsonBody("HostParams"),
+				Responses:   created(responses(jsonResponse("host is changed (201 - created)", "HostResponse"), "400", "403", "404", "409", "422")),
+			},
+			"patch": {
+				Summary:     "Change given host parameters",
+				Parameters:  []Parameter{nameParam(), ipParam()},
+				RequestBody: jsonBody("HostParams"),
+				Responses:   responses(jsonResponse("host is changed", "HostResponse"), "400", "403", "404", "409", "422"),
+			},
+			"delete": {
+				Summary:    "Remove host from topic",
+				Parameters: []Parameter{nameParam(), ipParam()},
+				Responses:  responses(jsonResponse("host is removed", "OK"), "403", "404", "409", "422"),
+			},
+		},
+		"/v1/topics/{name}/hosts/{ip}/results": {
+			"get": {
+				Summary:    "Last checks of host, oldest first",
+				Parameters: []Parameter{nameParam(), ipParam()},
+				Responses:  responses(jsonResponse("results", "ResultsResponse"), "403", "404", "422"),
+			},
+		},
+		"/v1/topics/{name}/document": {
+			"get": {
+				Summary:     "Topic in PUT format with update secrets and host state",
+				Description: "requires write scope, as document has secrets; used by `pinger ctl export`",
+				Parameters:  []Parameter{nameParam()},
+				Responses:   responses(jsonResponse("topic document", "DocumentResponse"), "403", "404"),
+			},
+		},
+		"/v1/topics/{name}/history": {
+			"get": {
+				Summary: "State transitions of topic hosts, ordered by time",
+				Parameters: []Parameter{
+					nameParam(),
+					{Name: "host", In: "query", Schema: &Schema{Type: "string"}},
+					{Name: "from", In: "query", Description: "RFC3339 time or unix timestamp", Schema: &Schema{Type: "string"}},
+					{Name: "to", In: "query", Description: "RFC3339 time or unix timestamp", Schema: &Schema{Type: "string"}},
+				},
+				Responses: responses(jsonResponse("transitions", "HistoryResponse"), "403", "422"),
+			},
+		},
+		"/admin/reload": {
+			"post": {
+				Summary:     "Re-read config file and apply changed settings",
+				Description: "same as SIGHUP; listeners and storage paths are applied after restart only",
+				Responses:   responses(jsonResponse("reload result", "ReloadResult"), "403", "422"),
+			},
+		},
+		"/events": {
+			"get": {
+				Summary:     "Server-Sent Events stream of host state changes",
+				Description: "event name is kind (state, check, gap), data is json event; resume with Last-Event-ID header or since",
+				Parameters:  streamParams(),
+				Responses: responses(Response{
+					Description: "event stream",
+					Content:     map[string]MediaType{"text/event-stream": {Schema: ref("StreamEvent")}},
+				}, "403", "422"),
+			},
+		},
+		"/events/ws": {
+			"get": {
+				Summary:     "WebSocket stream of host state changes",
+				Description: "every message is json event (kind state, check, resolve or gap)",
+				Parameters:  streamParams(),
+				Responses: responses(Response{
+					Description: "websocket messages",
+					Content:     map[string]MediaType{"application/json": {Schema: ref("StreamEvent")}},
+				}, "403", "422"),
+			},
+		},
+		"/dashboard": {
+			"get": {
+				Summary:     "Web UI",
+				Description: "prefix of embedded static files (/dashboard/ and below), served without authentication",
+				Responses:   dashboardResponses(),
+			},
+			"head": {
+				Summary:   "Web UI file headers",
+				Responses: dashboardResponses(),
+			},
+		},
+		"/openapi.json": {
+			"get": {
+				Summary: "This document",
+				Responses: responses(Response{
+					Description: "OpenAPI 3 document",
+					Content:     map[string]MediaType{"application/json": {Schema: &Schema{Type: "object"}}},
+				}),
+			},
+		},
+	},
+}
+
+// topicProperties - topic json fields, as parsed by pools.ParseTopics
+func topicProperties() map[string]*Schema {
+	properties := updateProperties()
+	properties["Name"] = &Schema{Type: "string", Description: "ignored, topic name is the key"}
+	properties["Hosts"] = &Schema{Type: "array", Items: ref("Host")}
+	return properties
+}
+
+// hostProperties - host json fields, as parsed by pools.ParseHosts
+func hostProperties(withIP bool) map[string]*Schema {
+	properties := updateProperties()
+	if withIP {
+		properties["host"] = &Schema{Type: "string", Format: "host", Description: "ip address or dns name"}
+	}
+	properties["alive"] = &Schema{Type: "boolean", Description: "host state in your DB"}
+	properties["changed"] = &Schema{Type: "string", Format: "date-time", Description: "time of last state change"}
+	return properties
+}
+
+// updateProperties - parameters of both topic and host
+func updateProperties() map[string]*Schema {
+	return map[string]*Schema{
+		"Probes":        {Type: "integer", Minimum: number(1)},
+		"Interval":      {Type: "integer", Minimum: number(1), Description: "seconds"},
+		"UpdateURL":     {Type: "string", Description: "template, see README \"Request templates\""},
+		"UpdateSecret":  {Type: "string"},
+		"UpdateFormat":  {Type: "string", Enum: notify.Formats},
+		"UpdateMethod":  {Type: "string"},
+		"UpdateHeaders": stringMap(),
+		"UpdateBody":    {Type: "string"},
+		"Labels":        stringMap(),
+		"Notifiers":     stringArray(),
+		"Resolve":       {Type: "string", Enum: pools.ResolveModes, Description: "addresses of dns name to ping, default first"},
+	}
+}
+
+func streamParams() []Parameter {
+	return []Parameter{
+		{Name: "topic", In: "query", Description: "comma separated or repeated", Schema: &Schema{Type: "string"}},
+		{Name: "host", In: "query", Description: "comma separated or repeated", Schema: &Schema{Type: "string"}},
+		{Name: "checks", In: "query", Description: "send every check result", Schema: &Schema{Type: "boolean"}},
+		{Name: "since", In: "query", Description: "resume after event id", Schema: &Schema{Type: "integer", Minimum: number(0)}},
+		{Name: "access_token", In: "query", Description: "token for clients, which cannot send headers", Schema: &Schema{Type: "string"}},
+	}
+}
+
+func slaParams() []Parameter {
+	return []Parameter{
+		topicParam(true),
+		{Name: "host", In: "query", Schema: &Schema{Type: "string"}},
+		{Name: "from", In: "query", Description: "RFC3339 time or unix timestamp", Schema: &Schema{Type: "string"}},
+		{Name: "to", In: "query", Description: "RFC3339 time or unix timestamp", Schema: &Schema{Type: "string"}},
+		{Name: "exclude-maintenance", In: "query", Schema: &Schema{Type: "boolean"}},
+	}
+}
+
+func hostParam(description string, ip bool) Parameter {
+	schema := &Schema{Type: "string"}
+	if ip {
+		schema.Format = "ip"
+	}
+	return Parameter{Name: "host", In: "query", Description: description, Required: true, Schema: schema}
+}
+
+func probesParam() Parameter {
+	return Parameter{Name: "probes", In: "query", Schema: &Schema{Type: "integer", Minimum: number(1)}}
+}
+
+func topicParam(required bool) Parameter {
+	return Parameter{Name: "topic", In: "query", Required: required, Schema: &Schema{Type: "string"}}
+}
+
+func nameParam() Parameter {
+	return Parameter{Name: "name", In: "path", Description: "topic name", Required: true, Schema: &Schema{Type: "string"}}
+}
+
+func ipParam() Parameter {
+	return Parameter{Name: "ip", In: "path", Required: true, Description: "ip address or dns name", Schema: &Schema{Type: "string", Format: "host"}}
+}
+
+func jsonBody(schema string) *RequestBody {
+	return &RequestBody{Required: true, Content: map[string]MediaType{"application/json": {Schema: ref(schema)}}}
+}
+
+func jsonResponse(description string, schema string) Response {
+	return Response{Description: description, Content: map[string]MediaType{"application/json": {Schema: ref(schema)}}}
+}
+
+// responses - success response and error responses by status; 401 is possible for every route
+func responses(ok Response, errors ...string) map[string]Response {
+	result := map[string]Response{"200": ok}
+	for _, status := range append([]string{"401"}, errors...) {
+		result[status] = jsonResponse("error", "Error")
+	}
+	return result
+}
+
+// dashboardResponses - static files are not json and need no token
+func dashboardResponses() map[string]Response {
+	return map[string]Response{
+		"200": {Description: "html, js or css file", Content: map[string]MediaType{"text/html": {Schema: &Schema{Type: "string"}}}},
+		"301": {Description: "/dashboard is redirected to /dashboard/"},
+		"404": {Description: "file is not found"},
+	}
+}
+
+// created - add 201 response, same as 200
+func created(result map[string]Response) map[string]Response {
+	result["201"] = result["200"]
+	return result
+}
+
+func errorCodes() []string {
+	codes := make([]string, 0)
+	for _, code := range Codes {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+func ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+func number(n float64) *float64 {
+	return &n
+}
+
+func stringMap() *Schema {
+	return &Schema{Type: "object", AdditionalProperties: &Schema{Type: "string"}}
+}
+
+func stringArray() *Schema {
+	return &Schema{Type: "array", Items: &Schema{Type: "string"}}
+}
@@ -0,0 +1,271 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"pinger/auth"
+	"pinger/pools"
+	"sort"
+
+	"github.com/gorilla/mux"
+)
+
+// TopicsResponse - GET /v1/topics response
+type TopicsResponse struct {
+	OK     bool              `json:"ok"`
+	Topics []pools.TopicInfo `json:"topics"`
+}
+
+// TopicResponse - /v1/topics/{name} response
+type TopicResponse struct {
+	OK    bool            `json:"ok"`
+	Topic pools.TopicInfo `json:"topic"`
+}
+
+// HostResponse - /v1/topics/{name}/hosts/{ip} response
+type HostResponse struct {
+	OK   bool           `json:"ok"`
+	Host pools.HostInfo `json:"host"`
+}
+
+// DocumentResponse - /v1/topics/{name}/document response
+type DocumentResponse struct {
+	OK       bool                   `json:"ok"`
+	Document map[string]interface{} `json:"document"`
+}
+
+// ResultsResponse - /v1/topics/{name}/hosts/{ip}/results response
+type ResultsResponse struct {
+	OK      bool                 `json:"ok"`
+	Results []pools.RecentResult `json:"results"`
+}
+
+/*
+Topics - GET /v1/topics: list of topics readable by token, without hosts
+*/
+func (ws *Params) Topics(w http.ResponseWriter, r *http.Request) {
+	principal := auth.FromRequest(r)
+	result := TopicsResponse{OK: true, Topics: []pools.TopicInfo{}}
+	pools.TopicPool.Topics.Range(func(name, topic interface{}) bool {
+		if principal.Can(auth.ScopeRead, name.(string)) {
+			result.Topics = append(result.Topics, topic.(*pools.Topic).Info(false))
+		}
+		return true
+	})
+	sort.Slice(result.Topics, func(i, j int) bool { return result.Topics[i].Name < result.Topics[j].Name })
+	writeJSON(w, r, http.StatusOK, result)
+}
+
+/*
+Topic - /v1/topics/{name}
+
+	GET     topic with hosts
+	PUT     create or replace topic: body is topic json (like in /get-or-store), hosts missing in body are removed
+	PATCH   change given topic parameters; given hosts are added or changed, other hosts are kept
+	DELETE  remove topic with all hosts
+*/
+func (ws *Params) Topic(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	if !topicAllowed(w, r, name) {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		topic, ok := pools.TopicPool.Topics.Load(name)
+		if !ok {
+			ReturnError(w, r, "Topic not found", http.StatusNotFound)
+			return
+		}
+		info := topic.(*pools.Topic).Info(true)
+		sort.Slice(info.Hosts, func(i, j int) bool { return compareIP(info.Hosts[i].IP, info.Hosts[j].IP) < 0 })
+		writeJSON(w, r, http.StatusOK, TopicResponse{OK: true, Topic: info})
+	case http.MethodPut, http.MethodPatch:
+		params, ok := readObject(w, r)
+		if !ok {
+			return
+		}
+		probes, interval := ws.Defaults()
+		topic, created, err := pools.TopicPool.StoreTopic(name, params, r.Method == http.MethodPatch, probes, interval)
+		if errors.Is(err, pools.ErrTopicNotFound) {
+			ReturnError(w, r, "Topic not found", http.StatusNotFound)
+			return
+		} else if errors.Is(err, pools.ErrStatic) {
+			ReturnError(w, r, "Topic is declared in config file", http.StatusConflict)
+			return
+		} else if err != nil {
+			ReturnValidationError(w, r, err)
+			return
+		}
+		info := topic.Info(true)
+		sort.Slice(info.Hosts, func(i, j int) bool { return compareIP(info.Hosts[i].IP, info.Hosts[j].IP) < 0 })
+		writeJSON(w, r, createdStatus(created), TopicResponse{OK: true, Topic: info})
+	case http.MethodDelete:
+		if err := pools.TopicPool.RemoveTopic(name); errors.Is(err, pools.ErrTopicNotFound) {
+			ReturnError(w, r, "Topic not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			ReturnError(w, r, "Topic is declared in config file", http.StatusConflict)
+			return
+		}
+		fmt.Fprintf(w, `{"ok":true}`)
+	}
+}
+
+/*
+TopicHost - /v1/topics/{name}/hosts/{ip}
+
+	GET     host with state and last check
+	PUT     add host or replace it's parameters: body is host json (like in topic Hosts), missing parameters are taken from topic
+	PATCH   change given host parameters
+	DELETE  remove host from topic
+*/
+func (ws *Params) TopicHost(w http.ResponseWriter, r *http.Request) {
+	name, ip := mux.Vars(r)["name"], mux.Vars(r)["ip"]
+	if !topicAllowed(w, r, name) {
+		return
+	}
+	ip, ok := pools.HostKey(ip)
+	if !ok {
+		ReturnFieldError(w, r, "ip", "should be ip address or dns name")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		topic, ok := pools.TopicPool.Topics.Load(name)
+		if !ok {
+			ReturnError(w, r, "Topic not found", http.StatusNotFound)
+			return
+		}
+		host, ok := topic.(*pools.Topic).Hosts.Load(ip)
+		if !ok {
+			ReturnError(w, r, "Host not found", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, r, http.StatusOK, HostResponse{OK: true, Host: host.(*pools.DBHost).Info()})
+	case http.MethodPut, http.MethodPatch:
+		params, ok := readObject(w, r)
+		if !ok {
+			return
+		}
+		host, created, err := pools.TopicPool.StoreHost(name, ip, params, r.Method == http.MethodPatch)
+		if errors.Is(err, pools.ErrTopicNotFound) {
+			ReturnError(w, r, "Topic not found", http.StatusNotFound)
+			return
+		} else if errors.Is(err, pools.ErrHostNotFound) {
+			ReturnError(w, r, "Host not found", http.StatusNotFound)
+			return
+		} else if errors.Is(err, pools.ErrStatic) {
+			ReturnError(w, r, "Host is declared in config file", http.StatusConflict)
+			return
+		} else if err != nil {
+			ReturnValidationError(w, r, err)
+			return
+		}
+		writeJSON(w, r, createdStatus(created), HostResponse{OK: true, Host: host.Info()})
+	case http.MethodDelete:
+		if err := pools.TopicPool.RemoveHostFromTopic(name, ip); errors.Is(err, pools.ErrTopicNotFound) {
+			ReturnError(w, r, "Topic not found", http.StatusNotFound)
+			return
+		} else if errors.Is(err, pools.ErrStatic) {
+			ReturnError(w, r, "Host is declared in config file", http.StatusConflict)
+			return
+		} else if err != nil {
+			ReturnError(w, r, "Host not found", http.StatusNotFound)
+			return
+		}
+		fmt.Fprintf(w, `{"ok":true}`)
+	}
+}
+
+/*
+TopicDocument - GET /v1/topics/{name}/document: topic in PUT /v1/topics/{name} format with update secrets and host state.
+Requires write scope, as document has secrets
+*/
+func (ws *Params) TopicDocument(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	if !auth.FromRequest(r).Can(auth.ScopeWrite, name) {
+		Deny(w, r, http.StatusForbidden)
+		return
+	}
+	topic, ok := pools.TopicPool.Topics.Load(name)
+	if !ok {
+		ReturnError(w, r, "Topic not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, DocumentResponse{OK: true, Document: topic.(*pools.Topic).Document()})
+}
+
+/*
+TopicHostResults - GET /v1/topics/{name}/hosts/{ip}/results: last checks of host (see pools.RecentResults), oldest first
+*/
+func (ws *Params) TopicHostResults(w http.ResponseWriter, r *http.Request) {
+	name, ip := mux.Vars(r)["name"], mux.Vars(r)["ip"]
+	if !topicAllowed(w, r, name) {
+		return
+	}
+	topic, ok := pools.TopicPool.Topics.Load(name)
+	if !ok {
+		ReturnError(w, r, "Topic not found", http.StatusNotFound)
+		return
+	}
+	key, _ := pools.HostKey(ip)
+	host, ok := topic.(*pools.Topic).Hosts.Load(key)
+	if !ok {
+		ReturnError(w, r, "Host not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, ResultsResponse{OK: true, Results: host.(*pools.DBHost).RecentResults()})
+}
+
+// topicAllowed - check topic access: GET requires read scope, other methods - write. Writes 403 if denied
+func topicAllowed(w http.ResponseWriter, r *http.Request, name string) bool {
+	scope := auth.ScopeWrite
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		scope = auth.ScopeRead
+	}
+	if !auth.FromRequest(r).Can(scope, name) {
+		Deny(w, r, http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// readObject - read json object from request body. Writes 400 on error
+func readObject(w http.ResponseWriter, r *http.Request) (map[string]interface{}, bool) {
+	params := make(map[string]interface{})
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		ReturnError(w, r, fmt.Sprintf("Error getting input: %s", err.Error()), http.StatusBadRequest)
+		return nil, false
+	}
+	r.Body.Close()
+
+	if err := json.Unmarshal(body, &params); err != nil {
+		ReturnError(w, r, fmt.Sprintf("Cannot parse json body: %s", err.Error()), http.StatusBadRequest)
+		return nil, false
+	}
+	return params, true
+}
+
+func createdStatus(created bool) int {
+	if created {
+		return http.StatusCreated
+	}
+	return http.StatusOK
+}
+
+// writeJSON - write response with status
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, value interface{}) {
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		ReturnError(w, r, fmt.Sprintf("Cannot marshal result: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "%s", string(bytes))
+}
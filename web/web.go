@@ -2,21 +2,26 @@ package web
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"io/ioutil"
 	"net/http"
-	"pinger/logger"
+	"pinger/auth"
+	"pinger/history"
 	"pinger/pools"
+	"sync"
 )
 
 /*
-Params - keep default wariables. Old stuff, must be reworked or deleted
+Params - keep default wariables. Old stuff, must be reworked or deleted.
+Values are changed on config reload, so they are accessed with mutex
  */
 type Params struct {
-	DefaultProbes   int
-	DefaultInterval int64
-	DefaultURL      string
+	defaultProbes   int
+	defaultInterval int64
+	defaultURL      string
+	maintenance     []history.Maintenance
+	allowedOrigins  []string
+	mx              sync.Mutex
 }
 
 /*
@@ -24,14 +29,64 @@ NewWeb - returns new Params with parameters
  */
 func NewWeb(p int, i int64, u string) *Params {
 	w := Params{
-		DefaultProbes:   p,
-		DefaultInterval: i,
-		DefaultURL:      u,
+		defaultProbes:   p,
+		defaultInterval: i,
+		defaultURL:      u,
 	}
 
 	return &w
 }
 
+// SetDefaults - change default probes and interval of topics
+func (ws *Params) SetDefaults(probes int, interval int64) {
+	ws.mx.Lock()
+	defer ws.mx.Unlock()
+	ws.defaultProbes = probes
+	ws.defaultInterval = interval
+}
+
+// Defaults - default probes and interval of topics
+func (ws *Params) Defaults() (int, int64) {
+	ws.mx.Lock()
+	defer ws.mx.Unlock()
+	return ws.defaultProbes, ws.defaultInterval
+}
+
+// SetDefaultURL - change result url
+func (ws *Params) SetDefaultURL(url string) {
+	ws.mx.Lock()
+	defer ws.mx.Unlock()
+	ws.defaultURL = url
+}
+
+// SetMaintenance - change maintenance windows, which can be excluded from reports
+func (ws *Params) SetMaintenance(maintenance []history.Maintenance) {
+	ws.mx.Lock()
+	defer ws.mx.Unlock()
+	ws.maintenance = maintenance
+}
+
+// Maintenance - maintenance windows of config
+func (ws *Params) Maintenance() []history.Maintenance {
+	ws.mx.Lock()
+	defer ws.mx.Unlock()
+	return ws.maintenance
+}
+
+// SetAllowedOrigins - change origins of other sites, which browsers may open websocket streams from
+func (ws *Params) SetAllowedOrigins(origins []string) {
+	ws.mx.Lock()
+	defer ws.mx.Unlock()
+	ws.allowedOrigins = origins
+}
+
+// AllowedOrigins - origins of other sites allowed for websocket streams
+func (ws *Params) AllowedOrigins() []string {
+	ws.mx.Lock()
+	defer ws.mx.Unlock()
+	return ws.allowedOrigins
+}
+
 // Store - same as 'GetOrStore', but without removing non-existing hosts
 func (ws *Params) Store(w http.ResponseWriter, r *http.Request) {
 	ws.getOrStore(w, r, false)
@@ -44,36 +99,23 @@ func (ws *Params) GetOrStore(w http.ResponseWriter, r *http.Request) {
 	ws.getOrStore(w, r, true)
 }
 
-//func (ws *Params) getOrStore(w http.ResponseWriter, r *http.Request, removeOld bool) {
-func (ws *Params) getOrStore(w io.Writer, r *http.Request, removeOld bool) {
+func (ws *Params) getOrStore(w http.ResponseWriter, r *http.Request, removeOld bool) {
 
 	// todo: remove layer [topics:[]] from json
-	jsonParams := make(map[string]interface{})
-
-	body, bodyErr := ioutil.ReadAll(r.Body)
-	if bodyErr != nil {
-		ReturnError(w, r, fmt.Sprintf("Error getting input: %s", bodyErr.Error()), http.StatusBadRequest)
-		return
-	}
-	r.Body.Close()
-
-	jsonErr := json.Unmarshal(body, &jsonParams)
-	if nil != jsonErr {
-		ReturnError(w, r, fmt.Sprintf("Cannot parse json body: %s", jsonErr.Error()), http.StatusBadRequest)
+	jsonParams, ok := readObject(w, r)
+	if !ok {
 		return
 	}
 
 	//topics, err := ws.getTopics(jsonParams)
-	topics, err := pools.ParseTopics(jsonParams, ws.DefaultProbes, ws.DefaultInterval)
-	if err != nil {
-		ReturnError(w, r, err.Error(), http.StatusBadRequest)
+	result, err := ws.StoreTopics(auth.FromRequest(r), jsonParams, removeOld)
+	if errors.Is(err, ErrForbidden) {
+		Deny(w, r, http.StatusForbidden)
+		return
+	} else if err != nil {
+		ReturnValidationError(w, r, err)
 		return
 	}
-
-	//logger.Debug("TOPICS: %#v", topics)
-
-	//ret := pools.GlobalPool.GetOrStore(topics)
-	result := pools.TopicPool.GetOrStore(topics, removeOld)
 	// return json report with current objects
 	bytes, e := json.Marshal(result)
 	if e != nil {
@@ -83,12 +125,48 @@ func (ws *Params) getOrStore(w io.Writer, r *http.Request, removeOld bool) {
 	fmt.Fprintf(w, "%s", string(bytes))
 }
 
+// ErrForbidden - principal has no access to topic or host
+var ErrForbidden = errors.New("forbidden")
+
 /*
-ReturnError returns an http error and logs it into error log
+StoreTopics - check write access to all topics, parse and store them (see DBPool.GetOrStore).
+Returns ErrForbidden or *pools.FieldError
 */
-//func ReturnError(w http.ResponseWriter, r *http.Request, err string, status int) {
-func ReturnError(w io.Writer, r *http.Request, err string, status int) {
-	//http.Error(w, err, status)
-	fmt.Fprintf(w, fmt.Sprintf(`{"ok":false, "message":"%s"}`, err))
-	logger.Err("[web]: Error: %s (%s)", err, r.URL)
+func (ws *Params) StoreTopics(principal *auth.Principal, params map[string]interface{}, removeOld bool) (map[string]map[string]bool, error) {
+	// check permissions before parsing: forbidden request gets 403, not validation errors of topics it cannot change
+	for name := range params {
+		if !principal.Can(auth.ScopeWrite, name) {
+			return nil, ErrForbidden
+		}
+	}
+
+	probes, interval := ws.Defaults()
+	topics, err := pools.ParseTopics(params, probes, interval)
+	if err != nil {
+		return nil, err
+	}
+
+	//logger.Debug("TOPICS: %#v", topics)
+
+	//ret := pools.GlobalPool.GetOrStore(topics)
+	return pools.TopicPool.GetOrStore(topics, removeOld), nil
 }
+
+/*
+HostAllowed - check if principal can access host: host must be in one of principal's topics
+*/
+func HostAllowed(principal *auth.Principal, scope string, host string) bool {
+	if principal.CanAll(scope) {
+		return true
+	}
+	allowed := false
+	pools.TopicPool.Topics.Range(func(name, topic interface{}) bool {
+		if _, ok := topic.(*pools.Topic).Hosts.Load(host); ok && principal.Can(scope, name.(string)) {
+			allowed = true
+			return false
+		}
+		return true
+	})
+	return allowed
+}
+